@@ -0,0 +1,32 @@
+package configs
+
+import (
+	"os"
+	"strings"
+
+	"go-server/server"
+)
+
+// LoadGRPCSecurityConfig는 키 교체 gRPC 서버의 보안 설정을 환경 변수에서 읽습니다.
+//   - GRPC_TLS_CERT, GRPC_TLS_KEY: 서버 인증서/키 PEM 파일
+//   - GRPC_TLS_CLIENT_CA: 클라이언트 인증서를 검증할 CA PEM 파일 (mTLS)
+//   - GRPC_SHARED_SECRET: HMAC 서명용 공유 비밀 (GRPC_TLS_CERT/GRPC_TLS_KEY 필요)
+//   - GRPC_ALLOWED_CALLERS: 허용할 호출자, 쉼표로 구분 (예: "spring-backend,admin-cli")
+//   - GRPC_ALLOW_INSECURE: "true"이면 인증 없이 실행 (로컬 개발용)
+func LoadGRPCSecurityConfig() server.SecurityConfig {
+	config := server.SecurityConfig{
+		CertFile:      os.Getenv("GRPC_TLS_CERT"),
+		KeyFile:       os.Getenv("GRPC_TLS_KEY"),
+		ClientCAFile:  os.Getenv("GRPC_TLS_CLIENT_CA"),
+		SharedSecret:  os.Getenv("GRPC_SHARED_SECRET"),
+		AllowInsecure: os.Getenv("GRPC_ALLOW_INSECURE") == "true",
+	}
+
+	for _, caller := range strings.Split(os.Getenv("GRPC_ALLOWED_CALLERS"), ",") {
+		if caller = strings.TrimSpace(caller); caller != "" {
+			config.AllowedCallers = append(config.AllowedCallers, caller)
+		}
+	}
+
+	return config
+}
@@ -0,0 +1,76 @@
+package configs
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+)
+
+// ICEServerConfig는 브라우저와 서버 PeerConnection이 함께 쓰는 ICE 서버 하나입니다.
+// Ephemeral이면 Username/Credential 대신 요청마다 TURN REST API 방식의 임시 자격 증명을 만듭니다.
+type ICEServerConfig struct {
+	URLs       []string `json:"urls"`
+	Username   string   `json:"username,omitempty"`
+	Credential string   `json:"credential,omitempty"`
+	Ephemeral  bool     `json:"ephemeral,omitempty"`
+}
+
+// ICEConfig는 ICE 서버 목록과 임시 TURN 자격 증명 설정입니다.
+// TURNSecret은 TURN 서버의 shared secret(coturn의 static-auth-secret)과 같아야 합니다.
+type ICEConfig struct {
+	Servers       []ICEServerConfig
+	TURNSecret    string
+	CredentialTTL time.Duration
+}
+
+func DefaultICEConfig() ICEConfig {
+	return ICEConfig{
+		Servers:       []ICEServerConfig{{URLs: []string{"stun:stun.l.google.com:19302"}}},
+		CredentialTTL: time.Hour,
+	}
+}
+
+// LoadICEConfig는 ICE_SERVERS_FILE(JSON, 예: [{"urls":["stun:stun.example.com:3478"]},{"urls":["turn:turn.example.com:3478"],"ephemeral":true}]),
+// TURN_SECRET, TURN_CREDENTIAL_TTL(예: "1h")을 읽습니다. 파일이 없으면 공개 STUN 서버만 씁니다.
+// TURN_SECRET이 없으면 ephemeral 서버는 뺍니다.
+func LoadICEConfig() ICEConfig {
+	config := DefaultICEConfig()
+	config.TURNSecret = os.Getenv("TURN_SECRET")
+	if v := os.Getenv("TURN_CREDENTIAL_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Printf("Invalid TURN_CREDENTIAL_TTL %q, using %s", v, config.CredentialTTL)
+		} else {
+			config.CredentialTTL = d
+		}
+	}
+
+	if path := os.Getenv("ICE_SERVERS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("Failed to read ICE servers %s, using default: %v", path, err)
+			return config
+		}
+		var servers []ICEServerConfig
+		if err := json.Unmarshal(data, &servers); err != nil {
+			log.Printf("Failed to parse ICE servers %s, using default: %v", path, err)
+			return config
+		}
+		config.Servers = servers
+		log.Printf("Loaded %d ICE servers from %s", len(servers), path)
+	}
+
+	if config.TURNSecret == "" {
+		servers := config.Servers[:0:0]
+		for _, server := range config.Servers {
+			if server.Ephemeral {
+				log.Printf("TURN_SECRET is not set, skipping ephemeral ICE server %v", server.URLs)
+				continue
+			}
+			servers = append(servers, server)
+		}
+		config.Servers = servers
+	}
+	return config
+}
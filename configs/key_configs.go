@@ -0,0 +1,111 @@
+package configs
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"go-server/utils"
+)
+
+// LoadKeyRetention은 KEY_GRACE_PERIOD(예: "30m")와 KEY_MAX_PREVIOUS로 이전 공개키 유지 정책을 읽습니다.
+func LoadKeyRetention() utils.KeyRetention {
+	retention := utils.DefaultKeyRetention()
+
+	if v := os.Getenv("KEY_GRACE_PERIOD"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("Invalid KEY_GRACE_PERIOD %q, using %s: %v", v, retention.GracePeriod, err)
+		} else {
+			retention.GracePeriod = d
+		}
+	}
+	if v := os.Getenv("KEY_MAX_PREVIOUS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Printf("Invalid KEY_MAX_PREVIOUS %q, using %d: %v", v, retention.MaxPreviousKeys, err)
+		} else {
+			retention.MaxPreviousKeys = n
+		}
+	}
+
+	return retention
+}
+
+// JWKSConfig는 JWKS 조회 모드 설정입니다. URL이 비어 있으면 gRPC 푸시만 사용합니다.
+type JWKSConfig struct {
+	URL                string
+	RefreshInterval    time.Duration
+	MinRefreshInterval time.Duration
+}
+
+func LoadJWKSConfig() JWKSConfig {
+	config := JWKSConfig{
+		URL:                os.Getenv("JWKS_URL"),
+		RefreshInterval:    15 * time.Minute,
+		MinRefreshInterval: 30 * time.Second,
+	}
+
+	if v := os.Getenv("JWKS_REFRESH_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("Invalid JWKS_REFRESH_INTERVAL %q, using %s: %v", v, config.RefreshInterval, err)
+		} else {
+			config.RefreshInterval = d
+		}
+	}
+	if v := os.Getenv("JWKS_MIN_REFRESH_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("Invalid JWKS_MIN_REFRESH_INTERVAL %q, using %s: %v", v, config.MinRefreshInterval, err)
+		} else {
+			config.MinRefreshInterval = d
+		}
+	}
+
+	return config
+}
+
+// LoadKeyCacheTTL은 공개키 프로세스 내 캐시 유지 시간입니다. 0이면 캐시를 쓰지 않습니다.
+func LoadKeyCacheTTL() time.Duration {
+	ttl := time.Minute
+	if v := os.Getenv("KEY_CACHE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("Invalid KEY_CACHE_TTL %q, using %s: %v", v, ttl, err)
+		} else {
+			ttl = d
+		}
+	}
+	return ttl
+}
+
+// LoadAccessTokenMaxLifetime은 ACCESS_TOKEN_MAX_LIFETIME(기본 24h)을 읽습니다. 토큰 폐기 기록을 이 시간만큼 유지합니다.
+func LoadAccessTokenMaxLifetime() time.Duration {
+	lifetime := 24 * time.Hour
+	if v := os.Getenv("ACCESS_TOKEN_MAX_LIFETIME"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("Invalid ACCESS_TOKEN_MAX_LIFETIME %q, using %s: %v", v, lifetime, err)
+		} else {
+			lifetime = d
+		}
+	}
+	return lifetime
+}
+
+// LoadAuthTimeZone은 AUTH_TIME_ZONE(IANA 이름, 예: "Asia/Seoul")을 읽습니다.
+// 인증 서비스가 gRPC로 오프셋 없는 시각(LocalDateTime)을 보낼 때 그 시각의 시간대이며, 기본값은 UTC입니다.
+func LoadAuthTimeZone() *time.Location {
+	v := os.Getenv("AUTH_TIME_ZONE")
+	if v == "" {
+		return time.UTC
+	}
+	zone, err := time.LoadLocation(v)
+	if err != nil {
+		log.Printf("Invalid AUTH_TIME_ZONE %q, using UTC: %v", v, err)
+		return time.UTC
+	}
+	return zone
+}
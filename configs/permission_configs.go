@@ -0,0 +1,31 @@
+package configs
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+)
+
+// LoadPermissionPolicy는 PERMISSION_POLICY_FILE(JSON, 예: {"viewer":["read"]})에서
+// 역할별 권한 표를 읽습니다. 설정이 없거나 읽지 못하면 nil을 반환하고 기본 정책을 씁니다.
+func LoadPermissionPolicy() map[string][]string {
+	path := os.Getenv("PERMISSION_POLICY_FILE")
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Failed to read permission policy %s, using default: %v", path, err)
+		return nil
+	}
+
+	var policy map[string][]string
+	if err := json.Unmarshal(data, &policy); err != nil {
+		log.Printf("Failed to parse permission policy %s, using default: %v", path, err)
+		return nil
+	}
+
+	log.Printf("Loaded permission policy for %d roles from %s", len(policy), path)
+	return policy
+}
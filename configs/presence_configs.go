@@ -0,0 +1,58 @@
+package configs
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// PresenceConfig는 참가자 heartbeat와 만료 정리 주기입니다.
+// TTL 동안 heartbeat가 없는 참가자는 서버가 죽어 정리되지 못한 것으로 보고 지웁니다.
+type PresenceConfig struct {
+	HeartbeatInterval time.Duration
+	TTL               time.Duration
+	ReapInterval      time.Duration
+}
+
+func DefaultPresenceConfig() PresenceConfig {
+	return PresenceConfig{
+		HeartbeatInterval: 15 * time.Second,
+		TTL:               45 * time.Second,
+		ReapInterval:      15 * time.Second,
+	}
+}
+
+// LoadPresenceConfig는 PRESENCE_HEARTBEAT_INTERVAL, PRESENCE_TTL, PRESENCE_REAP_INTERVAL(예: "15s")을 읽습니다.
+func LoadPresenceConfig() PresenceConfig {
+	config := DefaultPresenceConfig()
+	if v := os.Getenv("PRESENCE_HEARTBEAT_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("Invalid PRESENCE_HEARTBEAT_INTERVAL %q, using %s: %v", v, config.HeartbeatInterval, err)
+		} else {
+			config.HeartbeatInterval = d
+		}
+	}
+	if v := os.Getenv("PRESENCE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("Invalid PRESENCE_TTL %q, using %s: %v", v, config.TTL, err)
+		} else {
+			config.TTL = d
+		}
+	}
+	if v := os.Getenv("PRESENCE_REAP_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("Invalid PRESENCE_REAP_INTERVAL %q, using %s: %v", v, config.ReapInterval, err)
+		} else {
+			config.ReapInterval = d
+		}
+	}
+
+	if config.TTL <= config.HeartbeatInterval {
+		log.Printf("PRESENCE_TTL %s must be longer than the heartbeat interval, using %s", config.TTL, 3*config.HeartbeatInterval)
+		config.TTL = 3 * config.HeartbeatInterval
+	}
+	return config
+}
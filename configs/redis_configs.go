@@ -2,8 +2,11 @@ package configs
 
 import (
 	"context"
+	"log"
+	"os"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
 )
 
 var RedisClient *redis.Client
@@ -19,3 +22,27 @@ func GetRedisClient() *redis.Client {
 }
 
 var Ctx = context.Background()
+
+// LoadInstanceID는 Redis에 남기는 참가자 연결의 소유자 ID입니다. 재시작해도 같아야
+// 시작할 때 이전 프로세스가 남긴 연결을 정리할 수 있으므로 INSTANCE_ID(없으면 호스트 이름)를 그대로 씁니다.
+// 레플리카마다 달라야 합니다(예: StatefulSet 파드 이름). 둘 다 없으면 ""을 반환하고 시작 시 정리를 건너뜁니다.
+func LoadInstanceID() string {
+	if v := os.Getenv("INSTANCE_ID"); v != "" {
+		return v
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		log.Printf("INSTANCE_ID is not set and hostname is unavailable; participants left by a crash will only expire by TTL: %v", err)
+		return ""
+	}
+	return hostname
+}
+
+// NewProcessID는 이 프로세스에만 쓰는 ID입니다. fan-out에서 자기가 보낸 메시지를 거르거나
+// 문서 방 소유권처럼 이전 프로세스와 겹치면 안 되는 곳에 씁니다.
+func NewProcessID(instanceID string) string {
+	if instanceID == "" {
+		instanceID = "go-server"
+	}
+	return instanceID + "-" + uuid.NewString()[:8]
+}
@@ -0,0 +1,53 @@
+package configs
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// CanvasSnapshotConfig는 캔버스 자동 스냅샷 주기와 저장소 선택 기준입니다.
+type CanvasSnapshotConfig struct {
+	// 마지막 자동 스냅샷 이후 이 시간이 지나면 저장 시 새 스냅샷 생성 (0이면 비활성)
+	Interval time.Duration
+	// 마지막 자동 스냅샷과 크기(byte) 차이가 이 값 이상이면 새 스냅샷 생성 (0이면 비활성)
+	SizeDelta int
+	// 이 크기(byte) 이상인 스냅샷은 GridFS에 저장
+	GridFSThreshold int
+}
+
+func LoadCanvasSnapshotConfig() CanvasSnapshotConfig {
+	config := CanvasSnapshotConfig{
+		Interval:        10 * time.Minute,
+		SizeDelta:       4 * 1024,
+		GridFSThreshold: 1024 * 1024,
+	}
+
+	if v := os.Getenv("CANVAS_SNAPSHOT_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("Invalid CANVAS_SNAPSHOT_INTERVAL %q, using %s: %v", v, config.Interval, err)
+		} else {
+			config.Interval = d
+		}
+	}
+	if v := os.Getenv("CANVAS_SNAPSHOT_SIZE_DELTA"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Printf("Invalid CANVAS_SNAPSHOT_SIZE_DELTA %q, using %d: %v", v, config.SizeDelta, err)
+		} else {
+			config.SizeDelta = n
+		}
+	}
+	if v := os.Getenv("CANVAS_SNAPSHOT_GRIDFS_THRESHOLD"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Printf("Invalid CANVAS_SNAPSHOT_GRIDFS_THRESHOLD %q, using %d: %v", v, config.GridFSThreshold, err)
+		} else {
+			config.GridFSThreshold = n
+		}
+	}
+
+	return config
+}
@@ -0,0 +1,181 @@
+package configs
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// 송신 큐가 가득 찼을 때의 처리
+const (
+	// OverflowDropOldest: 가장 오래된 메시지를 버리고 새 메시지를 넣습니다. 참가자 목록처럼 매번 전체 상태를 보내는 경우에 맞습니다.
+	OverflowDropOldest = "drop-oldest"
+	// OverflowDisconnect: 느린 클라이언트의 연결을 끊습니다. 클라이언트는 다시 연결해 상태를 새로 받습니다.
+	OverflowDisconnect = "disconnect"
+)
+
+// OutboundConfig는 WebSocket 연결마다 두는 송신 큐의 설정입니다.
+// 브로드캐스트는 큐에 넣기만 하고, 실제 쓰기는 연결별 writer goroutine이 합니다.
+type OutboundConfig struct {
+	QueueSize    int
+	WriteTimeout time.Duration
+	Overflow     string
+}
+
+func DefaultOutboundConfig() OutboundConfig {
+	return OutboundConfig{
+		QueueSize:    256,
+		WriteTimeout: 10 * time.Second,
+		Overflow:     OverflowDropOldest,
+	}
+}
+
+// LoadOutboundConfig는 WS_QUEUE_SIZE, WS_WRITE_TIMEOUT(예: "10s"), WS_OVERFLOW_POLICY("drop-oldest" 또는 "disconnect")를 읽습니다.
+func LoadOutboundConfig() OutboundConfig {
+	config := DefaultOutboundConfig()
+	if v := os.Getenv("WS_QUEUE_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Printf("Invalid WS_QUEUE_SIZE %q, using %d", v, config.QueueSize)
+		} else {
+			config.QueueSize = n
+		}
+	}
+	if v := os.Getenv("WS_WRITE_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("Invalid WS_WRITE_TIMEOUT %q, using %s: %v", v, config.WriteTimeout, err)
+		} else {
+			config.WriteTimeout = d
+		}
+	}
+	if v := os.Getenv("WS_OVERFLOW_POLICY"); v != "" {
+		switch v {
+		case OverflowDropOldest, OverflowDisconnect:
+			config.Overflow = v
+		default:
+			log.Printf("Invalid WS_OVERFLOW_POLICY %q, using %s", v, config.Overflow)
+		}
+	}
+	return config
+}
+
+// WebSocket 엔드포인트 이름. 한도 설정의 키이자 metric의 endpoint 라벨입니다.
+const (
+	EndpointParticipants = "participants"
+	EndpointSignaling    = "signaling"
+	EndpointMedia        = "media"
+	EndpointYDoc         = "ydoc"
+
+	// legacyEndpointAudio: SFU가 오디오 전용이던 때의 EndpointMedia 이름. WS_LIMITS_FILE에서만 받아 줍니다.
+	legacyEndpointAudio = "audio"
+)
+
+// RateLimit: 초당 PerSecond개씩 채워지고 Burst개까지 모이는 토큰 버킷. PerSecond가 0이면 제한하지 않습니다.
+type RateLimit struct {
+	PerSecond float64 `json:"perSecond"`
+	Burst     int     `json:"burst"`
+}
+
+// ActionLimit: 한 action의 연결별 한도와 사용자별 한도. 사용자별 한도는 인스턴스마다 따로 셉니다.
+type ActionLimit struct {
+	Connection RateLimit `json:"connection"`
+	User       RateLimit `json:"user"`
+}
+
+// EndpointLimits: 엔드포인트 하나의 한도. Actions에 없는 action은 "*" 항목을 씁니다.
+// MaxMessageSize(바이트)를 넘는 프레임을 받으면 1009(message too big)로 연결을 끊습니다. 0이면 제한하지 않습니다.
+type EndpointLimits struct {
+	MaxMessageSize int64                  `json:"maxMessageSize"`
+	Actions        map[string]ActionLimit `json:"actions"`
+}
+
+// LimitConfig는 WebSocket 엔드포인트의 메시지 크기, 속도, 연결 수 한도입니다.
+// 한도를 넘긴 메시지는 버리고, MaxViolations번 넘기면 연결을 끊습니다.
+type LimitConfig struct {
+	MaxConnectionsPerUser int                       `json:"maxConnectionsPerUser"`
+	MaxViolations         int                       `json:"maxViolations"`
+	Endpoints             map[string]EndpointLimits `json:"endpoints"`
+}
+
+func DefaultLimitConfig() LimitConfig {
+	return LimitConfig{
+		// 탭 하나가 /ws, /signaling, /webrtc/media, /yjs 문서들에 연결하므로 넉넉하게
+		MaxConnectionsPerUser: 32,
+		MaxViolations:         20,
+		Endpoints: map[string]EndpointLimits{
+			EndpointParticipants: {
+				MaxMessageSize: 16 * 1024,
+				Actions: map[string]ActionLimit{
+					// 조회마다 Redis HGETALL이 나가므로 더 낮게
+					"getParticipants":      {Connection: RateLimit{PerSecond: 2, Burst: 10}, User: RateLimit{PerSecond: 5, Burst: 20}},
+					"getAudioParticipants": {Connection: RateLimit{PerSecond: 2, Burst: 10}, User: RateLimit{PerSecond: 5, Burst: 20}},
+					"*":                    {Connection: RateLimit{PerSecond: 5, Burst: 20}, User: RateLimit{PerSecond: 10, Burst: 40}},
+				},
+			},
+			EndpointSignaling: {
+				MaxMessageSize: 256 * 1024,
+				Actions: map[string]ActionLimit{
+					// 연결을 맺을 때 SDP와 ICE candidate가 몰려서 옴
+					"publish": {Connection: RateLimit{PerSecond: 50, Burst: 300}, User: RateLimit{PerSecond: 100, Burst: 600}},
+					"*":       {Connection: RateLimit{PerSecond: 10, Burst: 50}, User: RateLimit{PerSecond: 20, Burst: 100}},
+				},
+			},
+			EndpointMedia: {
+				// 카메라와 화면 공유가 붙으면 SDP가 커짐
+				MaxMessageSize: 64 * 1024,
+				Actions: map[string]ActionLimit{
+					"iceCandidate": {Connection: RateLimit{PerSecond: 20, Burst: 100}, User: RateLimit{PerSecond: 40, Burst: 200}},
+					"*":            {Connection: RateLimit{PerSecond: 5, Burst: 20}, User: RateLimit{PerSecond: 10, Burst: 40}},
+				},
+			},
+			EndpointYDoc: {
+				// 처음 sync step 2에 문서 전체가 올 수 있음
+				MaxMessageSize: 16 * 1024 * 1024,
+				Actions: map[string]ActionLimit{
+					"*": {Connection: RateLimit{PerSecond: 100, Burst: 500}, User: RateLimit{PerSecond: 200, Burst: 1000}},
+				},
+			},
+		},
+	}
+}
+
+// LoadLimitConfig는 WS_LIMITS_FILE(JSON, 예: {"maxConnectionsPerUser":10,"endpoints":{"media":{"maxMessageSize":32768,"actions":{"*":{...}}}}})을 읽습니다.
+// 파일에 있는 엔드포인트는 기본값을 통째로 바꾸고, 없는 엔드포인트는 기본값을 씁니다.
+func LoadLimitConfig() LimitConfig {
+	config := DefaultLimitConfig()
+	path := os.Getenv("WS_LIMITS_FILE")
+	if path == "" {
+		return config
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Failed to read WebSocket limits %s, using default: %v", path, err)
+		return config
+	}
+
+	loaded := DefaultLimitConfig()
+	loaded.Endpoints = nil
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		log.Printf("Failed to parse WebSocket limits %s, using default: %v", path, err)
+		return config
+	}
+	if limits, ok := loaded.Endpoints[legacyEndpointAudio]; ok {
+		if _, ok := loaded.Endpoints[EndpointMedia]; !ok {
+			log.Printf("WebSocket limits endpoint %q is deprecated, use %q", legacyEndpointAudio, EndpointMedia)
+			loaded.Endpoints[EndpointMedia] = limits
+		}
+		delete(loaded.Endpoints, legacyEndpointAudio)
+	}
+	for endpoint, limits := range loaded.Endpoints {
+		config.Endpoints[endpoint] = limits
+	}
+	config.MaxConnectionsPerUser = loaded.MaxConnectionsPerUser
+	config.MaxViolations = loaded.MaxViolations
+
+	log.Printf("Loaded WebSocket limits for %d endpoints from %s", len(loaded.Endpoints), path)
+	return config
+}
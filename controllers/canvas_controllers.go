@@ -1,6 +1,10 @@
 package controllers
 
 import (
+	"errors"
+	"log"
+
+	"go-server/configs"
 	"go-server/models"
 	"go-server/repository"
 
@@ -8,11 +12,17 @@ import (
 )
 
 type CanvasController struct {
-	repo repository.CanvasRepositoryInterface
+	repo           repository.CanvasRepositoryInterface
+	snapshotRepo   repository.CanvasSnapshotRepositoryInterface
+	snapshotConfig configs.CanvasSnapshotConfig
 }
 
-func NewCanvasController(repo repository.CanvasRepositoryInterface) *CanvasController {
-	return &CanvasController{repo: repo}
+func NewCanvasController(repo repository.CanvasRepositoryInterface, snapshotRepo repository.CanvasSnapshotRepositoryInterface, snapshotConfig configs.CanvasSnapshotConfig) *CanvasController {
+	return &CanvasController{
+		repo:           repo,
+		snapshotRepo:   snapshotRepo,
+		snapshotConfig: snapshotConfig,
+	}
 }
 
 func (cc *CanvasController) CreateCanvas(c *fiber.Ctx) error {
@@ -22,10 +32,16 @@ func (cc *CanvasController) CreateCanvas(c *fiber.Ctx) error {
 	}
 
 	objectID, err := cc.repo.SaveCanvas(canvas)
+	if errors.Is(err, repository.ErrYDocActive) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Document has collaborative edits; edit it through the live session"})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save canvas"})
 	}
 
+	canvas.ID = objectID
+	cc.autoSnapshot(canvas, currentUserID(c))
+
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": objectID})
 }
 
@@ -64,8 +80,37 @@ func (cc *CanvasController) UpdateCanvasTitle(c *fiber.Ctx) error {
 
 func (cc *CanvasController) DeleteCanvasByID(c *fiber.Ctx) error {
 	id := c.Params("id")
+	// 스냅샷(본문 포함)을 먼저 지움. 캔버스가 지워지면 권한 확인이 404가 되어 남은 스냅샷을 지울 방법이 없음
+	if err := cc.snapshotRepo.DeleteSnapshotsByCanvasID(id); err != nil {
+		log.Printf("Failed to delete snapshots of canvas %s: %v\n", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete canvas snapshots"})
+	}
 	if err := cc.repo.DeleteCanvasByID(id); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete canvas"})
 	}
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
 }
+
+// ResolveCanvasTeam은 :id canvas의 team_id를 돌려줍니다. middleware.RequireTeamMember와 함께 사용합니다.
+func (cc *CanvasController) ResolveCanvasTeam(c *fiber.Ctx) (string, error) {
+	canvas, err := cc.repo.FindCanvasByID(c.Params("id"))
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusNotFound, "Canvas not found")
+	}
+	return canvas.TeamID, nil
+}
+
+// ResolveNewCanvasTeam은 생성 요청 본문의 team_id를 돌려줍니다.
+// 본문에 기존 id가 있으면 다른 팀 canvas를 덮어쓰지 못하도록 같은 팀인지도 확인합니다.
+func (cc *CanvasController) ResolveNewCanvasTeam(c *fiber.Ctx) (string, error) {
+	var canvas models.Canvas
+	if err := c.BodyParser(&canvas); err != nil {
+		return "", fiber.NewError(fiber.StatusBadRequest, "Invalid JSON")
+	}
+	if canvas.ID != "" {
+		if existing, err := cc.repo.FindCanvasByID(canvas.ID); err == nil && existing.TeamID != canvas.TeamID {
+			return "", fiber.NewError(fiber.StatusForbidden, "Canvas belongs to another team")
+		}
+	}
+	return canvas.TeamID, nil
+}
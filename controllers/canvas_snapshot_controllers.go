@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"go-server/models"
+	"go-server/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// autoSnapshot은 저장 직후 호출되어, 마지막 자동 스냅샷 이후 설정된 시간이 지났거나
+// 크기가 설정값 이상 달라졌으면 새 자동 스냅샷을 남깁니다.
+func (cc *CanvasController) autoSnapshot(canvas models.Canvas, authorID string) {
+	latest, err := cc.snapshotRepo.FindLatestAutoSnapshot(canvas.ID)
+	if err != nil && !errors.Is(err, repository.ErrSnapshotNotFound) {
+		log.Println("Failed to find latest auto snapshot:", err)
+		return
+	}
+
+	if err == nil {
+		interval := cc.snapshotConfig.Interval
+		sizeDelta := cc.snapshotConfig.SizeDelta
+		due := interval > 0 && time.Since(latest.CreatedAt) >= interval
+		grown := sizeDelta > 0 && abs(len(canvas.Canvas)-latest.Size) >= sizeDelta
+		if !due && !grown {
+			return
+		}
+	}
+
+	snapshot := models.CanvasSnapshot{
+		CanvasID: canvas.ID,
+		TeamID:   canvas.TeamID,
+		Name:     "auto " + time.Now().Format(time.RFC3339),
+		Title:    canvas.Title,
+		Auto:     true,
+		AuthorID: authorID,
+		Canvas:   canvas.Canvas,
+	}
+	if _, err := cc.snapshotRepo.SaveSnapshot(snapshot); err != nil {
+		log.Println("Failed to save auto snapshot:", err)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func (cc *CanvasController) CreateCanvasSnapshot(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var request struct {
+		Name string `json:"name"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid JSON"})
+	}
+	if request.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Missing snapshot name"})
+	}
+
+	canvas, err := cc.repo.FindCanvasByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Canvas not found"})
+	}
+
+	snapshotID, err := cc.snapshotRepo.SaveSnapshot(models.CanvasSnapshot{
+		CanvasID: id,
+		TeamID:   canvas.TeamID,
+		Name:     request.Name,
+		Title:    canvas.Title,
+		AuthorID: currentUserID(c),
+		Canvas:   canvas.Canvas,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save canvas snapshot"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": snapshotID})
+}
+
+func (cc *CanvasController) GetCanvasSnapshots(c *fiber.Ctx) error {
+	id := c.Params("id")
+	snapshots, err := cc.snapshotRepo.FindSnapshotsByCanvasID(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to find canvas snapshots"})
+	}
+	return c.Status(fiber.StatusOK).JSON(snapshots)
+}
+
+// RestoreCanvasSnapshot은 현재 상태를 먼저 자동 스냅샷으로 남긴 뒤 스냅샷 내용으로 캔버스를 덮어씁니다.
+func (cc *CanvasController) RestoreCanvasSnapshot(c *fiber.Ctx) error {
+	id := c.Params("id")
+	snapshotID := c.Params("snapshotId")
+
+	snapshot, err := cc.snapshotRepo.FindSnapshot(id, snapshotID)
+	if err != nil {
+		if errors.Is(err, repository.ErrSnapshotNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Snapshot not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to find canvas snapshot"})
+	}
+
+	current, err := cc.repo.FindCanvasByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Canvas not found"})
+	}
+	_, err = cc.snapshotRepo.SaveSnapshot(models.CanvasSnapshot{
+		CanvasID: id,
+		TeamID:   current.TeamID,
+		Name:     "before restore of " + snapshot.Name,
+		Title:    current.Title,
+		Auto:     true,
+		AuthorID: currentUserID(c),
+		Canvas:   current.Canvas,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save canvas snapshot"})
+	}
+
+	if err := cc.repo.RestoreCanvas(id, snapshot.Title, snapshot.Canvas); err != nil {
+		if errors.Is(err, repository.ErrYDocActive) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Document has collaborative edits; edit it through the live session"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to restore canvas"})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+}
@@ -0,0 +1,15 @@
+package controllers
+
+import (
+	middleware "go-server/middlewares"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// currentUserID는 JWTParser가 저장한 claims에서 사용자 ID를 꺼냅니다. 없으면 빈 문자열.
+func currentUserID(c *fiber.Ctx) string {
+	if claims, ok := c.Locals("user").(*middleware.CustomClaims); ok {
+		return claims.UserID
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"go-server/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	DocumentKindNote   = "note"
+	DocumentKindCanvas = "canvas"
+	// DocumentKindTeam은 문서가 아니라 팀 전체 채널("team/<teamId>")입니다.
+	DocumentKindTeam = "team"
+)
+
+// DocumentTeamResolver는 "note/<id>", "canvas/<id>", "team/<teamId>" 같은 문서 이름으로 문서가 속한 team_id를 찾습니다.
+// y-websocket room과 y-webrtc topic이 같은 이름 규칙을 씁니다.
+type DocumentTeamResolver struct {
+	noteRepo   repository.NoteRepositoryInterface
+	canvasRepo repository.CanvasRepositoryInterface
+}
+
+func NewDocumentTeamResolver(noteRepo repository.NoteRepositoryInterface, canvasRepo repository.CanvasRepositoryInterface) *DocumentTeamResolver {
+	return &DocumentTeamResolver{noteRepo: noteRepo, canvasRepo: canvasRepo}
+}
+
+// TeamOf는 문서가 없거나 kind를 모르면 404 *fiber.Error를 반환합니다.
+func (r *DocumentTeamResolver) TeamOf(kind, id string) (string, error) {
+	switch kind {
+	case DocumentKindNote:
+		note, err := r.noteRepo.FindNoteByID(id)
+		if err != nil {
+			return "", fiber.NewError(fiber.StatusNotFound, "Note not found")
+		}
+		return note.TeamID, nil
+	case DocumentKindCanvas:
+		canvas, err := r.canvasRepo.FindCanvasByID(id)
+		if err != nil {
+			return "", fiber.NewError(fiber.StatusNotFound, "Canvas not found")
+		}
+		return canvas.TeamID, nil
+	case DocumentKindTeam:
+		return id, nil
+	default:
+		return "", fiber.NewError(fiber.StatusNotFound, "Unknown document kind")
+	}
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+// heartbeat: interval마다 ping을 보내고, 한 주기 동안 pong도 다른 메시지도 없으면 연결을 끊습니다.
+type heartbeat struct {
+	alive atomic.Bool
+	done  chan struct{}
+}
+
+// startHeartbeat: onPong은 pong을 받을 때마다 읽기 goroutine에서 호출됩니다(nil 가능).
+// interval이 0이면 ping을 보내지 않습니다.
+func startHeartbeat(c *websocket.Conn, interval time.Duration, onPong func()) *heartbeat {
+	h := &heartbeat{done: make(chan struct{})}
+	h.alive.Store(true)
+	c.SetPongHandler(func(string) error {
+		h.alive.Store(true)
+		if onPong != nil {
+			onPong()
+		}
+		return nil
+	})
+	if interval > 0 {
+		go h.run(c, interval)
+	}
+	return h
+}
+
+// seen: 클라이언트가 메시지를 보냈으면 살아 있는 것으로 봅니다.
+func (h *heartbeat) seen() {
+	h.alive.Store(true)
+}
+
+func (h *heartbeat) stop() {
+	close(h.done)
+}
+
+func (h *heartbeat) run(c *websocket.Conn, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.done:
+			return
+		case <-ticker.C:
+			if !h.alive.Swap(false) {
+				_ = c.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "ping timeout"), time.Now().Add(time.Second))
+				// hijack된 연결은 핸들러가 끝나야 닫히므로 읽기를 바로 끝내 핸들러가 정리하게 함
+				_ = c.SetReadDeadline(time.Now())
+				return
+			}
+			// WriteControl은 다른 쓰기와 동시에 호출해도 안전함
+			if err := c.WriteControl(websocket.PingMessage, nil, time.Now().Add(interval)); err != nil {
+				_ = c.SetReadDeadline(time.Now())
+				return
+			}
+		}
+	}
+}
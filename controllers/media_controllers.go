@@ -0,0 +1,669 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-server/configs"
+	middleware "go-server/middlewares"
+	"go-server/models"
+	"go-server/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"github.com/pion/webrtc/v4"
+)
+
+// MediaSocketController: 팀별 SFU. 오디오, 카메라, 화면 공유 트랙을 같은 팀의 다른 연결로 중계합니다.
+// 미디어는 이 인스턴스의 PeerConnection끼리만 중계되므로 한 팀의 미디어 연결은 게이트웨이에서 같은 인스턴스로 보내야 합니다
+// (참가자 목록은 ParticipantsController가 fanout으로 공유).
+type MediaSocketController struct {
+	mu          sync.Mutex
+	membership  middleware.TeamMembershipProvider
+	limits      *wsLimiter
+	outbound    configs.OutboundConfig
+	ice         configs.ICEConfig
+	api         *webrtc.API
+	teams       map[string]map[*websocket.Conn]*mediaMember
+	teamsTracks map[string]map[*websocket.Conn][]*mediaTrack
+}
+
+// mediaMember: 팀에 들어간 연결 하나. pc는 팀에 들어간 뒤 바뀌지 않으므로 다른 연결의 콜백에서도 읽습니다.
+type mediaMember struct {
+	peer          *mediaPeer
+	pc            *webrtc.PeerConnection
+	participantID string
+}
+
+func NewMediaSocketController(membership middleware.TeamMembershipProvider) *MediaSocketController {
+	api, err := newMediaAPI()
+	if err != nil {
+		log.Fatalf("Failed to register SFU codecs: %v", err)
+	}
+	return &MediaSocketController{
+		membership:  membership,
+		limits:      newWSLimiter(configs.EndpointMedia, configs.DefaultLimitConfig()),
+		outbound:    mediaOutbound(configs.DefaultOutboundConfig()),
+		ice:         configs.DefaultICEConfig(),
+		api:         api,
+		teams:       make(map[string]map[*websocket.Conn]*mediaMember),
+		teamsTracks: make(map[string]map[*websocket.Conn][]*mediaTrack),
+	}
+}
+
+// SetLimits: 메시지 크기와 type별 속도 한도를 바꿉니다. HandleWebRTC가 불리기 전에 설정해야 합니다.
+func (msc *MediaSocketController) SetLimits(limits configs.LimitConfig) {
+	msc.limits = newWSLimiter(configs.EndpointMedia, limits)
+}
+
+// SetOutbound: 연결별 송신 큐 크기를 바꿉니다. HandleWebRTC가 불리기 전에 설정해야 합니다.
+func (msc *MediaSocketController) SetOutbound(outbound configs.OutboundConfig) {
+	msc.outbound = mediaOutbound(outbound)
+}
+
+// SetICE: 브라우저에 알려 주고 서버 PeerConnection도 쓰는 ICE 서버 목록을 바꿉니다.
+func (msc *MediaSocketController) SetICE(ice configs.ICEConfig) {
+	msc.ice = ice
+}
+
+// iceServers: userID에 묶인 임시 TURN 자격 증명을 채운 ICE 서버 목록과 자격 증명의 유효 기간
+func (msc *MediaSocketController) iceServers(userID string) ([]models.ICEServer, time.Duration) {
+	var ttl time.Duration
+	servers := make([]models.ICEServer, 0, len(msc.ice.Servers))
+	for _, server := range msc.ice.Servers {
+		s := models.ICEServer{URLs: server.URLs, Username: server.Username, Credential: server.Credential}
+		if server.Ephemeral {
+			credential := utils.NewTURNCredential(msc.ice.TURNSecret, userID, time.Now().Add(msc.ice.CredentialTTL))
+			s.Username, s.Credential = credential.Username, credential.Credential
+			ttl = msc.ice.CredentialTTL
+		}
+		servers = append(servers, s)
+	}
+	return servers, ttl
+}
+
+// ICEServers: GET /webrtc/ice-servers. 로그인한 사용자에게 RTCPeerConnection에 넣을 ICE 서버 목록을 줍니다.
+func (msc *MediaSocketController) ICEServers(c *fiber.Ctx) error {
+	userID := currentUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	servers, ttl := msc.iceServers(userID)
+	// 자격 증명이 들어 있으므로 캐시하지 않음
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(models.ICEServersResponse{ICEServers: servers, TTL: int64(ttl / time.Second)})
+}
+
+// mediaOutbound: SDP나 ICE candidate를 하나라도 버리면 협상이 깨지므로 큐가 넘치면 항상 연결을 끊습니다.
+func mediaOutbound(outbound configs.OutboundConfig) configs.OutboundConfig {
+	outbound.Overflow = configs.OverflowDisconnect
+	return outbound
+}
+
+// maxPendingICECandidates: remote description 전에 쌓아 둘 수 있는 클라이언트 candidate 수
+const maxPendingICECandidates = 64
+
+// mediaPeer: 미디어 연결 하나의 상태. 응답과 서버 candidate는 pion 콜백에서도 보내므로 모두 송신 큐를 거칩니다.
+// pc와 pendingCandidates는 협상 큐 goroutine에서만 씁니다(media_negotiation.go).
+type mediaPeer struct {
+	out         *outboundQueue
+	negotiation mediaNegotiation
+	pc          *webrtc.PeerConnection
+	// remote description이 설정되기 전에 도착한 클라이언트 ICE candidate
+	pendingCandidates []webrtc.ICECandidateInit
+
+	// answer를 보내기 전에 모인 서버 candidate. 클라이언트가 answer보다 candidate를 먼저 받지 않게 합니다.
+	mu              sync.Mutex
+	answered        bool
+	localCandidates []webrtc.ICECandidateInit
+	// offer의 sources로 받은 클라이언트 MediaStream id별 출처. OnTrack 콜백에서 읽습니다.
+	sources map[string]string
+	// 새로 더했지만 아직 협상이 끝나지 않은 비디오 트랙. answer를 받으면 키프레임을 요청합니다.
+	keyframePending []*mediaTrack
+}
+
+func (p *mediaPeer) awaitKeyframe(track *mediaTrack) {
+	p.mu.Lock()
+	p.keyframePending = append(p.keyframePending, track)
+	p.mu.Unlock()
+}
+
+// requestPendingKeyframes: 방금 끝난 협상에 들어간 트랙의 publisher에게 키프레임을 요청합니다.
+// 그 offer를 만든 뒤에 더한 트랙(mid 없음)은 다음 answer까지 남겨 둡니다.
+func (p *mediaPeer) requestPendingKeyframes() {
+	p.mu.Lock()
+	var ready []*mediaTrack
+	pending := p.keyframePending[:0]
+	for _, track := range p.keyframePending {
+		switch {
+		case !hasTrack(p.pc, track.local):
+			// 협상 전에 빠진 트랙
+		case negotiated(p.pc, track.local):
+			ready = append(ready, track)
+		default:
+			pending = append(pending, track)
+		}
+	}
+	p.keyframePending = pending
+	p.mu.Unlock()
+	for _, track := range ready {
+		track.requestKeyframe("subscribe")
+	}
+}
+
+func (p *mediaPeer) send(msg interface{}) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Println("Failed to marshal media message:", err)
+		return
+	}
+	if err := p.out.WriteMessage(websocket.TextMessage, data); err != nil {
+		log.Println("WriteMessage error:", err)
+	}
+}
+
+// sendCandidate: 서버 candidate를 보냅니다. pion 콜백 goroutine에서 불립니다.
+func (p *mediaPeer) sendCandidate(candidate webrtc.ICECandidateInit) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.answered {
+		p.localCandidates = append(p.localCandidates, candidate)
+		return
+	}
+	p.send(map[string]interface{}{"type": "iceCandidate", "candidate": candidate})
+}
+
+// sendAnswer: answer를 보내고 그 사이 모인 서버 candidate를 이어서 보냅니다.
+func (p *mediaPeer) sendAnswer(answer webrtc.SessionDescription) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.send(map[string]interface{}{"type": "answer", "sdp": answer.SDP})
+	p.answered = true
+	for _, candidate := range p.localCandidates {
+		p.send(map[string]interface{}{"type": "iceCandidate", "candidate": candidate})
+	}
+	p.localCandidates = nil
+}
+
+func (p *mediaPeer) sendError(message string) {
+	p.send(map[string]interface{}{"type": "error", "error": message})
+}
+
+// setSources: offer에 적힌 스트림 출처를 더합니다. 같은 스트림은 새 값으로 바뀝니다.
+func (p *mediaPeer) setSources(sources map[string]string) {
+	if len(sources) == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sources == nil {
+		p.sources = make(map[string]string)
+	}
+	for streamID, source := range sources {
+		p.sources[streamID] = source
+	}
+}
+
+// source: 클라이언트 MediaStream id에 적힌 출처. 없으면 빈 문자열
+func (p *mediaPeer) source(streamID string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.sources[streamID]
+}
+
+// canJoinTeam은 업그레이드 때 저장된 claims 기준으로 teamId 팀의 멤버인지 확인합니다.
+func (msc *MediaSocketController) canJoinTeam(c *websocket.Conn, teamID string) bool {
+	claims, ok := c.Locals("user").(*middleware.CustomClaims)
+	if !ok || msc.membership == nil {
+		return true
+	}
+	member, err := msc.membership.IsMember(configs.Ctx, claims, teamID)
+	if err != nil {
+		log.Println("Failed to check team membership:", err)
+		return false
+	}
+	return member
+}
+
+func (msc *MediaSocketController) HandleWebRTC(c *websocket.Conn) {
+	out := newOutboundQueue(c, msc.outbound, configs.EndpointMedia)
+	peer := &mediaPeer{out: out}
+	peer.startNegotiation()
+	defer func() {
+		peer.stopNegotiation()
+		msc.cleanupConnection(c)
+		out.stop()
+		c.Close()
+	}()
+	limiter := msc.limits.connection(c, out.disconnect)
+
+	for {
+		_, msg, err := c.ReadMessage()
+		if err != nil {
+			log.Println("Read error:", err)
+			limiter.readError(err)
+			break
+		}
+
+		var payload map[string]interface{}
+		err = json.Unmarshal(msg, &payload)
+		action, _ := payload["type"].(string)
+		if !limiter.allow(action) {
+			continue
+		}
+		if err != nil {
+			log.Println("Error parsing message:", err)
+			continue
+		}
+
+		// 협상 상태를 바꾸는 메시지는 협상 큐에서 순서대로 처리
+		switch payload["type"] {
+		case "offer":
+			peer.enqueue(func() { msc.handleOffer(c, peer, payload) })
+
+		case "answer":
+			peer.enqueue(func() { msc.handleAnswer(peer, payload) })
+
+		case "iceCandidate":
+			peer.enqueue(func() { msc.handleICECandidate(peer, payload) })
+		}
+	}
+}
+
+func (msc *MediaSocketController) cleanupConnection(c *websocket.Conn) {
+	msc.mu.Lock()
+	defer msc.mu.Unlock()
+
+	// 모든 팀을 돌면서 해당 conn이 있는지 찾고 제거
+	for teamID, connMap := range msc.teams {
+		member, ok := connMap[c]
+		if !ok {
+			continue
+		}
+		member.pc.Close()
+		delete(connMap, c)
+		if len(connMap) == 0 {
+			delete(msc.teams, teamID)
+		}
+
+		// 이 연결의 트랙을 남은 연결에서 빼고 트랙 맵도 제거
+		if trackMap, ok2 := msc.teamsTracks[teamID]; ok2 {
+			for _, track := range trackMap[c] {
+				for _, other := range connMap {
+					track.unsubscribe(other.pc)
+				}
+			}
+			delete(trackMap, c)
+			if len(trackMap) == 0 {
+				delete(msc.teamsTracks, teamID)
+			}
+		}
+		msc.broadcastStreamsLocked(teamID)
+		break
+	}
+}
+
+// removeTrack: publisher의 원격 트랙이 끝났을 때(예: 화면 공유 중지) 팀에서 그 트랙을 뺍니다.
+// 연결이 정리되면서 이미 빠졌으면 아무것도 하지 않습니다.
+func (msc *MediaSocketController) removeTrack(teamID string, c *websocket.Conn, track *mediaTrack) {
+	msc.mu.Lock()
+	defer msc.mu.Unlock()
+
+	tracks := msc.teamsTracks[teamID][c]
+	for i, t := range tracks {
+		if t != track {
+			continue
+		}
+		msc.teamsTracks[teamID][c] = append(tracks[:i:i], tracks[i+1:]...)
+		for otherConn, other := range msc.teams[teamID] {
+			if otherConn != c {
+				track.unsubscribe(other.pc)
+			}
+		}
+		msc.broadcastStreamsLocked(teamID)
+		return
+	}
+}
+
+// streamsLocked: c가 받는 트랙, 즉 같은 팀의 다른 연결이 보내는 트랙 목록. msc.mu를 잡고 불러야 합니다.
+func (msc *MediaSocketController) streamsLocked(teamID string, c *websocket.Conn) models.MediaStreamsMessage {
+	msg := models.MediaStreamsMessage{Type: "streams", Tracks: []models.MediaTrackInfo{}}
+	for otherConn, tracks := range msc.teamsTracks[teamID] {
+		if otherConn == c {
+			continue
+		}
+		for _, track := range tracks {
+			msg.Tracks = append(msg.Tracks, track.info)
+		}
+	}
+	sort.Slice(msg.Tracks, func(i, j int) bool {
+		if msg.Tracks[i].StreamID != msg.Tracks[j].StreamID {
+			return msg.Tracks[i].StreamID < msg.Tracks[j].StreamID
+		}
+		return msg.Tracks[i].TrackID < msg.Tracks[j].TrackID
+	})
+	return msg
+}
+
+// broadcastStreamsLocked: 팀의 모든 연결에 각자 받는 트랙 목록을 보냅니다. msc.mu를 잡고 불러야 합니다.
+// 트랙이 더해질 때는 이 메시지가 그 트랙을 담은 서버 re-offer보다 먼저 나갑니다.
+func (msc *MediaSocketController) broadcastStreamsLocked(teamID string) {
+	for conn, member := range msc.teams[teamID] {
+		member.peer.send(msc.streamsLocked(teamID, conn))
+	}
+}
+
+// 클라이언트 offer를 처리 -> 서버가 Answer. 첫 offer면 팀에 들어가고, 그 뒤의 offer는 같은 PeerConnection의 재협상입니다.
+// {"type":"offer","sdp":"...","teamId":"...","sources":{"<MediaStream id>":"screen"}}
+// sources는 선택이며, 화면 공유를 시작할 때처럼 재협상 offer에서 더할 수 있습니다.
+func (msc *MediaSocketController) handleOffer(c *websocket.Conn, peer *mediaPeer, payload map[string]interface{}) {
+	sources, err := parseMediaSources(payload["sources"])
+	if err != nil {
+		peer.sendError("Invalid sources: " + err.Error())
+		return
+	}
+	peer.setSources(sources)
+
+	sdp, _ := payload["sdp"].(string)
+	if peer.pc != nil {
+		msc.answerOffer(peer, sdp)
+		return
+	}
+
+	teamID, _ := payload["teamId"].(string)
+	if !msc.canJoinTeam(c, teamID) {
+		log.Printf("Denied media join to team=%s conn=%p\n", teamID, c)
+		peer.sendError("Not a member of this team")
+		return
+	}
+	// 서버 쪽도 브라우저와 같은 방식으로 이 연결의 사용자에 묶인 자격 증명을 씀
+	var userID string
+	if claims, ok := c.Locals("user").(*middleware.CustomClaims); ok {
+		userID = claims.UserID
+	}
+	// 스트림 메타데이터의 트랙 소유자. 토큰이 없는 연결은 연결별로 구분만 함
+	// 같은 사용자가 여러 탭으로 들어와도 stream id가 겹치지 않게 연결 ID를 붙임
+	connectionID := utils.GenerateID()
+	participantID := userID
+	if participantID == "" {
+		participantID = "conn-" + connectionID
+	}
+	servers, _ := msc.iceServers(userID)
+	iceServers := make([]webrtc.ICEServer, 0, len(servers))
+	for _, server := range servers {
+		iceServers = append(iceServers, webrtc.ICEServer{URLs: server.URLs, Username: server.Username, Credential: server.Credential})
+	}
+	peerConnection, err := msc.api.NewPeerConnection(webrtc.Configuration{ICEServers: iceServers})
+	if err != nil {
+		log.Println("Failed to create PeerConnection:", err)
+		return
+	}
+
+	// 오디오는 sendrecv를 미리 둠(받기만 하는 오디오 클라이언트 호환). 비디오 m-line은 클라이언트 offer와 중계 트랙을 따라 생김
+	_, err = peerConnection.AddTransceiverFromKind(
+		webrtc.RTPCodecTypeAudio,
+		webrtc.RTPTransceiverInit{Direction: webrtc.RTPTransceiverDirectionSendrecv},
+	)
+	if err != nil {
+		log.Println("AddTransceiverFromKind failed:", err)
+	}
+
+	// 1) OnNegotiationNeeded: 서버가 트랙을 더하거나 빼면 이 콜백이 뜸 -> 협상 큐가 stable일 때 re-offer
+	peerConnection.OnNegotiationNeeded(func() {
+		log.Println("[OnNegotiationNeeded] => re-offer queued")
+		peer.requestNegotiation()
+	})
+
+	// trickle ICE: 서버 candidate는 모이는 대로 보냄. nil은 수집 완료 신호
+	peerConnection.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+		if candidate == nil {
+			return
+		}
+		peer.sendCandidate(localCandidateInit(peerConnection, candidate))
+	})
+
+	// (2) OnTrack -> 같은 팀의 다른 피어들에게만 RTP 중계
+	peerConnection.OnTrack(func(remoteTrack *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
+		source := mediaSource(remoteTrack.Kind(), peer.source(remoteTrack.StreamID()))
+		log.Printf("Got remote %s track from team=%s conn=%p, trackID=%s source=%s\n", remoteTrack.Kind(), teamID, c, remoteTrack.ID(), source)
+
+		track, err := newMediaTrack(participantID, connectionID, source, remoteTrack, peerConnection)
+		if err != nil {
+			log.Println("Failed to create local track:", err)
+			return
+		}
+
+		msc.mu.Lock()
+		if _, ok := msc.teams[teamID][c]; !ok {
+			// 연결이 이미 정리됨
+			msc.mu.Unlock()
+			return
+		}
+		// 같은 팀만 순회
+		for otherConn, other := range msc.teams[teamID] {
+			if otherConn == c {
+				continue
+			}
+			if err := track.subscribe(other); err != nil {
+				log.Printf("AddTrack error: %v\n", err)
+			} else {
+				log.Printf("Forward track to team=%s conn=%p\n", teamID, otherConn)
+			}
+		}
+		// 이 conn이 소유한 트랙 목록에 저장
+		msc.teamsTracks[teamID][c] = append(msc.teamsTracks[teamID][c], track)
+		msc.broadcastStreamsLocked(teamID)
+		msc.mu.Unlock()
+
+		// RTP를 localTrack으로 계속 포워딩하고, 원격 트랙이 끝나면 팀에서 뺌
+		go func() {
+			track.forward(remoteTrack)
+			msc.removeTrack(teamID, c, track)
+		}()
+	})
+
+	// (3) 팀 맵에 등록
+	msc.mu.Lock()
+	if msc.teams[teamID] == nil {
+		msc.teams[teamID] = make(map[*websocket.Conn]*mediaMember)
+	}
+	if msc.teamsTracks[teamID] == nil {
+		msc.teamsTracks[teamID] = make(map[*websocket.Conn][]*mediaTrack)
+	}
+
+	msc.teams[teamID][c] = &mediaMember{peer: peer, pc: peerConnection, participantID: participantID}
+	msc.teamsTracks[teamID][c] = []*mediaTrack{}
+	msc.mu.Unlock()
+	peer.pc = peerConnection
+
+	// 4) offer에 answer
+	if !msc.answerOffer(peer, sdp) {
+		return
+	}
+
+	// 5) 이미 존재하던 다른 사람들의 track도 이 유저에게 addTrack하고 트랙 목록을 보냄
+	// OnTrack이 (3) 이후에 이미 더한 트랙은 subscribe가 건너뜀
+	msc.mu.Lock()
+	member := msc.teams[teamID][c]
+	for otherConn, otherTracks := range msc.teamsTracks[teamID] {
+		if otherConn == c || member == nil {
+			continue
+		}
+		for _, track := range otherTracks {
+			if err := track.subscribe(member); err != nil {
+				log.Println("AddTrack for existing track error:", err)
+			}
+		}
+	}
+	peer.send(msc.streamsLocked(teamID, c))
+	msc.mu.Unlock()
+	// AddTrack으로 OnNegotiationNeeded가 뜨고, 협상 큐가 이 작업 뒤에 re-offer를 보냄
+}
+
+// answerOffer: 클라이언트 offer에 답합니다. 서버 offer의 answer를 기다리는 중이면(glare) impolite 쪽으로서 무시하고 false를 반환합니다.
+func (msc *MediaSocketController) answerOffer(peer *mediaPeer, sdp string) bool {
+	pc := peer.pc
+	if pc.SignalingState() != webrtc.SignalingStateStable {
+		peer.negotiation.ignoreOffer = true
+		mediaNegotiationCollisions.Inc()
+		log.Println("Ignoring client offer: server offer is outstanding (glare)")
+		return false
+	}
+	peer.negotiation.ignoreOffer = false
+
+	// SetRemoteDescription(offer) → CreateAnswer → SetLocalDescription(answer)
+	if err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: sdp}); err != nil {
+		log.Println("Failed to set remote description:", err)
+		peer.sendError("Invalid offer")
+		return false
+	}
+	msc.flushCandidates(peer)
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		log.Println("Failed to create Answer:", err)
+		return false
+	}
+	if err := pc.SetLocalDescription(answer); err != nil {
+		log.Println("Failed to set local description:", err)
+		return false
+	}
+
+	// answer -> 클라이언트로. 서버 candidate는 OnICECandidate로 따로 감
+	peer.sendAnswer(answer)
+	return true
+}
+
+// 클라이언트가 서버 re-offer에 대한 answer를 보냈을 때
+func (msc *MediaSocketController) handleAnswer(peer *mediaPeer, payload map[string]interface{}) {
+	if peer.pc == nil {
+		log.Println("handleAnswer: no PeerConnection found for this client")
+		return
+	}
+	if state := peer.pc.SignalingState(); state != webrtc.SignalingStateHaveLocalOffer {
+		log.Printf("handleAnswer: ignoring answer in signaling state %s\n", state)
+		return
+	}
+	sdp, _ := payload["sdp"].(string)
+	answer := webrtc.SessionDescription{
+		Type: webrtc.SDPTypeAnswer,
+		SDP:  sdp,
+	}
+	if err := peer.pc.SetRemoteDescription(answer); err != nil {
+		log.Println("handleAnswer: SetRemoteDescription error:", err)
+		return
+	}
+	log.Println("handleAnswer: remoteDescription set (answer)")
+	msc.flushCandidates(peer)
+	peer.requestPendingKeyframes()
+}
+
+// handleICECandidate: 클라이언트 candidate를 추가합니다. remote description 전에 온 것은 모아 두었다가 설정 직후 추가합니다.
+// {"type":"iceCandidate","candidate":{"candidate":"candidate:...","sdpMid":"0","sdpMLineIndex":0,"usernameFragment":"..."}}
+// candidate가 null이거나 빈 문자열이면 end-of-candidates입니다.
+func (msc *MediaSocketController) handleICECandidate(peer *mediaPeer, payload map[string]interface{}) {
+	candidate, err := parseICECandidate(payload["candidate"])
+	if err != nil {
+		log.Println("Invalid ICE candidate:", err)
+		peer.sendError("Invalid ICE candidate: " + err.Error())
+		return
+	}
+
+	if peer.pc == nil || peer.pc.RemoteDescription() == nil {
+		if len(peer.pendingCandidates) >= maxPendingICECandidates {
+			peer.sendError("Too many ICE candidates before offer")
+			return
+		}
+		peer.pendingCandidates = append(peer.pendingCandidates, candidate)
+		return
+	}
+	// 무시한 offer에 딸린 candidate는 실패해도 괜찮음
+	if err := peer.pc.AddICECandidate(candidate); err != nil && !peer.negotiation.ignoreOffer {
+		log.Println("AddICECandidate error:", err)
+	}
+}
+
+// flushCandidates: remote description이 설정된 뒤 모아 둔 candidate를 추가합니다.
+func (msc *MediaSocketController) flushCandidates(peer *mediaPeer) {
+	pending := peer.pendingCandidates
+	peer.pendingCandidates = nil
+	for _, candidate := range pending {
+		if err := peer.pc.AddICECandidate(candidate); err != nil {
+			log.Println("AddICECandidate error:", err)
+		}
+	}
+}
+
+// parseICECandidate: RTCIceCandidateInit 모양의 JSON 객체를 읽습니다. 필드 타입이 틀리면 오류를 반환합니다.
+func parseICECandidate(raw interface{}) (webrtc.ICECandidateInit, error) {
+	var candidate webrtc.ICECandidateInit
+	switch raw.(type) {
+	case nil:
+		return candidate, nil
+	case map[string]interface{}:
+	default:
+		return candidate, errors.New("candidate must be an object")
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return candidate, err
+	}
+	if err := json.Unmarshal(data, &candidate); err != nil {
+		return candidate, err
+	}
+	return candidate, nil
+}
+
+// parseMediaSources: offer의 sources({"<MediaStream id>":"microphone|camera|screen"})를 읽습니다.
+func parseMediaSources(raw interface{}) (map[string]string, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	entries, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("sources must be an object")
+	}
+	sources := make(map[string]string, len(entries))
+	for streamID, value := range entries {
+		source, _ := value.(string)
+		switch source {
+		case models.MediaSourceMicrophone, models.MediaSourceCamera, models.MediaSourceScreen:
+			sources[streamID] = source
+		default:
+			return nil, fmt.Errorf("source of stream %q must be one of microphone, camera, screen", streamID)
+		}
+	}
+	return sources, nil
+}
+
+// localCandidateInit: 서버 candidate를 클라이언트로 보낼 모양으로 바꿉니다.
+// pion의 ToJSON은 sdpMid를 비워 두므로, BUNDLE로 묶인 첫 m-line의 mid와 ice-ufrag를 local description에서 채웁니다.
+func localCandidateInit(pc *webrtc.PeerConnection, candidate *webrtc.ICECandidate) webrtc.ICECandidateInit {
+	init := candidate.ToJSON()
+	desc := pc.LocalDescription()
+	if desc == nil {
+		return init
+	}
+	var mid, ufrag string
+	for _, line := range strings.Split(desc.SDP, "\n") {
+		line = strings.TrimSpace(line)
+		if v, ok := strings.CutPrefix(line, "a=mid:"); ok && mid == "" {
+			mid = v
+		}
+		if v, ok := strings.CutPrefix(line, "a=ice-ufrag:"); ok && ufrag == "" {
+			ufrag = v
+		}
+	}
+	if mid != "" {
+		init.SDPMid = &mid
+	}
+	if ufrag != "" {
+		init.UsernameFragment = &ufrag
+	}
+	return init
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"log"
+	"sync"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// SFU의 재협상은 "perfect negotiation" 패턴을 따릅니다. 서버는 impolite, 클라이언트는 polite 쪽입니다.
+//   - 서버 offer의 answer를 기다리는 중에 클라이언트 offer가 오면(glare) 서버는 그 offer를 무시합니다.
+//     클라이언트는 자기 offer를 rollback하고 서버 offer에 답한 뒤, stable로 돌아오면 다시 offer합니다.
+//     브라우저는 have-local-offer에서 setRemoteDescription(offer)를 부르면 알아서 rollback하므로 그대로 polite 쪽이 됩니다.
+//   - offer/answer/candidate 처리는 연결마다 한 goroutine(협상 큐)에서 도착 순서대로 실행합니다.
+//   - 트랙이 더해져 재협상이 필요해지면 표시만 해 두었다가 signaling state가 stable일 때 offer 하나로 묶어 보냅니다.
+
+var mediaNegotiationCollisions = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "webrtc_offer_collisions_total",
+	Help: "Client offers ignored because a server offer was outstanding (glare).",
+})
+
+// mediaNegotiation: 연결 하나의 협상 큐. needsOffer와 ignoreOffer는 큐 goroutine에서만 씁니다.
+type mediaNegotiation struct {
+	ops       chan func()
+	negotiate chan struct{}
+	done      chan struct{}
+	wg        sync.WaitGroup
+
+	needsOffer bool
+	// 마지막 클라이언트 offer를 glare로 무시했는지. 그 offer의 candidate는 추가에 실패해도 괜찮습니다.
+	ignoreOffer bool
+}
+
+// startNegotiation: 협상 큐 goroutine을 시작합니다.
+func (p *mediaPeer) startNegotiation() {
+	p.negotiation = mediaNegotiation{
+		ops:       make(chan func(), 32),
+		negotiate: make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+	p.negotiation.wg.Add(1)
+	go p.runNegotiation()
+}
+
+// stopNegotiation: 큐를 닫고 실행 중인 작업이 끝날 때까지 기다립니다. 남은 작업은 버립니다.
+func (p *mediaPeer) stopNegotiation() {
+	close(p.negotiation.done)
+	p.negotiation.wg.Wait()
+}
+
+// enqueue: 클라이언트 메시지 처리를 큐에 넣습니다. 큐가 가득 차면 읽기 goroutine이 기다립니다.
+func (p *mediaPeer) enqueue(op func()) {
+	select {
+	case p.negotiation.ops <- op:
+	case <-p.negotiation.done:
+	}
+}
+
+// requestNegotiation: 재협상이 필요하다고 표시합니다. pion 콜백에서 불리며 막히지 않습니다.
+// 이미 요청이 쌓여 있으면 하나로 합쳐집니다.
+func (p *mediaPeer) requestNegotiation() {
+	select {
+	case p.negotiation.negotiate <- struct{}{}:
+	default:
+	}
+}
+
+func (p *mediaPeer) runNegotiation() {
+	defer p.negotiation.wg.Done()
+	for {
+		select {
+		case <-p.negotiation.done:
+			return
+		case op := <-p.negotiation.ops:
+			op()
+		case <-p.negotiation.negotiate:
+			p.negotiation.needsOffer = true
+		}
+		p.offerIfNeeded()
+	}
+}
+
+// offerIfNeeded: 재협상이 밀려 있고 stable이면 서버 offer를 보냅니다.
+// 첫 클라이언트 offer에 답하기 전이나 answer를 기다리는 중에는 다음 기회로 미룹니다.
+func (p *mediaPeer) offerIfNeeded() {
+	pc := p.pc
+	if !p.negotiation.needsOffer || pc == nil || pc.RemoteDescription() == nil || pc.SignalingState() != webrtc.SignalingStateStable {
+		return
+	}
+	p.negotiation.needsOffer = false
+
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		log.Println("CreateOffer error:", err)
+		return
+	}
+	if err := pc.SetLocalDescription(offer); err != nil {
+		log.Println("SetLocalDescription error:", err)
+		return
+	}
+	p.send(map[string]interface{}{
+		"type": "offer",
+		"sdp":  offer.SDP,
+	})
+	log.Println("[Server -> Client] re-offer sent")
+}
@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"go-server/models"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// keyframeRequestInterval: 한 트랙의 publisher에게 키프레임을 다시 요청하기까지의 최소 간격.
+// 여러 사람이 한꺼번에 들어와도 publisher가 키프레임을 연달아 만들지 않게 합니다.
+const keyframeRequestInterval = 500 * time.Millisecond
+
+var mediaKeyframeRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "webrtc_keyframe_requests_total",
+	Help: "PLIs sent to video publishers, by reason (subscribe: new subscriber finished negotiating, relay: PLI/FIR from a subscriber).",
+}, []string{"reason"})
+
+// newMediaAPI: SFU가 중계하는 코덱(opus, VP8, VP9, H264)만 등록한 pion API.
+// 받은 RTP를 payload type 그대로 넘기므로 publisher와 subscriber가 같은 코덱 목록으로 협상해야 합니다.
+func newMediaAPI() (*webrtc.API, error) {
+	mediaEngine := &webrtc.MediaEngine{}
+	videoFeedback := []webrtc.RTCPFeedback{
+		{Type: "goog-remb"},
+		{Type: "ccm", Parameter: "fir"},
+		{Type: "nack"},
+		{Type: "nack", Parameter: "pli"},
+	}
+	codecs := []struct {
+		kind   webrtc.RTPCodecType
+		params webrtc.RTPCodecParameters
+	}{
+		{webrtc.RTPCodecTypeAudio, webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2, SDPFmtpLine: "minptime=10;useinbandfec=1"},
+			PayloadType:        111,
+		}},
+		{webrtc.RTPCodecTypeVideo, webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000, RTCPFeedback: videoFeedback},
+			PayloadType:        96,
+		}},
+		{webrtc.RTPCodecTypeVideo, webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP9, ClockRate: 90000, SDPFmtpLine: "profile-id=0", RTCPFeedback: videoFeedback},
+			PayloadType:        98,
+		}},
+		{webrtc.RTPCodecTypeVideo, webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264, ClockRate: 90000, SDPFmtpLine: "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f", RTCPFeedback: videoFeedback},
+			PayloadType:        102,
+		}},
+	}
+	for _, codec := range codecs {
+		if err := mediaEngine.RegisterCodec(codec.params, codec.kind); err != nil {
+			return nil, err
+		}
+	}
+
+	// NACK, RTCP 리포트, TWCC
+	registry := &interceptor.Registry{}
+	if err := webrtc.RegisterDefaultInterceptors(mediaEngine, registry); err != nil {
+		return nil, err
+	}
+	return webrtc.NewAPI(webrtc.WithMediaEngine(mediaEngine), webrtc.WithInterceptorRegistry(registry)), nil
+}
+
+// mediaSource: 트랙의 출처. 클라이언트가 스트림에 적은 값이 우선입니다.
+func mediaSource(kind webrtc.RTPCodecType, declared string) string {
+	if declared != "" {
+		return declared
+	}
+	if kind == webrtc.RTPCodecTypeVideo {
+		return models.MediaSourceCamera
+	}
+	return models.MediaSourceMicrophone
+}
+
+// mediaStreamID: 중계 트랙의 stream id. 마이크와 카메라는 한 스트림(립싱크), 화면 공유는 따로 묶습니다.
+// 같은 참가자의 다른 연결(탭)과 섞이지 않도록 연결 ID를 넣습니다.
+func mediaStreamID(participantID, connectionID, source string) string {
+	prefix := participantID + ":" + connectionID
+	if source == models.MediaSourceScreen {
+		return prefix + ":screen"
+	}
+	return prefix + ":user"
+}
+
+// mediaTrack: publisher 하나의 원격 트랙을 같은 팀 subscriber들에게 중계하는 로컬 트랙
+type mediaTrack struct {
+	local     *webrtc.TrackLocalStaticRTP
+	info      models.MediaTrackInfo
+	publisher *webrtc.PeerConnection
+	ssrc      webrtc.SSRC
+
+	mu            sync.Mutex
+	lastKeyframes time.Time
+}
+
+func newMediaTrack(participantID, connectionID, source string, remote *webrtc.TrackRemote, publisher *webrtc.PeerConnection) (*mediaTrack, error) {
+	streamID := mediaStreamID(participantID, connectionID, source)
+	local, err := webrtc.NewTrackLocalStaticRTP(remote.Codec().RTPCodecCapability, remote.ID(), streamID)
+	if err != nil {
+		return nil, err
+	}
+	return &mediaTrack{
+		local: local,
+		info: models.MediaTrackInfo{
+			TrackID:       remote.ID(),
+			StreamID:      streamID,
+			ParticipantID: participantID,
+			ConnectionID:  connectionID,
+			Kind:          remote.Kind().String(),
+			Source:        source,
+			MimeType:      remote.Codec().MimeType,
+		},
+		publisher: publisher,
+		ssrc:      remote.SSRC(),
+	}, nil
+}
+
+func (t *mediaTrack) isVideo() bool {
+	return t.info.Kind == webrtc.RTPCodecTypeVideo.String()
+}
+
+// requestKeyframe: publisher에게 PLI를 보냅니다. keyframeRequestInterval 안에 다시 불리면 건너뜁니다.
+// subscriber가 보낸 FIR도 PLI로 바꿔 보냅니다(브라우저 publisher는 둘 다 키프레임으로 답함).
+func (t *mediaTrack) requestKeyframe(reason string) {
+	if !t.isVideo() {
+		return
+	}
+	t.mu.Lock()
+	if time.Since(t.lastKeyframes) < keyframeRequestInterval {
+		t.mu.Unlock()
+		return
+	}
+	t.lastKeyframes = time.Now()
+	t.mu.Unlock()
+
+	if err := t.publisher.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: uint32(t.ssrc)}}); err != nil {
+		log.Println("Failed to send PLI to publisher:", err)
+		return
+	}
+	mediaKeyframeRequests.WithLabelValues(reason).Inc()
+}
+
+// forward: 원격 트랙의 RTP를 로컬 트랙으로 계속 넘깁니다. 원격 트랙이 끝나면 반환합니다.
+func (t *mediaTrack) forward(remote *webrtc.TrackRemote) {
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := remote.Read(buf)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Println("remoteTrack read error:", err)
+			}
+			return
+		}
+		if _, err := t.local.Write(buf[:n]); err != nil && !errors.Is(err, io.ErrClosedPipe) {
+			log.Println("localTrack write error:", err)
+			return
+		}
+	}
+}
+
+// relayRTCP: subscriber 쪽 sender의 RTCP를 읽습니다. 읽어야 interceptor(NACK 등)가 동작하고,
+// PLI/FIR은 publisher에게 키프레임 요청으로 넘깁니다. sender가 멈추면 반환합니다.
+func (t *mediaTrack) relayRTCP(sender *webrtc.RTPSender) {
+	for {
+		packets, _, err := sender.ReadRTCP()
+		if err != nil {
+			return
+		}
+		for _, packet := range packets {
+			switch packet.(type) {
+			case *rtcp.PictureLossIndication, *rtcp.FullIntraRequest:
+				t.requestKeyframe("relay")
+			}
+		}
+	}
+}
+
+// subscribe: member의 pc에 트랙을 더하고 RTCP 중계를 시작합니다. 이미 보내고 있으면 아무것도 하지 않습니다.
+// 키프레임은 재협상이 끝난 뒤(handleAnswer) 요청합니다. 지금 요청하면 subscriber가 받기 전에 지나갑니다.
+func (t *mediaTrack) subscribe(member *mediaMember) error {
+	if hasTrack(member.pc, t.local) {
+		return nil
+	}
+	sender, err := member.pc.AddTrack(t.local)
+	if err != nil {
+		return err
+	}
+	go t.relayRTCP(sender)
+	if t.isVideo() {
+		member.peer.awaitKeyframe(t)
+	}
+	return nil
+}
+
+// unsubscribe: pc에서 트랙을 뺍니다. 재협상은 OnNegotiationNeeded가 처리합니다.
+func (t *mediaTrack) unsubscribe(pc *webrtc.PeerConnection) {
+	for _, sender := range pc.GetSenders() {
+		if sender.Track() == t.local {
+			if err := pc.RemoveTrack(sender); err != nil && !errors.Is(err, webrtc.ErrConnectionClosed) {
+				log.Println("RemoveTrack error:", err)
+			}
+		}
+	}
+}
+
+// negotiated: pc가 보내는 track이 offer에 들어가 mid를 받았는지
+func negotiated(pc *webrtc.PeerConnection, track webrtc.TrackLocal) bool {
+	for _, transceiver := range pc.GetTransceivers() {
+		if sender := transceiver.Sender(); sender != nil && sender.Track() == track {
+			return transceiver.Mid() != ""
+		}
+	}
+	return false
+}
+
+// hasTrack: pc가 이미 track을 보내고 있는지
+func hasTrack(pc *webrtc.PeerConnection, track webrtc.TrackLocal) bool {
+	for _, sender := range pc.GetSenders() {
+		if sender.Track() == track {
+			return true
+		}
+	}
+	return false
+}
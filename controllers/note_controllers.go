@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"go-server/models"
 	"go-server/repository"
 
@@ -21,7 +22,10 @@ func (nc *NoteController) CreateNote(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid JSON"})
 	}
 
-	objectID, err := nc.repo.SaveNote(note)
+	objectID, err := nc.repo.SaveNote(note, currentUserID(c))
+	if errors.Is(err, repository.ErrYDocActive) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Document has collaborative edits; edit it through the live session"})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save note"})
 	}
@@ -62,6 +66,37 @@ func (nc *NoteController) UpdateNoteTitle(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
 }
 
+func (nc *NoteController) UpdateNoteContent(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var request struct {
+		Note    string `json:"note"`
+		Version *int   `json:"version"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid JSON"})
+	}
+	if request.Version == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Missing version"})
+	}
+
+	version, err := nc.repo.UpdateNoteContent(id, request.Note, *request.Version, currentUserID(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrVersionConflict):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":           "Note was modified by someone else",
+				"current_version": version,
+			})
+		case errors.Is(err, repository.ErrNoteNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Note not found"})
+		case errors.Is(err, repository.ErrYDocActive):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Document has collaborative edits; edit it through the live session"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update note content"})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "version": version})
+}
+
 func (nc *NoteController) DeleteNoteByID(c *fiber.Ctx) error {
 	id := c.Params("id")
 	if err := nc.repo.DeleteNoteByID(id); err != nil {
@@ -69,3 +104,75 @@ func (nc *NoteController) DeleteNoteByID(c *fiber.Ctx) error {
 	}
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
 }
+
+func (nc *NoteController) GetNoteRevisions(c *fiber.Ctx) error {
+	id := c.Params("id")
+	revisions, err := nc.repo.FindNoteRevisions(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to find note revisions"})
+	}
+	return c.Status(fiber.StatusOK).JSON(revisions)
+}
+
+func (nc *NoteController) GetNoteRevision(c *fiber.Ctx) error {
+	id := c.Params("id")
+	rev, err := c.ParamsInt("rev")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid revision"})
+	}
+
+	revision, err := nc.repo.FindNoteRevision(id, rev)
+	if err != nil {
+		if errors.Is(err, repository.ErrRevisionNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Revision not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to find note revision"})
+	}
+	return c.Status(fiber.StatusOK).JSON(revision)
+}
+
+func (nc *NoteController) RestoreNoteRevision(c *fiber.Ctx) error {
+	id := c.Params("id")
+	rev, err := c.ParamsInt("rev")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid revision"})
+	}
+
+	version, err := nc.repo.RestoreNoteRevision(id, rev, currentUserID(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRevisionNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Revision not found"})
+		case errors.Is(err, repository.ErrNoteNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Note not found"})
+		case errors.Is(err, repository.ErrYDocActive):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Document has collaborative edits; edit it through the live session"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to restore note revision"})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "version": version})
+}
+
+// ResolveNoteTeam은 :id note의 team_id를 돌려줍니다. middleware.RequireTeamMember와 함께 사용합니다.
+func (nc *NoteController) ResolveNoteTeam(c *fiber.Ctx) (string, error) {
+	note, err := nc.repo.FindNoteByID(c.Params("id"))
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusNotFound, "Note not found")
+	}
+	return note.TeamID, nil
+}
+
+// ResolveNewNoteTeam은 생성 요청 본문의 team_id를 돌려줍니다.
+// 본문에 기존 id가 있으면 다른 팀 note를 덮어쓰지 못하도록 같은 팀인지도 확인합니다.
+func (nc *NoteController) ResolveNewNoteTeam(c *fiber.Ctx) (string, error) {
+	var note models.Note
+	if err := c.BodyParser(&note); err != nil {
+		return "", fiber.NewError(fiber.StatusBadRequest, "Invalid JSON")
+	}
+	if note.ID != "" {
+		if existing, err := nc.repo.FindNoteByID(note.ID); err == nil && existing.TeamID != note.TeamID {
+			return "", fiber.NewError(fiber.StatusForbidden, "Note belongs to another team")
+		}
+	}
+	return note.TeamID, nil
+}
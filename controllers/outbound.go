@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"go-server/configs"
+
+	"github.com/gofiber/websocket/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// ErrSlowConsumer: disconnect 정책에서 큐가 가득 차 연결을 끊었습니다.
+	ErrSlowConsumer = errors.New("outbound queue full, disconnecting slow consumer")
+	// ErrOutboundClosed: 이미 닫힌 연결에 보내려 했습니다.
+	ErrOutboundClosed = errors.New("outbound queue closed")
+)
+
+var (
+	outboundQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "websocket_outbound_queue_depth",
+		Help: "Messages waiting in WebSocket outbound queues, summed over connections.",
+	}, []string{"endpoint"})
+	outboundDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "websocket_outbound_dropped_total",
+		Help: "Messages dropped from full WebSocket outbound queues.",
+	}, []string{"endpoint"})
+	outboundSlowConsumers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "websocket_slow_consumer_disconnects_total",
+		Help: "WebSocket connections closed because their outbound queue was full.",
+	}, []string{"endpoint"})
+)
+
+// outboundConn: 송신 큐가 쓰는 WebSocket 연결의 메서드. 단위 테스트에서 가짜 연결로 바꿀 수 있습니다.
+type outboundConn interface {
+	WriteMessage(messageType int, data []byte) error
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+	SetWriteDeadline(t time.Time) error
+	SetReadDeadline(t time.Time) error
+	NetConn() net.Conn
+}
+
+// outboundMessage: 큐의 한 칸. 순서대로 보내야 하는 여러 프레임(예: y-websocket 동기화 응답)을 한 칸에 담을 수 있습니다.
+type outboundMessage struct {
+	messageType int
+	frames      [][]byte
+}
+
+// outboundQueue: 연결 하나의 제한된 송신 큐와 writer goroutine
+// WriteMessage는 큐에 넣기만 하므로 컨트롤러 락을 잡은 채 호출해도 막히지 않습니다.
+// WSConn을 구현하므로 시그널링 방에 연결 대신 들어갑니다.
+type outboundQueue struct {
+	conn     outboundConn
+	config   configs.OutboundConfig
+	endpoint string
+	mu       sync.Mutex
+	pending  []outboundMessage
+	closed   bool
+	ready    chan struct{}
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func newOutboundQueue(conn outboundConn, config configs.OutboundConfig, endpoint string) *outboundQueue {
+	if config.QueueSize <= 0 {
+		config.QueueSize = configs.DefaultOutboundConfig().QueueSize
+	}
+	q := &outboundQueue{
+		conn:     conn,
+		config:   config,
+		endpoint: endpoint,
+		ready:    make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	q.wg.Add(1)
+	go q.run()
+	return q
+}
+
+// WriteMessage: 메시지를 큐에 넣습니다. 큐가 가득 차면 정책에 따라 가장 오래된 메시지를 버리거나 연결을 끊습니다.
+func (q *outboundQueue) WriteMessage(messageType int, data []byte) error {
+	return q.enqueue(outboundMessage{messageType: messageType, frames: [][]byte{data}})
+}
+
+// WriteBatch: 여러 프레임을 한 칸으로 넣습니다. 프레임은 순서대로, 다른 메시지와 섞이지 않고 보내집니다.
+func (q *outboundQueue) WriteBatch(messageType int, frames [][]byte) error {
+	return q.enqueue(outboundMessage{messageType: messageType, frames: frames})
+}
+
+func (q *outboundQueue) enqueue(msg outboundMessage) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return ErrOutboundClosed
+	}
+	if len(q.pending) >= q.config.QueueSize {
+		if q.config.Overflow == configs.OverflowDisconnect {
+			q.shutdownLocked()
+			// stop이 기다리도록 락 안에서 등록
+			q.wg.Add(1)
+			q.mu.Unlock()
+			outboundSlowConsumers.WithLabelValues(q.endpoint).Inc()
+			log.Printf("[%s] Disconnecting slow consumer: %d messages queued\n", q.endpoint, q.config.QueueSize)
+			// writer가 막힌 쓰기를 잡고 있으면 WriteControl도 기다리므로 따로 보냄
+			go q.sendClose(websocket.ClosePolicyViolation, "slow consumer")
+			return ErrSlowConsumer
+		}
+		q.pending = q.pending[1:]
+		outboundDropped.WithLabelValues(q.endpoint).Inc()
+		outboundQueueDepth.WithLabelValues(q.endpoint).Dec()
+	}
+	q.pending = append(q.pending, msg)
+	outboundQueueDepth.WithLabelValues(q.endpoint).Inc()
+	q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Close: 큐를 닫고 연결의 읽기를 끝내 핸들러가 정리하게 합니다. 여러 번 불러도 됩니다.
+func (q *outboundQueue) Close() error {
+	q.shutdown()
+	_ = q.conn.SetReadDeadline(time.Now())
+	return nil
+}
+
+// stop: 핸들러가 끝날 때 부릅니다. 진행 중인 쓰기를 끊고 writer goroutine이 끝날 때까지 기다립니다.
+// 핸들러가 반환되면 연결 객체가 재사용되므로 그 뒤에 쓰면 안 됩니다.
+func (q *outboundQueue) stop() {
+	q.shutdown()
+	if netConn := q.conn.NetConn(); netConn != nil {
+		_ = netConn.SetWriteDeadline(time.Now())
+	}
+	q.wg.Wait()
+}
+
+// Len: 큐에 남은 메시지 수
+func (q *outboundQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// disconnect: 큐를 닫고 남은 메시지 대신 close 프레임을 보내 연결을 끊습니다.
+func (q *outboundQueue) disconnect(code int, reason string) {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.shutdownLocked()
+	q.wg.Add(1)
+	q.mu.Unlock()
+	go q.sendClose(code, reason)
+}
+
+// sendClose: close 프레임을 보내고 연결을 끊습니다. writer가 막힌 쓰기를 잡고 있으면 기다리므로 goroutine에서 부릅니다.
+func (q *outboundQueue) sendClose(code int, reason string) {
+	defer q.wg.Done()
+	closeWebSocket(q.conn, code, reason)
+}
+
+// shutdown: 큐를 닫고 남은 메시지를 버립니다.
+func (q *outboundQueue) shutdown() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.shutdownLocked()
+	}
+}
+
+func (q *outboundQueue) shutdownLocked() {
+	q.closed = true
+	outboundQueueDepth.WithLabelValues(q.endpoint).Sub(float64(len(q.pending)))
+	q.pending = nil
+	close(q.done)
+}
+
+func (q *outboundQueue) pop() (outboundMessage, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed || len(q.pending) == 0 {
+		return outboundMessage{}, false
+	}
+	msg := q.pending[0]
+	q.pending = q.pending[1:]
+	outboundQueueDepth.WithLabelValues(q.endpoint).Dec()
+	return msg, true
+}
+
+func (q *outboundQueue) run() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.done:
+			return
+		case <-q.ready:
+		}
+		for {
+			msg, ok := q.pop()
+			if !ok {
+				break
+			}
+			for _, frame := range msg.frames {
+				if q.config.WriteTimeout > 0 {
+					_ = q.conn.SetWriteDeadline(time.Now().Add(q.config.WriteTimeout))
+				}
+				if err := q.conn.WriteMessage(msg.messageType, frame); err != nil {
+					log.Printf("[%s] Write error: %v\n", q.endpoint, err)
+					_ = q.Close()
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"go-server/configs"
+
+	"github.com/gofiber/websocket/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingConn은 release가 닫힐 때까지 쓰기를 막는 outboundConn입니다. 클라이언트가 읽지 않는 상황을 흉내 냅니다.
+type blockingConn struct {
+	mu           sync.Mutex
+	written      []string
+	controls     []int
+	readDeadline bool
+	writing      chan struct{}
+	release      chan struct{}
+	once         sync.Once
+}
+
+func newBlockingConn() *blockingConn {
+	return &blockingConn{writing: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (b *blockingConn) WriteMessage(messageType int, data []byte) error {
+	b.once.Do(func() { close(b.writing) })
+	<-b.release
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.written = append(b.written, string(data))
+	return nil
+}
+
+func (b *blockingConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.controls = append(b.controls, messageType)
+	return nil
+}
+
+func (b *blockingConn) SetWriteDeadline(time.Time) error { return nil }
+
+func (b *blockingConn) SetReadDeadline(time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.readDeadline = true
+	return nil
+}
+
+func (b *blockingConn) NetConn() net.Conn { return nil }
+
+func (b *blockingConn) snapshot() ([]string, []int, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.written...), append([]int(nil), b.controls...), b.readDeadline
+}
+
+func TestOutboundQueue_DropOldest(t *testing.T) {
+	conn := newBlockingConn()
+	q := newOutboundQueue(conn, configs.OutboundConfig{QueueSize: 3, Overflow: configs.OverflowDropOldest}, "test")
+
+	assert.NoError(t, q.WriteMessage(websocket.TextMessage, []byte("m0")))
+	<-conn.writing // writer가 m0를 쓰다 막힘
+
+	// 큐가 가득 차도 WriteMessage는 막히지 않고 오래된 것부터 버림
+	for _, m := range []string{"m1", "m2", "m3", "m4", "m5"} {
+		assert.NoError(t, q.WriteMessage(websocket.TextMessage, []byte(m)))
+	}
+	assert.Equal(t, 3, q.Len())
+
+	close(conn.release)
+	assert.Eventually(t, func() bool {
+		written, _, _ := conn.snapshot()
+		return len(written) == 4
+	}, time.Second, 5*time.Millisecond)
+	q.stop()
+
+	written, _, _ := conn.snapshot()
+	assert.Equal(t, []string{"m0", "m3", "m4", "m5"}, written)
+}
+
+func TestOutboundQueue_DisconnectSlowConsumer(t *testing.T) {
+	conn := newBlockingConn()
+	q := newOutboundQueue(conn, configs.OutboundConfig{QueueSize: 2, Overflow: configs.OverflowDisconnect}, "test")
+
+	assert.NoError(t, q.WriteMessage(websocket.TextMessage, []byte("m0")))
+	<-conn.writing
+	assert.NoError(t, q.WriteMessage(websocket.TextMessage, []byte("m1")))
+	assert.NoError(t, q.WriteMessage(websocket.TextMessage, []byte("m2")))
+
+	assert.ErrorIs(t, q.WriteMessage(websocket.TextMessage, []byte("m3")), ErrSlowConsumer)
+	assert.ErrorIs(t, q.WriteMessage(websocket.TextMessage, []byte("m4")), ErrOutboundClosed)
+	assert.Zero(t, q.Len())
+
+	assert.Eventually(t, func() bool {
+		_, controls, readDeadline := conn.snapshot()
+		return len(controls) == 1 && readDeadline
+	}, time.Second, 5*time.Millisecond)
+	_, controls, _ := conn.snapshot()
+	assert.Equal(t, websocket.CloseMessage, controls[0])
+
+	close(conn.release)
+	q.stop()
+	written, _, _ := conn.snapshot()
+	assert.Equal(t, []string{"m0"}, written)
+}
+
+func TestOutboundQueue_BatchIsOneSlot(t *testing.T) {
+	conn := newBlockingConn()
+	q := newOutboundQueue(conn, configs.OutboundConfig{QueueSize: 1, Overflow: configs.OverflowDisconnect}, "test")
+
+	assert.NoError(t, q.WriteMessage(websocket.BinaryMessage, []byte("m0")))
+	<-conn.writing
+	assert.NoError(t, q.WriteBatch(websocket.BinaryMessage, [][]byte{[]byte("b1"), []byte("b2"), []byte("b3")}))
+	assert.Equal(t, 1, q.Len())
+
+	close(conn.release)
+	assert.Eventually(t, func() bool {
+		written, _, _ := conn.snapshot()
+		return len(written) == 4
+	}, time.Second, 5*time.Millisecond)
+	q.stop()
+
+	written, _, _ := conn.snapshot()
+	assert.Equal(t, []string{"m0", "b1", "b2", "b3"}, written)
+}
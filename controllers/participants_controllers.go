@@ -1,262 +1,505 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"go-server/configs"
+	middleware "go-server/middlewares"
 	"go-server/models"
 	"go-server/repository"
+	"go-server/utils"
 
 	"github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
 )
 
 type ParticipantsController struct {
 	participantRepo repository.ParticipantRepositoryInterface
-	connections     map[*websocket.Conn]string
-	rooms           map[string]map[*websocket.Conn]bool
+	policy          middleware.PermissionPolicy
+	membership      middleware.TeamMembershipProvider
+	connections     map[*websocket.Conn]*participantConnection
+	rooms           map[string]map[*websocket.Conn]*outboundQueue
+	fanout          *utils.RoomFanout
+	presence        configs.PresenceConfig
+	outbound        configs.OutboundConfig
+	limits          *wsLimiter
 	mu              sync.Mutex
 }
 
-func NewParticipantsController(participantRepo repository.ParticipantRepositoryInterface) *ParticipantsController {
+// participantConnection: WebSocket 연결 하나와 그 연결로 들어간 방들(roomKey별 참가자 ID)
+// 한 연결이 canvas와 audio처럼 여러 "teamID:kind" 방에 동시에 들어갈 수 있습니다.
+type participantConnection struct {
+	id       string
+	out      *outboundQueue
+	sessions map[string]participantSession
+}
+
+type participantSession struct {
+	teamID        string
+	kind          string
+	participantID string
+}
+
+// participantsFanoutNamespace: 참가자 목록 브로드캐스트가 쓰는 Redis 채널 이름공간
+const participantsFanoutNamespace = "participants"
+
+func NewParticipantsController(participantRepo repository.ParticipantRepositoryInterface, policy middleware.PermissionPolicy, membership middleware.TeamMembershipProvider) *ParticipantsController {
 	return &ParticipantsController{
 		participantRepo: participantRepo,
-		connections:     make(map[*websocket.Conn]string),
-		rooms:           make(map[string]map[*websocket.Conn]bool),
+		policy:          policy,
+		membership:      membership,
+		connections:     make(map[*websocket.Conn]*participantConnection),
+		rooms:           make(map[string]map[*websocket.Conn]*outboundQueue),
+		presence:        configs.DefaultPresenceConfig(),
+		outbound:        configs.DefaultOutboundConfig(),
+		limits:          newWSLimiter(configs.EndpointParticipants, configs.DefaultLimitConfig()),
+	}
+}
+
+// SetPresence: heartbeat 간격과 참가자 만료 시간을 바꿉니다. HandleWebSocket이 불리기 전에 설정해야 합니다.
+func (pc *ParticipantsController) SetPresence(presence configs.PresenceConfig) {
+	pc.presence = presence
+}
+
+// SetOutbound: 연결별 송신 큐 크기와 넘칠 때의 정책을 바꿉니다. HandleWebSocket이 불리기 전에 설정해야 합니다.
+func (pc *ParticipantsController) SetOutbound(outbound configs.OutboundConfig) {
+	pc.outbound = outbound
+}
+
+// SetLimits: 메시지 크기와 action별 속도 한도를 바꿉니다. HandleWebSocket이 불리기 전에 설정해야 합니다.
+func (pc *ParticipantsController) SetLimits(limits configs.LimitConfig) {
+	pc.limits = newWSLimiter(configs.EndpointParticipants, limits)
+}
+
+// SetFanout: 참가자 목록 브로드캐스트를 다른 인스턴스의 같은 방 연결에도 전달합니다.
+func (pc *ParticipantsController) SetFanout(fanout *utils.RoomFanout) {
+	pc.fanout = fanout
+	fanout.Register(participantsFanoutNamespace, pc.deliverLocal)
+}
+
+// participantFromPayload: 인증된 연결이면 ID와 이름은 claims에서 가져오고, 프로필 사진과 색상만 payload를 씁니다.
+func participantFromPayload(c *websocket.Conn, payload models.ParticipantJoinPayload) models.Participant {
+	participant := models.Participant{
+		ID:             payload.Participant,
+		Name:           payload.Name,
+		ProfilePicture: payload.ProfilePicture,
+		Color:          payload.Color,
+	}
+	if claims, ok := c.Locals("user").(*middleware.CustomClaims); ok {
+		participant.ID = claims.UserID
+		if claims.Username != "" {
+			participant.Name = claims.Username
+		}
+	}
+	return participant
+}
+
+// canJoinTeam은 인증된 연결이 team_id 팀의 멤버인지 확인합니다.
+func (pc *ParticipantsController) canJoinTeam(c *websocket.Conn, teamID string) bool {
+	claims, ok := c.Locals("user").(*middleware.CustomClaims)
+	if !ok || pc.membership == nil {
+		return true
+	}
+	member, err := pc.membership.IsMember(configs.Ctx, claims, teamID)
+	if err != nil {
+		log.Println("Failed to check team membership:", err)
+		return false
+	}
+	return member
+}
+
+// canRemoveParticipant: 자기 자신을 빼는 것은 항상 허용하고, 다른 참가자를 내보내는 것은 kick 권한이 필요합니다.
+// 업그레이드 전에 claims가 저장되지 않은 연결은 기존처럼 허용합니다.
+func (pc *ParticipantsController) canRemoveParticipant(c *websocket.Conn, participantID string) bool {
+	claims, ok := c.Locals("user").(*middleware.CustomClaims)
+	if !ok || pc.policy == nil {
+		return true
+	}
+	if claims.UserID == participantID {
+		return true
+	}
+	return pc.policy.Allows(claims.Role, middleware.PermissionKickParticipant)
+}
+
+// reply: 연결 하나의 송신 큐에 메시지를 넣습니다.
+func (pc *ParticipantsController) reply(c *websocket.Conn, v interface{}) {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		log.Println("Marshal error:", err)
+		return
+	}
+
+	pc.mu.Lock()
+	conn := pc.connections[c]
+	pc.mu.Unlock()
+	if conn == nil {
+		return
+	}
+	if err := conn.out.WriteMessage(websocket.TextMessage, msg); err != nil {
+		log.Println("Write error:", err)
 	}
 }
 
+func (pc *ParticipantsController) writeError(c *websocket.Conn, req models.ParticipantRequest, code, message string) {
+	pc.reply(c, models.ParticipantError{
+		Version:   models.ParticipantProtocolVersion,
+		Action:    models.ActionError,
+		RequestID: req.RequestID,
+		For:       req.Action,
+		Code:      code,
+		Error:     message,
+	})
+}
+
+// ack: requestId가 있는 요청에만 처리 완료를 알립니다. 이전 클라이언트는 requestId를 보내지 않으므로 받지 않습니다.
+func (pc *ParticipantsController) ack(c *websocket.Conn, req models.ParticipantRequest) {
+	if req.RequestID == "" {
+		return
+	}
+	pc.reply(c, models.ParticipantAck{
+		Version:   models.ParticipantProtocolVersion,
+		Action:    models.ActionAck,
+		RequestID: req.RequestID,
+		For:       req.Action,
+	})
+}
+
+// decodeParticipantRequest: 봉투를 풉니다. "v"가 없는 이전 형식은 메시지 전체를 payload로 씁니다.
+func decodeParticipantRequest(msg []byte) (models.ParticipantRequest, string, error) {
+	var req models.ParticipantRequest
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return req, models.ErrorCodeInvalidMessage, err
+	}
+	if req.Version == 0 && req.Payload == nil {
+		req.Payload = msg
+	}
+	if req.Version < 0 || req.Version > models.ParticipantProtocolVersion {
+		return req, models.ErrorCodeUnsupportedVersion, fmt.Errorf("unsupported protocol version %d, server speaks %d", req.Version, models.ParticipantProtocolVersion)
+	}
+	if req.Action == "" {
+		return req, models.ErrorCodeInvalidMessage, errors.New("action is required")
+	}
+	if _, ok := models.ParticipantClientPayloads[req.Action]; !ok {
+		return req, models.ErrorCodeUnknownAction, fmt.Errorf("unknown action %q", req.Action)
+	}
+	return req, "", nil
+}
+
+// decodePayload: payload를 action의 타입으로 풀고 검사합니다. 실패하면 에러 응답을 보내고 false를 돌려줍니다.
+func (pc *ParticipantsController) decodePayload(c *websocket.Conn, req models.ParticipantRequest, payload interface{ Validate() error }) bool {
+	if err := json.Unmarshal(req.Payload, payload); err != nil {
+		pc.writeError(c, req, models.ErrorCodeInvalidMessage, "Invalid payload: "+err.Error())
+		return false
+	}
+	if err := payload.Validate(); err != nil {
+		pc.writeError(c, req, models.ErrorCodeInvalidMessage, err.Error())
+		return false
+	}
+	return true
+}
+
 func (pc *ParticipantsController) HandleWebSocket(c *websocket.Conn) {
 	log.Println("New participant connected")
 	log.Println("Remote addr:", c.RemoteAddr())
 	log.Println("Local addr:", c.LocalAddr())
 
+	out := newOutboundQueue(c, pc.outbound, "participants")
+	pc.mu.Lock()
+	pc.connections[c] = &participantConnection{id: uuid.NewString(), out: out, sessions: make(map[string]participantSession)}
+	pc.mu.Unlock()
+
 	defer func() {
 		pc.mu.Lock()
-		participant := pc.connections[c]
+		conn := pc.connections[c]
 		delete(pc.connections, c)
+		for roomKey, clients := range pc.rooms {
+			delete(clients, c)
+			if len(clients) == 0 {
+				delete(pc.rooms, roomKey)
+			}
+		}
 		pc.mu.Unlock()
 
-		// Remove participant from Redis
-		var payload map[string]string
-		if err := json.Unmarshal([]byte(participant), &payload); err == nil {
-			log.Println("Payload:", payload)
-			pc.participantRepo.RemoveParticipant(configs.Ctx, payload["team_id"], payload["kind"], payload["participant"])
-			pc.broadcastParticipants(payload["team_id"], payload["kind"])
-			// 추가: 오디오 참여자 업데이트
-			if payload["kind"] == "audio" {
-				pc.broadcastAudioParticipants(payload["team_id"], payload["kind"])
+		// 이 연결로 들어간 방마다 Redis 참조를 놓고, 마지막 연결이었으면 참가자 목록을 알림
+		if conn != nil {
+			var changed []repository.ParticipantRoom
+			for _, session := range conn.sessions {
+				removed, err := pc.participantRepo.LeaveParticipant(configs.Ctx, session.teamID, session.kind, conn.id)
+				if err != nil {
+					log.Println("Failed to release participant:", err)
+					continue
+				}
+				if removed {
+					changed = append(changed, repository.ParticipantRoom{TeamID: session.teamID, Kind: session.kind})
+				}
 			}
-		} else {
-			log.Println("Failed to unmarshal participant:", err)
+			pc.broadcastRooms(changed)
 		}
-
+		out.stop()
 		c.Close()
 	}()
 
+	hb := startHeartbeat(c, pc.presence.HeartbeatInterval, func() { pc.touchConnection(c) })
+	defer hb.stop()
+	limiter := pc.limits.connection(c, out.disconnect)
+
 	for {
 		_, msg, err := c.ReadMessage()
 		if err != nil {
 			log.Println("Read error:", err)
+			limiter.readError(err)
 			break
 		}
+		hb.seen()
 
-		var payload map[string]string
-		if err := json.Unmarshal(msg, &payload); err != nil {
-			log.Println("Unmarshal error:", err)
+		req, code, err := decodeParticipantRequest(msg)
+		if !limiter.allow(req.Action) {
+			pc.writeError(c, req, models.ErrorCodeRateLimited, "Rate limit exceeded")
 			continue
 		}
+		if err != nil {
+			pc.writeError(c, req, code, err.Error())
+			continue
+		}
+		pc.dispatch(c, req)
+	}
+}
 
-		switch payload["action"] {
-		case "addParticipant":
-			pc.handleAddParticipant(c, payload)
-		case "removeParticipant":
-			pc.handleRemoveParticipant(c, payload)
-		case "getParticipants":
-			pc.handleGetParticipants(c, payload)
-		case "addAudioParticipant":
-			pc.handleAddAudioParticipant(c, payload)
-		case "removeAudioParticipant":
-			pc.handleRemoveAudioParticipant(c, payload)
-		case "getAudioParticipants":
-			pc.handleGetAudioParticipants(c, payload)
+func (pc *ParticipantsController) dispatch(c *websocket.Conn, req models.ParticipantRequest) {
+	switch req.Action {
+	case models.ActionAddParticipant, models.ActionAddAudioParticipant:
+		var payload models.ParticipantJoinPayload
+		if pc.decodePayload(c, req, &payload) {
+			pc.handleAddParticipant(c, req, payload)
+		}
+	case models.ActionRemoveParticipant, models.ActionRemoveAudioParticipant:
+		var payload models.ParticipantLeavePayload
+		if pc.decodePayload(c, req, &payload) {
+			pc.handleRemoveParticipant(c, req, payload)
+		}
+	case models.ActionGetParticipants, models.ActionGetAudioParticipants:
+		var payload models.ParticipantRoomPayload
+		if pc.decodePayload(c, req, &payload) {
+			pc.handleGetParticipants(c, req, payload)
 		}
 	}
 }
 
-func (pc *ParticipantsController) handleAddAudioParticipant(c *websocket.Conn, payload map[string]string) {
-	teamID := payload["team_id"]
-	kind := payload["kind"]
-	participant := models.Participant{
-		ID:             payload["participant"],
-		Name:           payload["name"],
-		ProfilePicture: payload["profilePicture"],
-		Color:          payload["color"],
+// touchConnection: pong을 받으면 이 연결로 들어간 모든 방에서 참가자의 last-seen을 갱신합니다.
+func (pc *ParticipantsController) touchConnection(c *websocket.Conn) {
+	pc.mu.Lock()
+	var sessions []participantSession
+	if conn := pc.connections[c]; conn != nil {
+		for _, session := range conn.sessions {
+			sessions = append(sessions, session)
+		}
 	}
+	pc.mu.Unlock()
 
-	err := pc.participantRepo.AddParticipant(configs.Ctx, teamID, kind, participant)
-	if err != nil {
-		log.Println("Failed to add audio participant:", err)
-		return
+	for _, session := range sessions {
+		if err := pc.participantRepo.TouchParticipant(configs.Ctx, session.teamID, session.kind, session.participantID); err != nil {
+			log.Println("Failed to touch participant:", err)
+		}
 	}
+}
 
-	payloadStr, err := json.Marshal(payload)
-	if err != nil {
-		log.Println("Failed to marshal payload:", err)
-		return
+// joinRoom: 연결 c를 participant로 방에 등록하고 방 브로드캐스트를 받게 합니다.
+// 같은 방에 다시 들어오면 참가자 정보만 갱신됩니다.
+func (pc *ParticipantsController) joinRoom(c *websocket.Conn, teamID, kind string, participant models.Participant) error {
+	roomKey := teamID + ":" + kind
+
+	pc.mu.Lock()
+	conn := pc.connections[c]
+	if conn == nil {
+		pc.mu.Unlock()
+		return errors.New("connection is closed")
+	}
+	previous, joined := conn.sessions[roomKey]
+	pc.mu.Unlock()
+
+	// 같은 연결이 다른 ID로 다시 들어오면 이전 ID의 참조부터 놓음
+	if joined && previous.participantID != participant.ID {
+		if _, err := pc.participantRepo.LeaveParticipant(configs.Ctx, teamID, kind, conn.id); err != nil {
+			return err
+		}
+	}
+	if err := pc.participantRepo.AddParticipant(configs.Ctx, teamID, kind, conn.id, participant); err != nil {
+		return err
 	}
 
 	pc.mu.Lock()
-	pc.connections[c] = string(payloadStr)
-	roomKey := teamID + ":" + kind
+	conn.sessions[roomKey] = participantSession{teamID: teamID, kind: kind, participantID: participant.ID}
 	if pc.rooms[roomKey] == nil {
-		pc.rooms[roomKey] = make(map[*websocket.Conn]bool)
+		pc.rooms[roomKey] = make(map[*websocket.Conn]*outboundQueue)
 	}
-	pc.rooms[roomKey][c] = true
+	pc.rooms[roomKey][c] = conn.out
 	pc.mu.Unlock()
-
-	pc.broadcastAudioParticipants(teamID, kind)
+	return nil
 }
 
-func (pc *ParticipantsController) handleRemoveAudioParticipant(c *websocket.Conn, payload map[string]string) {
-	teamID := payload["team_id"]
-	kind := payload["kind"]
-	participantID := payload["participant"]
+// removeFromRoom: 이 연결로 등록한 자기 자신을 빼는 경우에는 이 연결의 참조만 놓고(다른 탭은 남음),
+// 그 밖에는 참가자를 모든 연결과 함께 방에서 내보냅니다.
+// 연결은 계속 방 브로드캐스트를 받습니다.
+func (pc *ParticipantsController) removeFromRoom(c *websocket.Conn, teamID, kind, participantID string) error {
+	roomKey := teamID + ":" + kind
 
-	err := pc.participantRepo.RemoveParticipant(configs.Ctx, teamID, kind, participantID)
-	if err != nil {
-		log.Println("Failed to remove audio participant:", err)
-		return
+	pc.mu.Lock()
+	var connID string
+	if conn := pc.connections[c]; conn != nil {
+		if session, ok := conn.sessions[roomKey]; ok && session.participantID == participantID {
+			connID = conn.id
+			delete(conn.sessions, roomKey)
+		}
 	}
+	pc.mu.Unlock()
 
-	pc.broadcastAudioParticipants(teamID, kind)
+	if connID != "" {
+		_, err := pc.participantRepo.LeaveParticipant(configs.Ctx, teamID, kind, connID)
+		return err
+	}
+	return pc.participantRepo.RemoveParticipant(configs.Ctx, teamID, kind, participantID)
 }
 
-func (pc *ParticipantsController) handleGetAudioParticipants(c *websocket.Conn, payload map[string]string) {
-	teamID := payload["team_id"]
-	kind := payload["kind"]
-
-	participants, err := pc.participantRepo.GetParticipants(configs.Ctx, teamID, kind)
-	if err != nil {
-		log.Println("Failed to get audio participants:", err)
-		return
+// RunPresenceReaper: ctx가 끝날 때까지 주기적으로 heartbeat가 끊긴 참가자를 지우고 해당 방에 알립니다.
+// 여러 인스턴스가 함께 돌려도 한 참가자는 한 번만 지워집니다.
+func (pc *ParticipantsController) RunPresenceReaper(ctx context.Context) {
+	ticker := time.NewTicker(pc.presence.ReapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rooms, err := pc.participantRepo.RemoveExpiredParticipants(ctx, pc.presence.TTL)
+			if err != nil {
+				log.Println("Failed to remove expired participants:", err)
+			}
+			pc.broadcastRooms(rooms)
+		}
 	}
+}
 
-	response := map[string]interface{}{
-		"action":            "getAudioParticipants",
-		"audioParticipants": participants,
-	}
-	responseMsg, err := json.Marshal(response)
-	if err != nil {
-		log.Println("Marshal error:", err)
-		return
-	}
+// ReconcileParticipants: 시작할 때 이 인스턴스 이름으로 남아 있는 참가자(이전 프로세스가 비정상 종료하며 남긴 것)를 지웁니다.
+// 참가자 저장소의 instanceID가 재시작해도 같아야(configs.LoadInstanceID) 이전 프로세스의 연결을 찾을 수 있습니다.
+func (pc *ParticipantsController) ReconcileParticipants(ctx context.Context) error {
+	rooms, err := pc.participantRepo.RemoveOwnedParticipants(ctx)
+	pc.broadcastRooms(rooms)
+	return err
+}
 
-	if err := c.WriteMessage(websocket.TextMessage, responseMsg); err != nil {
-		log.Println("Write error:", err)
+func (pc *ParticipantsController) broadcastRooms(rooms []repository.ParticipantRoom) {
+	for _, room := range rooms {
+		pc.broadcastParticipants(room.TeamID, room.Kind)
+		if room.Kind == models.KindAudio {
+			pc.broadcastAudioParticipants(room.TeamID, room.Kind)
+		}
 	}
 }
 
-func (pc *ParticipantsController) broadcastAudioParticipants(teamID, kind string) {
-	participants, err := pc.participantRepo.GetParticipants(configs.Ctx, teamID, kind)
-	if err != nil {
-		log.Println("Failed to get audio participants:", err)
-		return
+// isAudioAction: 오디오 참가자 action은 목록을 audioParticipants 필드로 주고받습니다.
+func isAudioAction(action string) bool {
+	switch action {
+	case models.ActionAddAudioParticipant, models.ActionRemoveAudioParticipant, models.ActionGetAudioParticipants:
+		return true
+	default:
+		return false
 	}
+}
 
-	response := map[string]interface{}{
-		"action":            "updateAudioParticipants",
-		"audioParticipants": participants,
+func (pc *ParticipantsController) handleAddParticipant(c *websocket.Conn, req models.ParticipantRequest, payload models.ParticipantJoinPayload) {
+	if !pc.canJoinTeam(c, payload.TeamID) {
+		pc.writeError(c, req, models.ErrorCodeForbidden, "Not a member of this team")
+		return
 	}
-	responseMsg, err := json.Marshal(response)
-	if err != nil {
-		log.Println("Marshal error:", err)
+	participant := participantFromPayload(c, payload)
+	if participant.ID == "" {
+		pc.writeError(c, req, models.ErrorCodeInvalidMessage, models.ErrMissingParticipant.Error())
 		return
 	}
 
-	pc.mu.Lock()
-	defer pc.mu.Unlock()
-
-	roomKey := teamID + ":" + kind
-	for conn := range pc.rooms[roomKey] {
-		if err := conn.WriteMessage(websocket.TextMessage, responseMsg); err != nil {
-			log.Println("Write error:", err)
-		}
+	if err := pc.joinRoom(c, payload.TeamID, payload.Kind, participant); err != nil {
+		log.Println("Failed to add participant:", err)
+		pc.writeError(c, req, models.ErrorCodeInternal, "Failed to add participant")
+		return
 	}
+
+	pc.ack(c, req)
+	pc.broadcastAction(req.Action, payload.TeamID, payload.Kind)
 }
 
-func (pc *ParticipantsController) handleAddParticipant(c *websocket.Conn, payload map[string]string) {
-	teamID := payload["team_id"]
-	kind := payload["kind"]
-	participant := models.Participant{
-		ID:             payload["participant"],
-		Name:           payload["name"],
-		ProfilePicture: payload["profilePicture"],
-		Color:          payload["color"],
+func (pc *ParticipantsController) handleRemoveParticipant(c *websocket.Conn, req models.ParticipantRequest, payload models.ParticipantLeavePayload) {
+	// kick 권한은 자기 팀 안에서만 유효
+	if !pc.canJoinTeam(c, payload.TeamID) {
+		pc.writeError(c, req, models.ErrorCodeForbidden, "Not a member of this team")
+		return
+	}
+	if !pc.canRemoveParticipant(c, payload.Participant) {
+		log.Printf("Denied kick of participant %s from team %s\n", payload.Participant, payload.TeamID)
+		pc.writeError(c, req, models.ErrorCodeForbidden, "Not allowed to remove other participants")
+		return
 	}
 
-	err := pc.participantRepo.AddParticipant(configs.Ctx, teamID, kind, participant)
-	if err != nil {
-		log.Println("Failed to add participant:", err)
+	if err := pc.removeFromRoom(c, payload.TeamID, payload.Kind, payload.Participant); err != nil {
+		log.Println("Failed to remove participant:", err)
+		pc.writeError(c, req, models.ErrorCodeInternal, "Failed to remove participant")
 		return
 	}
 
-	payloadStr, err := json.Marshal(payload)
+	pc.ack(c, req)
+	pc.broadcastAction(req.Action, payload.TeamID, payload.Kind)
+}
+
+func (pc *ParticipantsController) handleGetParticipants(c *websocket.Conn, req models.ParticipantRequest, payload models.ParticipantRoomPayload) {
+	if !pc.canJoinTeam(c, payload.TeamID) {
+		pc.writeError(c, req, models.ErrorCodeForbidden, "Not a member of this team")
+		return
+	}
+	participants, err := pc.participantRepo.GetParticipants(configs.Ctx, payload.TeamID, payload.Kind)
 	if err != nil {
-		log.Println("Failed to marshal payload:", err)
+		log.Println("Failed to get participants:", err)
+		pc.writeError(c, req, models.ErrorCodeInternal, "Failed to get participants")
 		return
 	}
 
-	pc.mu.Lock()
-	pc.connections[c] = string(payloadStr)
-	roomKey := teamID + ":" + kind
-	if pc.rooms[roomKey] == nil {
-		pc.rooms[roomKey] = make(map[*websocket.Conn]bool)
+	if isAudioAction(req.Action) {
+		pc.reply(c, models.AudioParticipantsMessage{Version: models.ParticipantProtocolVersion, Action: req.Action, RequestID: req.RequestID, AudioParticipants: participants})
+		return
 	}
-	pc.rooms[roomKey][c] = true
-	pc.mu.Unlock()
-
-	pc.broadcastParticipants(teamID, kind)
+	pc.reply(c, models.ParticipantsMessage{Version: models.ParticipantProtocolVersion, Action: req.Action, RequestID: req.RequestID, Participants: participants})
 }
 
-func (pc *ParticipantsController) handleRemoveParticipant(c *websocket.Conn, payload map[string]string) {
-	teamID := payload["team_id"]
-	kind := payload["kind"]
-	participantID := payload["participant"]
-
-	err := pc.participantRepo.RemoveParticipant(configs.Ctx, teamID, kind, participantID)
-	if err != nil {
-		log.Println("Failed to remove participant:", err)
+func (pc *ParticipantsController) broadcastAction(action, teamID, kind string) {
+	if isAudioAction(action) {
+		pc.broadcastAudioParticipants(teamID, kind)
 		return
 	}
-
 	pc.broadcastParticipants(teamID, kind)
 }
 
-func (pc *ParticipantsController) handleGetParticipants(c *websocket.Conn, payload map[string]string) {
-	teamID := payload["team_id"]
-	kind := payload["kind"]
-
+func (pc *ParticipantsController) broadcastAudioParticipants(teamID, kind string) {
 	participants, err := pc.participantRepo.GetParticipants(configs.Ctx, teamID, kind)
 	if err != nil {
-		log.Println("Failed to get participants:", err)
+		log.Println("Failed to get audio participants:", err)
 		return
 	}
 
-	response := map[string]interface{}{
-		"action":       "getParticipants",
-		"participants": participants,
-	}
-	responseMsg, err := json.Marshal(response)
+	responseMsg, err := json.Marshal(models.AudioParticipantsMessage{
+		Version:           models.ParticipantProtocolVersion,
+		Action:            models.ActionUpdateAudioParticipants,
+		AudioParticipants: participants,
+	})
 	if err != nil {
 		log.Println("Marshal error:", err)
 		return
 	}
 
-	if err := c.WriteMessage(websocket.TextMessage, responseMsg); err != nil {
-		log.Println("Write error:", err)
-	}
+	pc.broadcastRoom(teamID+":"+kind, responseMsg)
 }
 
 func (pc *ParticipantsController) broadcastParticipants(teamID, kind string) {
@@ -266,22 +509,37 @@ func (pc *ParticipantsController) broadcastParticipants(teamID, kind string) {
 		return
 	}
 
-	response := map[string]interface{}{
-		"action":       "updateParticipants",
-		"participants": participants,
-	}
-	responseMsg, err := json.Marshal(response)
+	responseMsg, err := json.Marshal(models.ParticipantsMessage{
+		Version:      models.ParticipantProtocolVersion,
+		Action:       models.ActionUpdateParticipants,
+		Participants: participants,
+	})
 	if err != nil {
 		log.Println("Marshal error:", err)
 		return
 	}
 
+	pc.broadcastRoom(teamID+":"+kind, responseMsg)
+}
+
+// broadcastRoom: 이 인스턴스의 방 연결에 보내고, 다른 인스턴스에도 발행합니다.
+func (pc *ParticipantsController) broadcastRoom(roomKey string, msg []byte) {
+	pc.deliverLocal(roomKey, msg)
+	if pc.fanout != nil {
+		if err := pc.fanout.Publish(configs.Ctx, participantsFanoutNamespace, roomKey, msg); err != nil {
+			log.Println("Failed to publish room broadcast:", err)
+		}
+	}
+}
+
+// deliverLocal: 이 인스턴스에 접속한 roomKey 방 연결에만 보냅니다.
+// 송신 큐에 넣기만 하므로 느린 연결이 있어도 락을 오래 잡지 않습니다.
+func (pc *ParticipantsController) deliverLocal(roomKey string, msg []byte) {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
 
-	roomKey := teamID + ":" + kind
-	for conn := range pc.rooms[roomKey] {
-		if err := conn.WriteMessage(websocket.TextMessage, responseMsg); err != nil {
+	for _, out := range pc.rooms[roomKey] {
+		if err := out.WriteMessage(websocket.TextMessage, msg); err != nil {
 			log.Println("Write error:", err)
 		}
 	}
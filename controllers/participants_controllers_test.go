@@ -4,13 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http/httptest"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	middleware "go-server/middlewares"
 	"go-server/models"
+	"go-server/repository"
 
 	"github.com/fasthttp/websocket"
 	adaptor "github.com/gofiber/adaptor/v2"
@@ -22,16 +25,18 @@ import (
 // MockParticipantRepository 구조체 정의
 type MockParticipantRepository struct {
 	participants map[string]map[string]models.Participant // key: "teamID:kind", value: map[participantID]Participant
+	conns        map[string]map[string]string             // key: "teamID:kind", value: map[connID]participantID
 	mu           sync.Mutex
 }
 
 func NewMockParticipantRepository() *MockParticipantRepository {
 	return &MockParticipantRepository{
 		participants: make(map[string]map[string]models.Participant),
+		conns:        make(map[string]map[string]string),
 	}
 }
 
-func (m *MockParticipantRepository) AddParticipant(ctx context.Context, teamID, kind string, p models.Participant) error {
+func (m *MockParticipantRepository) AddParticipant(ctx context.Context, teamID, kind, connID string, p models.Participant) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	key := fmt.Sprintf("%s:%s", teamID, kind)
@@ -39,9 +44,30 @@ func (m *MockParticipantRepository) AddParticipant(ctx context.Context, teamID,
 		m.participants[key] = make(map[string]models.Participant)
 	}
 	m.participants[key][p.ID] = p
+	if _, exists := m.conns[key]; !exists {
+		m.conns[key] = make(map[string]string)
+	}
+	m.conns[key][connID] = p.ID
 	return nil
 }
 
+func (m *MockParticipantRepository) LeaveParticipant(ctx context.Context, teamID, kind, connID string) (bool, error) {
+	m.mu.Lock()
+	key := fmt.Sprintf("%s:%s", teamID, kind)
+	participantID, exists := m.conns[key][connID]
+	delete(m.conns[key], connID)
+	for _, other := range m.conns[key] {
+		if other == participantID {
+			exists = false
+		}
+	}
+	m.mu.Unlock()
+	if !exists {
+		return false, nil
+	}
+	return true, m.RemoveParticipant(ctx, teamID, kind, participantID)
+}
+
 func (m *MockParticipantRepository) RemoveParticipant(ctx context.Context, teamID, kind, participantID string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -68,6 +94,18 @@ func (m *MockParticipantRepository) GetParticipants(ctx context.Context, teamID,
 	return participants, nil
 }
 
+func (m *MockParticipantRepository) TouchParticipant(ctx context.Context, teamID, kind, participantID string) error {
+	return nil
+}
+
+func (m *MockParticipantRepository) RemoveExpiredParticipants(ctx context.Context, ttl time.Duration) ([]repository.ParticipantRoom, error) {
+	return nil, nil
+}
+
+func (m *MockParticipantRepository) RemoveOwnedParticipants(ctx context.Context) ([]repository.ParticipantRoom, error) {
+	return nil, nil
+}
+
 func TestFiberWebSocket(t *testing.T) {
 	app := fiber.New()
 	app.Get("/ws", fiberws.New(func(c *fiberws.Conn) {
@@ -103,7 +141,7 @@ func TestFiberWebSocket(t *testing.T) {
 
 func TestParticipantsController_AddAndBroadcastParticipant(t *testing.T) {
 	mockRepo := NewMockParticipantRepository()
-	controller := NewParticipantsController(mockRepo)
+	controller := NewParticipantsController(mockRepo, nil, nil)
 
 	// Fiber + Fiber 웹소켓 설정
 	app := fiber.New()
@@ -164,7 +202,7 @@ func TestParticipantsController_AddAndBroadcastParticipant(t *testing.T) {
 
 func TestParticipantsController_RemoveParticipant(t *testing.T) {
 	mockRepo := NewMockParticipantRepository()
-	controller := NewParticipantsController(mockRepo)
+	controller := NewParticipantsController(mockRepo, nil, nil)
 
 	app := fiber.New()
 	app.Use("/ws", func(c *fiber.Ctx) error {
@@ -232,7 +270,7 @@ func TestParticipantsController_DisconnectCleanup(t *testing.T) {
 	// 실제 구현에서 controller.HandleWebSocket 내부에
 	// defer/cleanup 로직을 넣어야 동작합니다.
 	mockRepo := NewMockParticipantRepository()
-	controller := NewParticipantsController(mockRepo)
+	controller := NewParticipantsController(mockRepo, nil, nil)
 
 	app := fiber.New()
 	app.Use("/ws", func(c *fiber.Ctx) error {
@@ -276,7 +314,7 @@ func TestParticipantsController_DisconnectCleanup(t *testing.T) {
 
 func TestParticipantsController_AudioParticipants(t *testing.T) {
 	mockRepo := NewMockParticipantRepository()
-	controller := NewParticipantsController(mockRepo)
+	controller := NewParticipantsController(mockRepo, nil, nil)
 
 	app := fiber.New()
 	app.Use("/ws", func(c *fiber.Ctx) error {
@@ -334,3 +372,79 @@ func TestParticipantsController_AudioParticipants(t *testing.T) {
 	assert.Equal(t, "getAudioParticipants", getResp["action"])
 	assert.Len(t, getResp["audioParticipants"], 1)
 }
+
+// startTestServer는 실제 리스너로 Fiber 앱을 띄웁니다. 웹소켓 업그레이드는 hijack이 필요해서
+// adaptor.FiberApp + httptest 조합으로는 연결되지 않습니다.
+func startTestServer(t *testing.T, app *fiber.App) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go app.Listener(ln)
+	t.Cleanup(func() { _ = app.Shutdown() })
+	return "ws://" + ln.Addr().String()
+}
+
+func TestParticipantsController_KickRequiresPermission(t *testing.T) {
+	mockRepo := NewMockParticipantRepository()
+	controller := NewParticipantsController(mockRepo, middleware.DefaultPermissionPolicy(), nil)
+
+	app := fiber.New()
+	// 업그레이드 전에 claims를 Locals에 넣어 둠 (JWTParser 역할)
+	app.Use("/ws", func(c *fiber.Ctx) error {
+		c.Locals("user", &middleware.CustomClaims{UserID: c.Query("user"), Role: c.Query("role")})
+		return c.Next()
+	})
+	app.Get("/ws", fiberws.New(controller.HandleWebSocket))
+	url := startTestServer(t, app) + "/ws"
+
+	dialer := websocket.Dialer{}
+	conn1, _, err := dialer.Dial(url+"?user=user1&role=viewer", nil)
+	assert.NoError(t, err)
+	defer conn1.Close()
+
+	addBytes, _ := json.Marshal(map[string]string{
+		"action":      "addParticipant",
+		"team_id":     "team1",
+		"kind":        "canvas",
+		"participant": "user1",
+		"name":        "User One",
+	})
+	conn1.WriteMessage(websocket.TextMessage, addBytes)
+	conn1.ReadMessage() // 응답 소진
+
+	removeBytes, _ := json.Marshal(map[string]string{
+		"action":      "removeParticipant",
+		"team_id":     "team1",
+		"kind":        "canvas",
+		"participant": "user1",
+	})
+
+	// editor는 다른 참가자를 내보낼 수 없음
+	conn2, _, err := dialer.Dial(url+"?user=user2&role=editor", nil)
+	assert.NoError(t, err)
+	defer conn2.Close()
+	conn2.WriteMessage(websocket.TextMessage, removeBytes)
+
+	_, resp, err := conn2.ReadMessage()
+	assert.NoError(t, err)
+	var response map[string]interface{}
+	_ = json.Unmarshal(resp, &response)
+	assert.Equal(t, "error", response["action"])
+
+	parts, _ := mockRepo.GetParticipants(context.Background(), "team1", "canvas")
+	assert.Len(t, parts, 1)
+
+	// owner는 가능 - 방에 있는 conn1이 갱신된 목록을 받음
+	conn3, _, err := dialer.Dial(url+"?user=user3&role=owner", nil)
+	assert.NoError(t, err)
+	defer conn3.Close()
+	conn3.WriteMessage(websocket.TextMessage, removeBytes)
+
+	_, resp, err = conn1.ReadMessage()
+	assert.NoError(t, err)
+	_ = json.Unmarshal(resp, &response)
+	assert.Equal(t, "updateParticipants", response["action"])
+	assert.Empty(t, response["participants"])
+
+	parts, _ = mockRepo.GetParticipants(context.Background(), "team1", "canvas")
+	assert.Len(t, parts, 0)
+}
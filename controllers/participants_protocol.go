@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"sort"
+
+	"go-server/models"
+	"go-server/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// participantServerActions: 서버 메시지 타입별로 action에 올 수 있는 값
+var participantServerActions = map[string][]string{
+	"ParticipantsMessage":      {models.ActionUpdateParticipants, models.ActionGetParticipants},
+	"AudioParticipantsMessage": {models.ActionUpdateAudioParticipants, models.ActionGetAudioParticipants},
+	"ParticipantAck":           {models.ActionAck},
+	"ParticipantError":         {models.ActionError},
+}
+
+// ParticipantProtocolSchema: /ws 프로토콜의 JSON Schema를 models의 Go 타입에서 만듭니다.
+// $defs의 ClientMessage는 클라이언트가 보낼 수 있는 메시지, ServerMessage는 받을 수 있는 메시지입니다.
+// 프론트엔드는 이 스키마에서 TypeScript 타입을 생성해 씁니다 (예: json-schema-to-typescript).
+func ParticipantProtocolSchema() map[string]interface{} {
+	b := utils.NewJSONSchemaBuilder()
+	version := map[string]interface{}{"const": models.ParticipantProtocolVersion}
+
+	actions := make([]string, 0, len(models.ParticipantClientPayloads))
+	for action := range models.ParticipantClientPayloads {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+
+	client := make([]interface{}, 0, len(actions))
+	for _, action := range actions {
+		client = append(client, map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"v":         version,
+				"action":    map[string]interface{}{"const": action},
+				"requestId": map[string]interface{}{"type": "string"},
+				"payload":   b.Ref(models.ParticipantClientPayloads[action]),
+			},
+			"required":             []string{"v", "action", "payload"},
+			"additionalProperties": false,
+		})
+	}
+
+	server := make([]interface{}, 0, len(models.ParticipantServerMessages))
+	for _, message := range models.ParticipantServerMessages {
+		server = append(server, b.Ref(message))
+	}
+	for name, values := range participantServerActions {
+		properties := b.Defs[name].(map[string]interface{})["properties"].(map[string]interface{})
+		properties["v"] = version
+		properties["action"] = map[string]interface{}{"enum": values}
+	}
+
+	defs := b.Defs
+	defs["ClientMessage"] = map[string]interface{}{"oneOf": client}
+	defs["ServerMessage"] = map[string]interface{}{"oneOf": server}
+	return map[string]interface{}{
+		"$schema":            utils.JSONSchemaDraft,
+		"title":              "Participants WebSocket protocol",
+		"x-protocol-version": models.ParticipantProtocolVersion,
+		"$defs":              defs,
+		"oneOf": []interface{}{
+			map[string]interface{}{"$ref": "#/$defs/ClientMessage"},
+			map[string]interface{}{"$ref": "#/$defs/ServerMessage"},
+		},
+	}
+}
+
+// ProtocolSchema: GET /ws/schema.json
+func (pc *ParticipantsController) ProtocolSchema(c *fiber.Ctx) error {
+	return c.JSON(ParticipantProtocolSchema())
+}
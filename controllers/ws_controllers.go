@@ -3,97 +3,218 @@ package controllers
 import (
 	"encoding/json"
 	"log"
+	"strings"
 	"sync"
+	"time"
+
+	"go-server/configs"
+	middleware "go-server/middlewares"
+	"go-server/utils"
 
 	"github.com/gofiber/websocket/v2"
 )
 
+// WSConn: 방에 들어가는 연결. 실제로는 outboundQueue입니다.
+// disconnect는 close 프레임을 보내고 읽기 기한을 당겨 핸들러가 반환되게 합니다. hijack된 연결은 Close로 닫히지 않습니다.
 type WSConn interface {
 	WriteMessage(messageType int, data []byte) error
-	Close() error
+	disconnect(code int, reason string)
 }
 
-// SignalMessage: y-webrtc가 사용하는 시그널링 메시지 구조
-// 예) {"type":"signal","to":"...","from":"...","room":"...","data":{...}}
+// SignalMessage: y-webrtc 시그널링 서버 프로토콜 메시지
+// 예) {"type":"subscribe","topics":["note/<id>"]}
+//
+//	{"type":"publish","topic":"note/<id>","data":{...}}
+//	{"type":"ping"}
 type SignalMessage struct {
-	Type string                 `json:"type"`
-	To   string                 `json:"to"`
-	From string                 `json:"from"`
-	Room string                 `json:"room"`
-	Data map[string]interface{} `json:"data"`
+	Type   string          `json:"type"`
+	Topics []string        `json:"topics,omitempty"`
+	Topic  string          `json:"topic,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
 }
 
-// WebSocketController: (예시) 방(room) 단위로 WebSocket 접속을 관리
+const defaultSignalingPingInterval = 30 * time.Second
+
+// WebSocketController: y-webrtc 시그널링 서버. 한 연결이 여러 topic(room)을 구독합니다.
+// topic 이름은 "note/<id>", "canvas/<id>", "team/<teamId>"이며, 구독할 때 팀 멤버인지 확인합니다.
 type WebSocketController struct {
-	mu    sync.Mutex
-	rooms map[string]map[WSConn]bool
+	documents    *DocumentTeamResolver
+	membership   middleware.TeamMembershipProvider
+	pingInterval time.Duration
+	outbound     configs.OutboundConfig
+	limits       *wsLimiter
+	fanout       *utils.RoomFanout
+	mu           sync.Mutex
+	rooms        map[string]map[WSConn]bool
 }
 
-// NewWebSocketController: 간단 초기화
-func NewWebSocketController() *WebSocketController {
+// signalingFanoutNamespace: y-webrtc publish가 쓰는 Redis 채널 이름공간
+const signalingFanoutNamespace = "signaling"
+
+func NewWebSocketController(documents *DocumentTeamResolver, membership middleware.TeamMembershipProvider) *WebSocketController {
 	return &WebSocketController{
-		rooms: make(map[string]map[WSConn]bool),
+		documents:    documents,
+		membership:   membership,
+		pingInterval: defaultSignalingPingInterval,
+		outbound:     configs.DefaultOutboundConfig(),
+		limits:       newWSLimiter(configs.EndpointSignaling, configs.DefaultLimitConfig()),
+		rooms:        make(map[string]map[WSConn]bool),
 	}
 }
 
+// SetPingInterval: 이 간격마다 ping을 보내고, 다음 ping 때까지 pong이 없으면 연결을 끊습니다.
+func (wsc *WebSocketController) SetPingInterval(interval time.Duration) {
+	wsc.pingInterval = interval
+}
+
+// SetOutbound: 연결별 송신 큐 크기와 넘칠 때의 정책을 바꿉니다. HandleYWebRTC가 불리기 전에 설정해야 합니다.
+func (wsc *WebSocketController) SetOutbound(outbound configs.OutboundConfig) {
+	wsc.outbound = outbound
+}
+
+// SetLimits: 메시지 크기와 type별 속도 한도를 바꿉니다. HandleYWebRTC가 불리기 전에 설정해야 합니다.
+func (wsc *WebSocketController) SetLimits(limits configs.LimitConfig) {
+	wsc.limits = newWSLimiter(configs.EndpointSignaling, limits)
+}
+
+// SetFanout: publish를 다른 인스턴스에서 같은 topic을 구독한 연결에도 전달합니다.
+func (wsc *WebSocketController) SetFanout(fanout *utils.RoomFanout) {
+	wsc.fanout = fanout
+	fanout.Register(signalingFanoutNamespace, func(roomID string, rawMessage []byte) {
+		wsc.deliverSignal(roomID, nil, rawMessage)
+	})
+}
+
 // HandleYWebRTC: y-webrtc 시그널링 전용 WebSocket 핸들러
 func (wsc *WebSocketController) HandleYWebRTC(c *websocket.Conn) {
-	// 1) Query "room" 파라미터
-	roomID := c.Query("room")
-	if roomID == "" {
-		log.Println("[y-webrtc] No 'room' query param provided, closing.")
-		_ = c.Close()
-		return
-	}
-
-	// 2) 방에 접속
-	wsc.joinRoom(roomID, c)
-	defer wsc.leaveRoom(roomID, c)
+	// 방에는 연결 대신 송신 큐가 들어가, 브로드캐스트가 느린 구독자를 기다리지 않음
+	out := newOutboundQueue(c, wsc.outbound, "signaling")
+	limiter := wsc.limits.connection(c, out.disconnect)
+	subscribed := make(map[string]bool)
+	defer func() {
+		for topic := range subscribed {
+			wsc.leaveRoom(topic, out)
+		}
+		// 핸들러가 반환되면 fasthttp가 연결을 닫음
+		out.stop()
+	}()
 
-	log.Printf("[y-webrtc] Client joined room=%s\n", roomID)
+	hb := startHeartbeat(c, wsc.pingInterval, nil)
+	defer hb.stop()
 
-	// 3) 메시지 루프
 	for {
 		msgType, msg, err := c.ReadMessage()
 		if err != nil {
-			log.Printf("[y-webrtc] Read error: %v\n", err)
+			limiter.readError(err)
 			break
 		}
+		hb.seen()
 		if msgType != websocket.TextMessage {
-			// y-webrtc는 주로 TextMessage 사용. 아닌 경우 무시
+			limiter.allow("")
 			continue
 		}
 
-		// y-webrtc에서 오는 메시지를 구조체로 파싱 (type, from, to, room, data...)
 		var signal SignalMessage
-		if err := json.Unmarshal(msg, &signal); err != nil {
-			log.Printf("[y-webrtc] JSON parse error: %v\n", err)
+		err = json.Unmarshal(msg, &signal)
+		if !limiter.allow(signal.Type) {
 			continue
 		}
-
-		// room 필드가 비어있다면 보완
-		if signal.Room == "" {
-			signal.Room = roomID
+		if err != nil {
+			log.Printf("[y-webrtc] JSON parse error: %v\n", err)
+			continue
 		}
 
-		// 4) y-webrtc가 'type: "signal"'을 보냈다면, 그대로 방 전체에 브로드캐스트
 		switch signal.Type {
-		case "signal":
-			// offer/answer/iceCandidate 등 협업에 필요한 핵심 메시지
-			wsc.broadcastSignal(roomID, c, msg)
+		case "subscribe":
+			for _, topic := range signal.Topics {
+				if subscribed[topic] {
+					continue
+				}
+				if !wsc.canSubscribe(c, topic) {
+					wsc.send(out, map[string]string{"type": "error", "topic": topic, "error": "Not allowed to subscribe to topic"})
+					continue
+				}
+				subscribed[topic] = true
+				wsc.joinRoom(topic, out)
+			}
+
+		case "unsubscribe":
+			for _, topic := range signal.Topics {
+				if subscribed[topic] {
+					delete(subscribed, topic)
+					wsc.leaveRoom(topic, out)
+				}
+			}
 
-		// 필요하다면, "participants"나 "awareness" 등 다른 타입도 그대로 중계 가능
-		case "participants":
-			// 예: 참가자 목록 메시지도 as-is로 뿌리고 싶다면
-			wsc.broadcastSignal(roomID, c, msg)
+		case "publish":
+			// 구독하지 않은 topic에는 보낼 수 없음 (구독할 때 권한을 확인했으므로)
+			if !subscribed[signal.Topic] {
+				continue
+			}
+			wsc.broadcastSignal(signal.Topic, out, withClientCount(msg, wsc.roomSize(signal.Topic)))
+
+		case "ping":
+			wsc.send(out, map[string]string{"type": "pong"})
 
 		default:
-			// 그 외 타입은 무시하거나, 로그만 찍기
-			log.Printf("[y-webrtc] Unknown type=%s message=%s\n", signal.Type, string(msg))
+			log.Printf("[y-webrtc] Unknown type=%s\n", signal.Type)
 		}
 	}
 }
 
+// canSubscribe: 인증된 연결이면 topic이 가리키는 팀의 멤버인지 확인합니다.
+func (wsc *WebSocketController) canSubscribe(c *websocket.Conn, topic string) bool {
+	claims, ok := c.Locals("user").(*middleware.CustomClaims)
+	if !ok || wsc.membership == nil {
+		return true
+	}
+	kind, id, found := strings.Cut(topic, "/")
+	if !found || id == "" || wsc.documents == nil {
+		return false
+	}
+	teamID, err := wsc.documents.TeamOf(kind, id)
+	if err != nil || teamID == "" {
+		return false
+	}
+	member, err := wsc.membership.IsMember(configs.Ctx, claims, teamID)
+	if err != nil {
+		log.Println("[y-webrtc] Failed to check team membership:", err)
+		return false
+	}
+	return member
+}
+
+// withClientCount: y-webrtc 서버처럼 publish 메시지에 구독자 수(clients)를 붙입니다.
+func withClientCount(rawMessage []byte, clients int) []byte {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(rawMessage, &fields); err != nil {
+		return rawMessage
+	}
+	fields["clients"], _ = json.Marshal(clients)
+	msg, err := json.Marshal(fields)
+	if err != nil {
+		return rawMessage
+	}
+	return msg
+}
+
+// send: 연결 하나에 JSON을 보냅니다.
+func (wsc *WebSocketController) send(conn WSConn, v interface{}) {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+		log.Println("[y-webrtc] Write error:", err)
+	}
+}
+
+func (wsc *WebSocketController) roomSize(roomID string) int {
+	wsc.mu.Lock()
+	defer wsc.mu.Unlock()
+	return len(wsc.rooms[roomID])
+}
+
 // joinRoom: 해당 roomID에 WebSocket 연결 추가
 func (wsc *WebSocketController) joinRoom(roomID string, conn WSConn) {
 	wsc.mu.Lock()
@@ -111,11 +232,7 @@ func (wsc *WebSocketController) leaveRoom(roomID string, conn WSConn) {
 	defer wsc.mu.Unlock()
 
 	if clients, ok := wsc.rooms[roomID]; ok {
-		if _, exists := clients[conn]; exists {
-			delete(clients, conn)
-			_ = conn.Close()
-			log.Printf("[y-webrtc] Client left room=%s\n", roomID)
-		}
+		delete(clients, conn)
 		if len(clients) == 0 {
 			delete(wsc.rooms, roomID)
 		}
@@ -123,7 +240,18 @@ func (wsc *WebSocketController) leaveRoom(roomID string, conn WSConn) {
 }
 
 // broadcastSignal: 받은 메시지를 방 안의 다른 클라이언트에게 그대로 전송
+// 다른 인스턴스에 접속한 구독자에게는 fanout으로 전달됨
 func (wsc *WebSocketController) broadcastSignal(roomID string, sender WSConn, rawMessage []byte) {
+	wsc.deliverSignal(roomID, sender, rawMessage)
+	if wsc.fanout != nil {
+		if err := wsc.fanout.Publish(configs.Ctx, signalingFanoutNamespace, roomID, rawMessage); err != nil {
+			log.Println("[y-webrtc] Failed to publish signal:", err)
+		}
+	}
+}
+
+// deliverSignal: 이 인스턴스에 접속한 방 연결 중 sender를 뺀 나머지에게 보냅니다.
+func (wsc *WebSocketController) deliverSignal(roomID string, sender WSConn, rawMessage []byte) {
 	wsc.mu.Lock()
 	defer wsc.mu.Unlock()
 
@@ -132,15 +260,14 @@ func (wsc *WebSocketController) broadcastSignal(roomID string, sender WSConn, ra
 		return
 	}
 
-	// 여기서는 rawMessage(msg) 그 자체를 그대로 중계
 	for conn := range clients {
-		// 자기 자신(sender)에게는 보내지 않음
+		// 자기 자신(sender)에게는 보내지 않음. y-webrtc 클라이언트는 자기 메시지를 어차피 무시함
 		if conn == sender {
 			continue
 		}
 		if err := conn.WriteMessage(websocket.TextMessage, rawMessage); err != nil {
 			log.Println("[y-webrtc] Write error:", err)
-			_ = conn.Close()
+			conn.disconnect(websocket.CloseInternalServerErr, "write failed")
 			delete(clients, conn)
 		}
 	}
@@ -17,14 +17,13 @@ func (m *mockConn) WriteMessage(messageType int, data []byte) error {
 	return nil
 }
 
-func (m *mockConn) Close() error {
+func (m *mockConn) disconnect(code int, reason string) {
 	m.closed = true
-	return nil
 }
 
 // TestBroadcastSignal는 broadcastSignal 함수의 로직을 단위 테스트합니다.
 func TestBroadcastSignal(t *testing.T) {
-	wsc := NewWebSocketController()
+	wsc := NewWebSocketController(nil, nil)
 	roomID := "testRoom"
 	sender := &mockConn{}
 	receiver := &mockConn{}
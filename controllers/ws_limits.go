@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"go-server/configs"
+	middleware "go-server/middlewares"
+	"go-server/utils"
+
+	fastws "github.com/fasthttp/websocket"
+	"github.com/gofiber/websocket/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// CloseRateLimited: 속도 한도를 계속 넘겨 끊은 연결의 close code (HTTP 429에 맞춤)
+// 프레임 크기를 넘기면 라이브러리가 표준 코드 1009(message too big)로 끊습니다.
+const CloseRateLimited = 4429
+
+var (
+	wsRateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "websocket_rate_limited_messages_total",
+		Help: "WebSocket messages dropped because a connection or user exceeded its rate limit.",
+	}, []string{"endpoint", "action", "scope"})
+	wsOversizedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "websocket_oversized_messages_total",
+		Help: "WebSocket frames rejected for exceeding the maximum message size.",
+	}, []string{"endpoint"})
+	wsLimitDisconnects = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "websocket_limit_disconnects_total",
+		Help: "WebSocket connections closed for exceeding a limit, by reason.",
+	}, []string{"endpoint", "reason"})
+)
+
+// wsLimiter: 엔드포인트 하나의 메시지 크기와 action별 속도 한도. 사용자별 버킷은 그 사용자의 모든 연결이 나눠 씁니다.
+type wsLimiter struct {
+	endpoint      string
+	limits        configs.EndpointLimits
+	maxViolations int
+	mu            sync.Mutex
+	users         map[string]*utils.KeyedRateLimiter
+}
+
+func newWSLimiter(endpoint string, config configs.LimitConfig) *wsLimiter {
+	return &wsLimiter{
+		endpoint:      endpoint,
+		limits:        config.Endpoints[endpoint],
+		maxViolations: config.MaxViolations,
+		users:         make(map[string]*utils.KeyedRateLimiter),
+	}
+}
+
+// actionLimit: action의 한도. 따로 정하지 않았으면 "*" 항목을 쓰고, 둘 다 없으면 제한하지 않습니다.
+func (l *wsLimiter) actionLimit(action string) (string, configs.ActionLimit) {
+	if limit, ok := l.limits.Actions[action]; ok {
+		return action, limit
+	}
+	return "*", l.limits.Actions["*"]
+}
+
+func (l *wsLimiter) allowUser(userID, action string, limit configs.RateLimit) bool {
+	if userID == "" || limit.PerSecond <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	users, ok := l.users[action]
+	if !ok {
+		users = utils.NewKeyedRateLimiter(limit.PerSecond, limit.Burst)
+		l.users[action] = users
+	}
+	l.mu.Unlock()
+	return users.Allow(userID)
+}
+
+// connection: 핸들러 시작에 부릅니다. 최대 프레임 크기를 설정하고 연결별 버킷을 만듭니다.
+// disconnect는 한도를 계속 넘길 때 close 프레임을 보내고 읽기를 끝내는 함수입니다.
+func (l *wsLimiter) connection(c *websocket.Conn, disconnect func(code int, reason string)) *wsConnLimiter {
+	if l.limits.MaxMessageSize > 0 {
+		c.SetReadLimit(l.limits.MaxMessageSize)
+	}
+	cl := &wsConnLimiter{limiter: l, disconnect: disconnect, buckets: make(map[string]*utils.TokenBucket)}
+	if claims, ok := c.Locals("user").(*middleware.CustomClaims); ok {
+		cl.userID = claims.UserID
+	}
+	return cl
+}
+
+// wsConnLimiter: 연결 하나의 버킷. 읽기 goroutine에서만 씁니다.
+type wsConnLimiter struct {
+	limiter    *wsLimiter
+	disconnect func(code int, reason string)
+	userID     string
+	buckets    map[string]*utils.TokenBucket
+	violations int
+	closed     bool
+}
+
+// allow: action 메시지를 처리해도 되는지. 한도를 넘기면 false이고, 한도를 maxViolations번 넘기면 연결을 끊습니다.
+func (cl *wsConnLimiter) allow(action string) bool {
+	if cl.closed {
+		return false
+	}
+	l := cl.limiter
+	action, limit := l.actionLimit(action)
+
+	scope := ""
+	if limit.Connection.PerSecond > 0 {
+		bucket, ok := cl.buckets[action]
+		if !ok {
+			bucket = utils.NewTokenBucket(limit.Connection.PerSecond, limit.Connection.Burst)
+			cl.buckets[action] = bucket
+		}
+		if !bucket.Allow() {
+			scope = "connection"
+		}
+	}
+	if scope == "" && !l.allowUser(cl.userID, action, limit.User) {
+		scope = "user"
+	}
+	if scope == "" {
+		return true
+	}
+
+	wsRateLimited.WithLabelValues(l.endpoint, action, scope).Inc()
+	cl.violations++
+	if l.maxViolations > 0 && cl.violations >= l.maxViolations {
+		cl.closed = true
+		wsLimitDisconnects.WithLabelValues(l.endpoint, "rate_limited").Inc()
+		log.Printf("[%s] Disconnecting user %q: rate limit exceeded %d times\n", l.endpoint, cl.userID, cl.violations)
+		cl.disconnect(CloseRateLimited, "rate limit exceeded")
+	}
+	return false
+}
+
+// readError: ReadMessage 오류가 프레임 크기 초과였으면 셉니다.
+// gofiber/websocket의 ErrReadLimit는 별도 변수라 실제로 반환되는 fasthttp 쪽과 비교합니다.
+func (cl *wsConnLimiter) readError(err error) {
+	if errors.Is(err, fastws.ErrReadLimit) {
+		wsOversizedMessages.WithLabelValues(cl.limiter.endpoint).Inc()
+		wsLimitDisconnects.WithLabelValues(cl.limiter.endpoint, "message_too_big").Inc()
+	}
+}
+
+// closeWebSocket: close 프레임을 보내고 읽기를 끝냅니다. WriteControl은 다른 쓰기와 동시에 호출해도 안전합니다.
+func closeWebSocket(c outboundConn, code int, reason string) {
+	_ = c.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	// hijack된 연결은 핸들러가 끝나야 닫히므로 읽기를 바로 끝내 핸들러가 정리하게 함
+	_ = c.SetReadDeadline(time.Now())
+}
@@ -0,0 +1,567 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go-server/configs"
+	middleware "go-server/middlewares"
+	"go-server/repository"
+	"go-server/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+)
+
+// YDocController는 y-websocket 호환 동기화 서버입니다.
+// 클라이언트는 new WebsocketProvider(serverUrl + "/yjs", "note/<id>", doc, { params: { token } })로 접속합니다.
+//
+// 서버는 Yjs CRDT를 직접 해석하지 않고 문서를 update 로그로 보관합니다.
+// 새 클라이언트의 sync step 1에는 로그 전체를 보낸 뒤 빈 state vector로 step 1을 보내고,
+// 로그를 모두 적용한 클라이언트가 돌려준 step 2(문서 전체 상태)로 그때까지의 로그를 하나로 합칩니다.
+// 로그가 압축 기준을 넘으면 방이 쓰기 가능한 연결에 step 1을 보내 전체 상태를 요청합니다.
+//
+// 방은 인스턴스 메모리에 있으므로 한 문서의 연결은 모두 같은 인스턴스로 가야 합니다
+// (로드밸런서에서 /yjs/:kind/:id 경로 기준으로 고정). SetRoomLease를 설정하면
+// 다른 인스턴스가 이미 연 문서로 온 연결은 CloseDocumentOpenElsewhere로 닫습니다.
+type YDocController struct {
+	documents      *DocumentTeamResolver
+	docs           map[string]repository.YDocRepositoryInterface
+	policy         middleware.PermissionPolicy
+	rooms          map[string]*yDocRoom
+	outbound       configs.OutboundConfig
+	limits         *wsLimiter
+	lease          *utils.RoomLease
+	compactUpdates int
+	compactBytes   int
+	mu             sync.Mutex
+}
+
+// CloseDocumentOpenElsewhere: 다른 인스턴스가 이미 연 문서로 온 연결의 close code(HTTP 421 Misdirected Request에 대응)
+const CloseDocumentOpenElsewhere = 4421
+
+// 기본 압축 기준: 로그의 update 수와 전체 바이트 수
+const (
+	defaultYDocCompactUpdates = 500
+	defaultYDocCompactBytes   = 1 << 20
+)
+
+var (
+	errYDocOpenElsewhere = errors.New("document is open on another instance")
+	errYDocRoomClosed    = errors.New("room closed")
+)
+
+func NewYDocController(documents *DocumentTeamResolver, noteDocs, canvasDocs repository.YDocRepositoryInterface, policy middleware.PermissionPolicy) *YDocController {
+	return &YDocController{
+		documents: documents,
+		docs: map[string]repository.YDocRepositoryInterface{
+			DocumentKindNote:   noteDocs,
+			DocumentKindCanvas: canvasDocs,
+		},
+		policy:         policy,
+		rooms:          make(map[string]*yDocRoom),
+		outbound:       yDocOutbound(configs.DefaultOutboundConfig()),
+		limits:         newWSLimiter(configs.EndpointYDoc, configs.DefaultLimitConfig()),
+		compactUpdates: defaultYDocCompactUpdates,
+		compactBytes:   defaultYDocCompactBytes,
+	}
+}
+
+// SetCompaction: 로그가 updates개 또는 bytes바이트 이상이면 압축을 요청합니다. 0이면 그 기준은 쓰지 않습니다.
+// HandleYWebSocket이 불리기 전에 설정해야 합니다.
+func (yc *YDocController) SetCompaction(updates, bytes int) {
+	yc.compactUpdates = updates
+	yc.compactBytes = bytes
+}
+
+// SetRoomLease: 문서 방을 열 때 lease로 소유권을 잡고, 방이 열려 있는 동안 갱신합니다.
+// HandleYWebSocket이 불리기 전에 설정해야 합니다.
+func (yc *YDocController) SetRoomLease(lease *utils.RoomLease) {
+	yc.lease = lease
+}
+
+// SetOutbound: 연결별 송신 큐 크기를 바꿉니다. HandleYWebSocket이 불리기 전에 설정해야 합니다.
+func (yc *YDocController) SetOutbound(outbound configs.OutboundConfig) {
+	yc.outbound = yDocOutbound(outbound)
+}
+
+// SetLimits: 메시지 크기와 메시지 종류("sync", "awareness")별 속도 한도를 바꿉니다. HandleYWebSocket이 불리기 전에 설정해야 합니다.
+func (yc *YDocController) SetLimits(limits configs.LimitConfig) {
+	yc.limits = newWSLimiter(configs.EndpointYDoc, limits)
+}
+
+// yDocOutbound: update를 하나라도 버리면 문서가 어긋나므로 큐가 넘치면 항상 연결을 끊습니다.
+// 다시 연결한 클라이언트는 sync step 1부터 새로 동기화합니다.
+func yDocOutbound(outbound configs.OutboundConfig) configs.OutboundConfig {
+	outbound.Overflow = configs.OverflowDisconnect
+	return outbound
+}
+
+// yDocRoom: 한 문서에 접속한 연결들과 서버가 보관하는 update 로그
+type yDocRoom struct {
+	key            string
+	id             string
+	repo           repository.YDocRepositoryInterface
+	compactUpdates int
+	compactBytes   int
+
+	mu      sync.Mutex
+	loaded  bool
+	closed  bool
+	stop    chan struct{}
+	updates [][]byte
+	size    int
+	// generation은 로그를 압축할 때마다 올라갑니다. 압축 후에는 이전 인덱스가 맞지 않기 때문입니다.
+	generation int
+	// compacting: 압축을 위해 전체 상태를 요청한 연결. 답하거나 나갈 때까지 다시 요청하지 않습니다.
+	compacting *websocket.Conn
+	peers      map[*websocket.Conn]*yDocPeer
+	awareness  map[uint64]utils.YAwarenessState
+}
+
+type yDocPeer struct {
+	out      *outboundQueue
+	readOnly bool
+	// syncedUpTo: step 1에 답하며 보낸 update 수. 이 peer의 다음 step 2는 updates[:syncedUpTo]를 모두 포함합니다. -1이면 없음.
+	syncedUpTo int
+	syncedGen  int
+	// 이 연결이 알린 awareness clientID. 연결이 끊기면 다른 peer에게 제거를 알립니다.
+	awarenessClients map[uint64]bool
+}
+
+// ResolveDocumentTeam은 :kind/:id 문서의 team_id를 돌려줍니다. middleware.RequireTeamMember와 함께 사용합니다.
+func (yc *YDocController) ResolveDocumentTeam(c *fiber.Ctx) (string, error) {
+	return yc.documents.TeamOf(c.Params("kind"), c.Params("id"))
+}
+
+// HandleYWebSocket: y-websocket 프로토콜(sync step 1/2, update, awareness) 핸들러
+func (yc *YDocController) HandleYWebSocket(c *websocket.Conn) {
+	kind, id := c.Params("kind"), c.Params("id")
+	repo, ok := yc.docs[kind]
+	if !ok {
+		closeWebSocket(c, websocket.ClosePolicyViolation, "unknown document kind")
+		return
+	}
+
+	out := newOutboundQueue(c, yc.outbound, "ydoc")
+	defer out.stop()
+	limiter := yc.limits.connection(c, out.disconnect)
+
+	peer := &yDocPeer{
+		out:              out,
+		readOnly:         !yc.canWrite(c),
+		syncedUpTo:       -1,
+		awarenessClients: make(map[uint64]bool),
+	}
+	room, err := yc.joinRoom(kind+"/"+id, id, repo, c, peer)
+	if errors.Is(err, errYDocOpenElsewhere) {
+		log.Printf("[y-websocket] Rejecting %s/%s: %v\n", kind, id, err)
+		closeWebSocket(c, CloseDocumentOpenElsewhere, "document is open on another instance")
+		return
+	}
+	if err != nil {
+		log.Printf("[y-websocket] Failed to load %s/%s: %v\n", kind, id, err)
+		closeWebSocket(c, websocket.CloseInternalServerErr, "failed to load document")
+		return
+	}
+	defer yc.leaveRoom(room, c)
+
+	for {
+		msgType, msg, err := c.ReadMessage()
+		if err != nil {
+			limiter.readError(err)
+			break
+		}
+		if !limiter.allow(yDocAction(msgType, msg)) || msgType != websocket.BinaryMessage {
+			continue
+		}
+		if err := room.handleMessage(c, peer, msg); err != nil {
+			if errors.Is(err, errYDocRoomClosed) {
+				break
+			}
+			log.Printf("[y-websocket] Invalid message in %s: %v\n", room.key, err)
+			break
+		}
+	}
+}
+
+// yDocAction: 속도 한도를 고를 메시지 종류. 첫 varuint로만 판단하고 나머지는 handleMessage가 검사합니다.
+func yDocAction(msgType int, msg []byte) string {
+	if msgType != websocket.BinaryMessage {
+		return ""
+	}
+	messageType, err := utils.NewYDecoder(msg).ReadVarUint()
+	if err != nil {
+		return ""
+	}
+	switch messageType {
+	case utils.YMessageSync:
+		return "sync"
+	case utils.YMessageAwareness:
+		return "awareness"
+	}
+	return ""
+}
+
+// canWrite: 쓰기 권한이 없는 역할은 읽기 전용으로 접속해 update가 무시됩니다.
+func (yc *YDocController) canWrite(c *websocket.Conn) bool {
+	claims, ok := c.Locals("user").(*middleware.CustomClaims)
+	if !ok || yc.policy == nil {
+		return true
+	}
+	return yc.policy.Allows(claims.Role, middleware.PermissionWrite)
+}
+
+func (yc *YDocController) joinRoom(key, id string, repo repository.YDocRepositoryInterface, c *websocket.Conn, peer *yDocPeer) (*yDocRoom, error) {
+	for {
+		yc.mu.Lock()
+		room, ok := yc.rooms[key]
+		if !ok {
+			room = &yDocRoom{
+				key:            key,
+				id:             id,
+				repo:           repo,
+				compactUpdates: yc.compactUpdates,
+				compactBytes:   yc.compactBytes,
+				stop:           make(chan struct{}),
+				peers:          make(map[*websocket.Conn]*yDocPeer),
+				awareness:      make(map[uint64]utils.YAwarenessState),
+			}
+			yc.rooms[key] = room
+		}
+		yc.mu.Unlock()
+
+		room.mu.Lock()
+		if room.closed {
+			// 마지막 연결이 나갔거나 저장 실패로 방이 닫힘. 새 방으로 다시 시도
+			room.mu.Unlock()
+			yc.mu.Lock()
+			if yc.rooms[key] == room {
+				delete(yc.rooms, key)
+			}
+			yc.mu.Unlock()
+			continue
+		}
+		if !room.loaded {
+			if err := yc.load(room); err != nil {
+				room.mu.Unlock()
+				yc.leaveRoom(room, c)
+				return nil, err
+			}
+		}
+		room.peers[c] = peer
+		if len(room.awareness) > 0 {
+			room.send(c, utils.EncodeYAwarenessMessage(room.awarenessUpdate()))
+		}
+		room.mu.Unlock()
+		return room, nil
+	}
+}
+
+// load: 방 소유권을 잡고 저장된 로그를 읽습니다. 방 락을 잡은 채 부릅니다.
+func (yc *YDocController) load(room *yDocRoom) error {
+	if yc.lease != nil {
+		ok, err := yc.lease.Acquire(configs.Ctx, room.key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errYDocOpenElsewhere
+		}
+	}
+	updates, err := room.repo.LoadUpdates(room.id)
+	if err != nil {
+		if yc.lease != nil {
+			_ = yc.lease.Release(configs.Ctx, room.key)
+		}
+		return err
+	}
+	room.setUpdates(updates)
+	room.loaded = true
+	if yc.lease != nil {
+		go room.keepLease(yc.lease)
+	}
+	return nil
+}
+
+// keepLease: 방이 닫힐 때까지 소유권을 갱신하고, 닫히면 놓습니다. 소유권을 잃으면 방을 닫습니다.
+func (r *yDocRoom) keepLease(lease *utils.RoomLease) {
+	ticker := time.NewTicker(lease.TTL() / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			if err := lease.Release(configs.Ctx, r.key); err != nil {
+				log.Printf("[y-websocket] Failed to release %s: %v\n", r.key, err)
+			}
+			return
+		case <-ticker.C:
+			ok, err := lease.Refresh(configs.Ctx, r.key)
+			if err != nil {
+				// Redis가 잠깐 안 되는 것은 TTL 안에서 다시 시도
+				log.Printf("[y-websocket] Failed to refresh %s: %v\n", r.key, err)
+				continue
+			}
+			if !ok {
+				log.Printf("[y-websocket] Lost ownership of %s\n", r.key)
+				r.mu.Lock()
+				r.fail(websocket.CloseTryAgainLater, "document moved to another instance")
+				r.mu.Unlock()
+			}
+		}
+	}
+}
+
+// leaveRoom: 연결을 빼고 그 연결의 awareness를 제거한 뒤, 마지막 연결이면 방을 정리합니다.
+func (yc *YDocController) leaveRoom(room *yDocRoom, c *websocket.Conn) {
+	yc.mu.Lock()
+	defer yc.mu.Unlock()
+	room.mu.Lock()
+	defer room.mu.Unlock()
+
+	if peer, ok := room.peers[c]; ok {
+		delete(room.peers, c)
+		// hijack된 연결은 Close로 닫히지 않으므로 읽기를 끝내 핸들러가 반환되게 함
+		_ = c.SetReadDeadline(time.Now())
+
+		var removed []utils.YAwarenessState
+		for clientID := range peer.awarenessClients {
+			if state, ok := room.awareness[clientID]; ok {
+				removed = append(removed, utils.YAwarenessState{ClientID: clientID, Clock: state.Clock + 1, State: "null"})
+				delete(room.awareness, clientID)
+			}
+		}
+		if len(removed) > 0 {
+			room.broadcast(nil, utils.EncodeYAwarenessMessage(utils.EncodeYAwarenessUpdate(removed)))
+		}
+	}
+
+	if room.compacting == c {
+		room.compacting = nil
+	}
+
+	if len(room.peers) == 0 {
+		room.close()
+		if yc.rooms[room.key] == room {
+			delete(yc.rooms, room.key)
+		}
+	}
+}
+
+// close: 방을 닫습니다. 방 락을 잡은 채 부릅니다.
+func (r *yDocRoom) close() {
+	if !r.closed {
+		r.closed = true
+		close(r.stop)
+	}
+}
+
+// fail: 방을 닫고 모든 연결을 code로 끊습니다. 다시 연결한 클라이언트는 저장된 로그로 새 방을 엽니다.
+func (r *yDocRoom) fail(code int, reason string) {
+	r.close()
+	for _, peer := range r.peers {
+		peer.out.disconnect(code, reason)
+	}
+}
+
+func (r *yDocRoom) setUpdates(updates [][]byte) {
+	r.updates = updates
+	r.size = 0
+	for _, update := range updates {
+		r.size += len(update)
+	}
+}
+
+func (r *yDocRoom) handleMessage(c *websocket.Conn, peer *yDocPeer, msg []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return errYDocRoomClosed
+	}
+
+	d := utils.NewYDecoder(msg)
+	messageType, err := d.ReadVarUint()
+	if err != nil {
+		return err
+	}
+
+	switch messageType {
+	case utils.YMessageSync:
+		syncType, err := d.ReadVarUint()
+		if err != nil {
+			return err
+		}
+		payload, err := d.ReadVarUint8Array()
+		if err != nil {
+			return err
+		}
+		switch syncType {
+		case utils.YSyncStep1:
+			r.sendState(c, peer)
+		case utils.YSyncStep2:
+			coveredUpTo := -1
+			if peer.syncedGen == r.generation {
+				coveredUpTo = peer.syncedUpTo
+			}
+			peer.syncedUpTo = -1
+			if r.compacting == c {
+				r.compacting = nil
+			}
+			if !peer.readOnly {
+				r.applyUpdate(c, payload, coveredUpTo)
+			}
+		case utils.YSyncUpdate:
+			if !peer.readOnly {
+				r.applyUpdate(c, payload, -1)
+			}
+		default:
+			return fmt.Errorf("unknown sync message type %d", syncType)
+		}
+
+	case utils.YMessageAwareness:
+		update, err := d.ReadVarUint8Array()
+		if err != nil {
+			return err
+		}
+		states, err := utils.DecodeYAwarenessUpdate(update)
+		if err != nil {
+			return err
+		}
+		for _, state := range states {
+			if state.Removed() {
+				delete(r.awareness, state.ClientID)
+				delete(peer.awarenessClients, state.ClientID)
+				continue
+			}
+			r.awareness[state.ClientID] = state
+			peer.awarenessClients[state.ClientID] = true
+		}
+		r.broadcast(c, msg)
+
+	case utils.YMessageQueryAwareness:
+		r.send(c, utils.EncodeYAwarenessMessage(r.awarenessUpdate()))
+
+	case utils.YMessageAuth:
+		// 인증은 업그레이드 전에 WebSocketJWT에서 끝남
+
+	default:
+		return errors.New("unknown message type")
+	}
+	return nil
+}
+
+// sendState: 로그를 순서대로 보내고(마지막 하나는 step 2로 보내 클라이언트가 synced가 되게 함),
+// 이어서 빈 state vector로 step 1을 보내 클라이언트의 전체 상태를 요청합니다.
+// 로그가 길어도 송신 큐에서는 한 칸만 차지합니다.
+func (r *yDocRoom) sendState(c *websocket.Conn, peer *yDocPeer) {
+	n := len(r.updates)
+	last := utils.YEmptyUpdate
+	frames := make([][]byte, 0, n+1)
+	if n > 0 {
+		for _, update := range r.updates[:n-1] {
+			frames = append(frames, utils.EncodeYSyncMessage(utils.YSyncUpdate, update))
+		}
+		last = r.updates[n-1]
+	}
+	frames = append(frames,
+		utils.EncodeYSyncMessage(utils.YSyncStep2, last),
+		utils.EncodeYSyncMessage(utils.YSyncStep1, utils.YEmptyStateVector),
+	)
+	if err := peer.out.WriteBatch(websocket.BinaryMessage, frames); err != nil {
+		log.Printf("[y-websocket] Write error in %s: %v\n", r.key, err)
+	}
+	peer.syncedUpTo = n
+	peer.syncedGen = r.generation
+}
+
+// applyUpdate: 로그에 저장하고 다른 연결에 전달합니다.
+// coveredUpTo > 0이면 update가 updates[:coveredUpTo]를 모두 포함하므로 그 부분을 update 하나로 바꿉니다.
+// 저장에 실패하거나 저장된 로그가 방 밖에서 바뀌었으면 방을 닫습니다.
+func (r *yDocRoom) applyUpdate(sender *websocket.Conn, update []byte, coveredUpTo int) {
+	if bytes.Equal(update, utils.YEmptyUpdate) {
+		return
+	}
+	update = bytes.Clone(update)
+
+	if coveredUpTo > 0 && coveredUpTo <= len(r.updates) {
+		compacted := append([][]byte{update}, r.updates[coveredUpTo:]...)
+		ok, err := r.repo.ReplaceUpdates(r.id, compacted, len(r.updates))
+		if err != nil {
+			log.Printf("[y-websocket] Failed to compact %s: %v\n", r.key, err)
+		}
+		if ok {
+			r.broadcast(sender, utils.EncodeYSyncMessage(utils.YSyncUpdate, update))
+			r.setUpdates(compacted)
+			r.generation++
+			return
+		}
+	}
+
+	ok, err := r.repo.AppendUpdate(r.id, update, len(r.updates))
+	if err != nil {
+		log.Printf("[y-websocket] Failed to persist update for %s: %v\n", r.key, err)
+		r.fail(websocket.CloseInternalServerErr, "failed to save document")
+		return
+	}
+	if !ok {
+		// REST 저장이나 복원으로 로그가 지워짐. 다시 연결해 새 내용부터 동기화
+		log.Printf("[y-websocket] %s changed outside the room, closing\n", r.key)
+		r.fail(websocket.CloseTryAgainLater, "document changed")
+		return
+	}
+	r.broadcast(sender, utils.EncodeYSyncMessage(utils.YSyncUpdate, update))
+	r.updates = append(r.updates, update)
+	r.size += len(update)
+	r.requestCompaction(sender)
+}
+
+// requestCompaction: 로그가 압축 기준을 넘으면 방금 update를 보낸(쓰기 가능한) 연결에 전체 상태를 요청합니다.
+// 그 연결의 step 2가 오면 applyUpdate가 그때까지의 로그를 하나로 바꿉니다.
+func (r *yDocRoom) requestCompaction(c *websocket.Conn) {
+	if r.compacting != nil {
+		return
+	}
+	if (r.compactUpdates <= 0 || len(r.updates) < r.compactUpdates) && (r.compactBytes <= 0 || r.size < r.compactBytes) {
+		return
+	}
+	peer, ok := r.peers[c]
+	if !ok || peer.syncedUpTo != -1 {
+		return
+	}
+	r.send(c, utils.EncodeYSyncMessage(utils.YSyncStep1, utils.YEmptyStateVector))
+	peer.syncedUpTo = len(r.updates)
+	peer.syncedGen = r.generation
+	r.compacting = c
+}
+
+func (r *yDocRoom) awarenessUpdate() []byte {
+	states := make([]utils.YAwarenessState, 0, len(r.awareness))
+	for _, state := range r.awareness {
+		states = append(states, state)
+	}
+	return utils.EncodeYAwarenessUpdate(states)
+}
+
+// send: 연결의 송신 큐에 넣습니다. 방 락을 잡은 채 불러도 느린 연결을 기다리지 않습니다.
+func (r *yDocRoom) send(c *websocket.Conn, msg []byte) {
+	peer, ok := r.peers[c]
+	if !ok {
+		return
+	}
+	if err := peer.out.WriteMessage(websocket.BinaryMessage, msg); err != nil {
+		log.Printf("[y-websocket] Write error in %s: %v\n", r.key, err)
+	}
+}
+
+// broadcast: sender를 제외한 방의 모든 연결에 보냅니다. sender가 nil이면 전부에게 보냅니다.
+func (r *yDocRoom) broadcast(sender *websocket.Conn, msg []byte) {
+	for conn := range r.peers {
+		if conn != sender {
+			r.send(conn, msg)
+		}
+	}
+}
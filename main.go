@@ -1,18 +1,23 @@
 package main
 
 import (
+	"context"
 	"go-server/configs"
 	"go-server/controllers"
+	middleware "go-server/middlewares"
 	"go-server/repository"
 	"go-server/routes"
 	"go-server/server"
 	"go-server/utils"
 	"log"
 	"os"
+	"time"
 
 	fiberprometheus "github.com/ansrivas/fiberprometheus/v2"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func main() {
@@ -34,20 +39,96 @@ func main() {
 	redisClient := configs.GetRedisClient()
 
 	collection := client.Database("mydb").Collection("notes")
+	collectionNoteRevisions := client.Database("mydb").Collection("note_revisions")
 	collectionCanvas := client.Database("mydb").Collection("canvases")
+	collectionCanvasSnapshots := client.Database("mydb").Collection("canvas_snapshots")
 
-	noteRepo := repository.NewNoteRepository(collection)
+	noteRepo := repository.NewNoteRepository(collection, collectionNoteRevisions)
 	noteController := controllers.NewNoteController(noteRepo)
 
-	participantRepo := repository.NewParticipantRepository(redisClient)
-	participantsController := controllers.NewParticipantsController(participantRepo)
+	store := utils.NewPublicKeyStore(redisClient, configs.LoadKeyRetention())
+	store.SetCacheTTL(configs.LoadKeyCacheTTL())
+	if err := store.SubscribeInvalidations(context.Background()); err != nil {
+		log.Printf("Public key cache invalidation across replicas disabled: %v", err)
+	}
+	if jwksConfig := configs.LoadJWKSConfig(); jwksConfig.URL != "" {
+		jwksClient := utils.NewJWKSClient(jwksConfig.URL, store, jwksConfig.RefreshInterval, jwksConfig.MinRefreshInterval)
+		store.SetMissingKeyLoader(jwksClient.LoadMissingKey)
+		go jwksClient.Run(context.Background())
+	}
+	revocations := utils.NewTokenRevocationStore(redisClient, configs.LoadAccessTokenMaxLifetime())
+	limits := configs.LoadLimitConfig()
+	sessions := middleware.NewSessionRegistry()
+	sessions.SetMaxConnectionsPerUser(limits.MaxConnectionsPerUser)
+	err = revocations.SubscribeRevocations(context.Background(), func(revocation utils.TokenRevocation) {
+		sessions.DisconnectRevoked(context.Background(), revocations, revocation)
+	})
+	if err != nil {
+		log.Printf("Disconnecting revoked WebSocket sessions disabled: %v", err)
+	}
 
-	audioController := controllers.NewAudioSocketController()
+	membership := middleware.AnyTeamMembership{
+		middleware.ClaimsTeamMembership{},
+		middleware.NewRedisTeamMembership(redisClient),
+	}
 
-	canvasRepo := repository.NewCanvasRepository(collectionCanvas)
-	canvasController := controllers.NewCanvasController(canvasRepo)
+	policy := middleware.DefaultPermissionPolicy()
+	if table := configs.LoadPermissionPolicy(); table != nil {
+		policy = middleware.PermissionPolicy(table)
+	}
+
+	instanceID := configs.LoadInstanceID()
+	processID := configs.NewProcessID(instanceID)
+	participantRepo := repository.NewParticipantRepository(redisClient, instanceID)
+	participantsController := controllers.NewParticipantsController(participantRepo, policy, membership)
+	participantsController.SetPresence(configs.LoadPresenceConfig())
+	outbound := configs.LoadOutboundConfig()
+	participantsController.SetOutbound(outbound)
+	participantsController.SetLimits(limits)
 
-	store := utils.NewPublicKeyStore(redisClient)
+	mediaController := controllers.NewMediaSocketController(membership)
+	mediaController.SetLimits(limits)
+	mediaController.SetOutbound(outbound)
+	mediaController.SetICE(configs.LoadICEConfig())
+
+	fanout := utils.NewRoomFanout(redisClient, processID)
+	participantsController.SetFanout(fanout)
+
+	canvasRepo := repository.NewCanvasRepository(collectionCanvas)
+	snapshotConfig := configs.LoadCanvasSnapshotConfig()
+	snapshotBucket, err := gridfs.NewBucket(client.Database("mydb"), options.GridFSBucket().SetName("canvas_snapshot_contents"))
+	if err != nil {
+		log.Fatalf("Failed to create GridFS bucket: %v", err)
+	}
+	canvasSnapshotRepo := repository.NewCanvasSnapshotRepository(
+		collectionCanvasSnapshots,
+		repository.NewInlineSnapshotContentStore(collectionCanvasSnapshots),
+		repository.NewGridFSSnapshotContentStore(snapshotBucket),
+		snapshotConfig.GridFSThreshold,
+	)
+	canvasController := controllers.NewCanvasController(canvasRepo, canvasSnapshotRepo, snapshotConfig)
+
+	documents := controllers.NewDocumentTeamResolver(noteRepo, canvasRepo)
+	signalingController := controllers.NewWebSocketController(documents, membership)
+	signalingController.SetFanout(fanout)
+	signalingController.SetOutbound(outbound)
+	signalingController.SetLimits(limits)
+	if err := fanout.Subscribe(context.Background()); err != nil {
+		log.Printf("WebSocket room fan-out across replicas disabled: %v", err)
+	}
+	if err := participantsController.ReconcileParticipants(context.Background()); err != nil {
+		log.Printf("Failed to reconcile participants left by a previous run: %v", err)
+	}
+	go participantsController.RunPresenceReaper(context.Background())
+	ydocController := controllers.NewYDocController(
+		documents,
+		repository.NewYDocRepository(collection),
+		repository.NewYDocRepository(collectionCanvas),
+		policy,
+	)
+	ydocController.SetOutbound(outbound)
+	ydocController.SetLimits(limits)
+	ydocController.SetRoomLease(utils.NewRoomLease(redisClient, processID, 30*time.Second))
 
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
@@ -72,9 +153,10 @@ func main() {
 		AllowMethods: "GET,POST,HEAD,PUT,DELETE,PATCH,OPTIONS",
 	}))
 
-	routes.NoteRoutes(app, noteController, store)
-	routes.WebSocketRoutes(app, participantsController, audioController)
-	routes.CanvasRoutes(app, canvasController, store)
+	routes.NoteRoutes(app, noteController, store, revocations, membership, policy)
+	routes.WebSocketRoutes(app, participantsController, mediaController, signalingController, store, revocations, sessions)
+	routes.CanvasRoutes(app, canvasController, store, revocations, membership, policy)
+	routes.YDocRoutes(app, ydocController, store, revocations, sessions, membership, policy)
 
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -82,8 +164,9 @@ func main() {
 		})
 	})
 
+	grpcSecurity := configs.LoadGRPCSecurityConfig()
 	go func() {
-		if err := server.RunGRPCServer(store); err != nil {
+		if err := server.RunGRPCServer(store, revocations, grpcSecurity, configs.LoadAuthTimeZone()); err != nil {
 			log.Fatalf("Failed to start gRPC server: %v", err)
 		}
 		log.Println("gRPC server started")
@@ -3,8 +3,10 @@ package middleware
 import (
 	"context"
 	"errors"
+	"fmt"
 	"go-server/utils"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
@@ -13,12 +15,13 @@ import (
 type CustomClaims struct {
 	jwt.RegisteredClaims
 
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	UserID   string   `json:"user_id"`
+	Username string   `json:"username"`
+	Role     string   `json:"role"`
+	Teams    []string `json:"teams"`
 }
 
-func JWTParser(store *utils.PublicKeyStore) fiber.Handler {
+func JWTParser(store *utils.PublicKeyStore, revocations *utils.TokenRevocationStore) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
@@ -34,7 +37,7 @@ func JWTParser(store *utils.PublicKeyStore) fiber.Handler {
 			})
 		}
 
-		claims, err := ParseJWT(tokenString, store)
+		claims, err := ParseJWT(tokenString, store, revocations)
 		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Invalid JWT: " + err.Error(),
@@ -46,7 +49,8 @@ func JWTParser(store *utils.PublicKeyStore) fiber.Handler {
 	}
 }
 
-func ParseJWT(tokenString string, store *utils.PublicKeyStore) (*CustomClaims, error) {
+// ParseJWT는 서명과 만료를 검증하고, revocations가 있으면 폐기된 토큰도 거부합니다.
+func ParseJWT(tokenString string, store *utils.PublicKeyStore, revocations *utils.TokenRevocationStore) (*CustomClaims, error) {
 	// 1) 토큰 헤더에서 kid 추출
 	token, _, err := new(jwt.Parser).ParseUnverified(tokenString, &CustomClaims{})
 	if err != nil {
@@ -64,21 +68,44 @@ func ParseJWT(tokenString string, store *utils.PublicKeyStore) (*CustomClaims, e
 		return nil, err
 	}
 
-	// 3) 실제 검증
+	// 3) 실제 검증 - 키에 등록된 알고리즘만 허용 (alg 헤더로 검증 방식을 바꾸는 algorithm confusion 방지)
 	parsedToken, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, errors.New("unexpected signing method")
+		if token.Method.Alg() != pubKey.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method %s for kid %s", token.Method.Alg(), kid)
 		}
-		return pubKey, nil
-	})
+		return pubKey.Key, nil
+	}, jwt.WithValidMethods([]string{pubKey.Algorithm}))
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := parsedToken.Claims.(*CustomClaims); ok && parsedToken.Valid {
-		return claims, nil
+	claims, ok := parsedToken.Claims.(*CustomClaims)
+	if !ok || !parsedToken.Valid {
+		return nil, errors.New("invalid token")
 	}
 
-	return nil, errors.New("invalid token")
+	// 4) 폐기 여부 - Redis를 확인할 수 없으면 거부
+	revoked, err := IsTokenRevoked(context.Background(), claims, revocations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if revoked {
+		return nil, errors.New("token has been revoked")
+	}
+
+	return claims, nil
+}
+
+// IsTokenRevoked는 claims의 jti와 사용자 ID로 폐기 목록을 확인합니다. revocations가 nil이면 확인하지 않습니다.
+func IsTokenRevoked(ctx context.Context, claims *CustomClaims, revocations *utils.TokenRevocationStore) (bool, error) {
+	if revocations == nil {
+		return false, nil
+	}
+
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	return revocations.IsRevoked(ctx, claims.ID, claims.UserID, issuedAt)
 }
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+)
+
+const (
+	PermissionRead            = "read"
+	PermissionWrite           = "write"
+	PermissionDelete          = "delete"
+	PermissionKickParticipant = "kick_participant"
+)
+
+// PermissionPolicy는 역할(role)별로 허용된 권한 목록입니다.
+type PermissionPolicy map[string][]string
+
+func DefaultPermissionPolicy() PermissionPolicy {
+	return PermissionPolicy{
+		RoleViewer: {PermissionRead},
+		RoleEditor: {PermissionRead, PermissionWrite},
+		RoleOwner:  {PermissionRead, PermissionWrite, PermissionDelete, PermissionKickParticipant},
+	}
+}
+
+// normalizeRole은 "ROLE_OWNER" 같은 Spring Security 형식도 "owner"로 맞춥니다.
+func normalizeRole(role string) string {
+	return strings.ToLower(strings.TrimPrefix(role, "ROLE_"))
+}
+
+func (p PermissionPolicy) Allows(role, permission string) bool {
+	for _, granted := range p[normalizeRole(role)] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission은 JWTParser 뒤에 두어, claims의 Role에 permission이 없으면 403을 반환합니다.
+func RequirePermission(policy PermissionPolicy, permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("user").(*CustomClaims)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Missing user claims",
+			})
+		}
+		if !policy.Allows(claims.Role, permission) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Role '" + claims.Role + "' is not allowed to " + permission,
+			})
+		}
+		return c.Next()
+	}
+}
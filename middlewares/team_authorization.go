@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2"
+)
+
+// TeamMembershipProvider는 사용자가 팀에 속해 있는지 판단합니다.
+type TeamMembershipProvider interface {
+	IsMember(ctx context.Context, claims *CustomClaims, teamID string) (bool, error)
+}
+
+// ClaimsTeamMembership은 토큰의 teams 클레임만으로 판단합니다.
+type ClaimsTeamMembership struct{}
+
+func (ClaimsTeamMembership) IsMember(ctx context.Context, claims *CustomClaims, teamID string) (bool, error) {
+	for _, t := range claims.Teams {
+		if t == teamID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RedisTeamMembership은 team:<teamID>:members Set에 사용자 ID가 있는지 확인합니다.
+type RedisTeamMembership struct {
+	redisClient *redis.Client
+}
+
+func NewRedisTeamMembership(redisClient *redis.Client) *RedisTeamMembership {
+	return &RedisTeamMembership{redisClient: redisClient}
+}
+
+func (m *RedisTeamMembership) IsMember(ctx context.Context, claims *CustomClaims, teamID string) (bool, error) {
+	if claims.UserID == "" {
+		return false, nil
+	}
+	key := fmt.Sprintf("team:%s:members", teamID)
+	ok, err := m.redisClient.SIsMember(ctx, key, claims.UserID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to SIsMember team membership in Redis: %w", err)
+	}
+	return ok, nil
+}
+
+// AnyTeamMembership은 provider 중 하나라도 멤버라고 하면 통과시킵니다.
+type AnyTeamMembership []TeamMembershipProvider
+
+func (providers AnyTeamMembership) IsMember(ctx context.Context, claims *CustomClaims, teamID string) (bool, error) {
+	var lastErr error
+	for _, p := range providers {
+		ok, err := p.IsMember(ctx, claims, teamID)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, lastErr
+}
+
+// TeamResolver는 요청이 접근하려는 문서의 team_id를 찾아냅니다.
+// 404 등 특정 상태로 응답하려면 *fiber.Error를 반환합니다.
+type TeamResolver func(c *fiber.Ctx) (string, error)
+
+func TeamFromParam(name string) TeamResolver {
+	return func(c *fiber.Ctx) (string, error) {
+		return c.Params(name), nil
+	}
+}
+
+// RequireTeamMember는 JWTParser 뒤에 두어, 호출자가 resolve한 팀의 멤버가 아니면 403을 반환합니다.
+func RequireTeamMember(membership TeamMembershipProvider, resolve TeamResolver) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("user").(*CustomClaims)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Missing user claims",
+			})
+		}
+
+		teamID, err := resolve(c)
+		if err != nil {
+			var fe *fiber.Error
+			if errors.As(err, &fe) {
+				return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to resolve team",
+			})
+		}
+		if teamID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Missing team_id",
+			})
+		}
+
+		member, err := membership.IsMember(c.Context(), claims, teamID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to check team membership",
+			})
+		}
+		if !member {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Not a member of this team",
+			})
+		}
+
+		c.Locals("team_id", teamID)
+		return c.Next()
+	}
+}
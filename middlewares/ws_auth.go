@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"go-server/utils"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+)
+
+// WebSocketTokenProtocol은 브라우저가 new WebSocket(url, ["access_token", jwt])로 토큰을 보낼 때 쓰는 서브프로토콜 이름입니다.
+// 서버는 이 이름을 응답에 선택해야 브라우저가 연결을 유지합니다.
+const WebSocketTokenProtocol = "access_token"
+
+// webSocketToken은 Sec-WebSocket-Protocol, token 쿼리 파라미터, Authorization 헤더 순으로 토큰을 찾습니다.
+func webSocketToken(c *fiber.Ctx) string {
+	protocols := strings.Split(c.Get(fiber.HeaderSecWebSocketProtocol), ",")
+	for i := 0; i+1 < len(protocols); i++ {
+		if strings.TrimSpace(protocols[i]) == WebSocketTokenProtocol {
+			return strings.TrimSpace(protocols[i+1])
+		}
+	}
+
+	if token := c.Query("token"); token != "" {
+		return token
+	}
+
+	return strings.TrimPrefix(c.Get("Authorization"), "Bearer ")
+}
+
+// WebSocketJWT는 업그레이드 전에 토큰을 검증하고 claims를 Locals("user")에 저장합니다.
+// 핸들러에서는 websocket.Conn.Locals("user")로 꺼낼 수 있습니다.
+func WebSocketJWT(store *utils.PublicKeyStore, revocations *utils.TokenRevocationStore) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !websocket.IsWebSocketUpgrade(c) {
+			return fiber.ErrUpgradeRequired
+		}
+
+		tokenString := webSocketToken(c)
+		if tokenString == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Missing access token",
+			})
+		}
+
+		claims, err := ParseJWT(tokenString, store, revocations)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid JWT: " + err.Error(),
+			})
+		}
+
+		c.Locals("user", claims)
+		return c.Next()
+	}
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"go-server/utils"
+
+	"github.com/gofiber/websocket/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// CloseTooManyConnections: 사용자별 연결 수 한도를 넘어 거절한 연결의 close code
+const CloseTooManyConnections = 4409
+
+var wsRejectedConnections = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "websocket_rejected_connections_total",
+	Help: "WebSocket connections closed right after the upgrade, by reason.",
+}, []string{"reason"})
+
+// SessionRegistry는 인증된 WebSocket 연결을 추적해 토큰이 폐기되면 연결을 끊습니다.
+// 사용자별 연결 수도 세어, 한도를 넘는 새 연결은 핸들러를 부르지 않고 닫습니다.
+type SessionRegistry struct {
+	mu         sync.Mutex
+	sessions   map[*websocket.Conn]*CustomClaims
+	users      map[string]int
+	maxPerUser int
+}
+
+func NewSessionRegistry() *SessionRegistry {
+	return &SessionRegistry{
+		sessions: make(map[*websocket.Conn]*CustomClaims),
+		users:    make(map[string]int),
+	}
+}
+
+// SetMaxConnectionsPerUser: 이 인스턴스에서 한 사용자가 동시에 열 수 있는 연결 수(모든 엔드포인트 합계). 0이면 제한하지 않습니다.
+func (r *SessionRegistry) SetMaxConnectionsPerUser(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxPerUser = n
+}
+
+// Track은 핸들러가 도는 동안 연결을 등록합니다. websocket.New(registry.Track(handler))처럼 씁니다.
+func (r *SessionRegistry) Track(handler func(*websocket.Conn)) func(*websocket.Conn) {
+	return func(c *websocket.Conn) {
+		claims, ok := c.Locals("user").(*CustomClaims)
+		if !ok {
+			handler(c)
+			return
+		}
+
+		r.mu.Lock()
+		if r.maxPerUser > 0 && r.users[claims.UserID] >= r.maxPerUser {
+			r.mu.Unlock()
+			wsRejectedConnections.WithLabelValues("too_many_connections").Inc()
+			log.Printf("Rejecting WebSocket connection of user %s: more than %d connections", claims.UserID, r.maxPerUser)
+			_ = c.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(CloseTooManyConnections, "too many connections"),
+				time.Now().Add(time.Second))
+			return
+		}
+		r.sessions[c] = claims
+		r.users[claims.UserID]++
+		r.mu.Unlock()
+
+		defer func() {
+			r.mu.Lock()
+			delete(r.sessions, c)
+			if r.users[claims.UserID]--; r.users[claims.UserID] <= 0 {
+				delete(r.users, claims.UserID)
+			}
+			r.mu.Unlock()
+		}()
+
+		handler(c)
+	}
+}
+
+// DisconnectRevoked는 폐기 알림에 해당하는 세션 중 실제로 폐기된 토큰을 쓰는 연결을 닫고, 닫은 수를 반환합니다.
+// close 프레임을 보낸 뒤 읽기 기한을 지금으로 당겨 핸들러의 ReadMessage가 실패하게 하므로 각 컨트롤러의 정리 로직이 그대로 실행됩니다.
+// hijack된 연결은 핸들러가 끝나야 닫히므로 Close만으로는 close 프레임을 무시하는 클라이언트를 끊을 수 없습니다.
+// Redis 조회와 close 프레임 전송은 락 밖에서 하므로 그동안에도 연결 등록/해제가 막히지 않습니다.
+func (r *SessionRegistry) DisconnectRevoked(ctx context.Context, revocations *utils.TokenRevocationStore, revocation utils.TokenRevocation) int {
+	type session struct {
+		conn   *websocket.Conn
+		claims *CustomClaims
+	}
+
+	r.mu.Lock()
+	var matched []session
+	for c, claims := range r.sessions {
+		if (revocation.JTI == "" || claims.ID != revocation.JTI) && (revocation.UserID == "" || claims.UserID != revocation.UserID) {
+			continue
+		}
+		matched = append(matched, session{conn: c, claims: claims})
+	}
+	r.mu.Unlock()
+
+	closed := 0
+	for _, s := range matched {
+		revoked, err := IsTokenRevoked(ctx, s.claims, revocations)
+		if err != nil {
+			log.Printf("Failed to check revocation for user %s: %v", s.claims.UserID, err)
+			continue
+		}
+		if !revoked {
+			continue
+		}
+
+		log.Printf("Closing WebSocket session of user %s: token revoked", s.claims.UserID)
+		_ = s.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "token revoked"),
+			time.Now().Add(time.Second))
+		_ = s.conn.SetReadDeadline(time.Now())
+		closed++
+	}
+	return closed
+}
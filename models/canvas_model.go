@@ -8,4 +8,5 @@ type Canvas struct {
 	Title     string    `bson:"title" json:"title"`
 	Canvas    string    `bson:"canvas" json:"canvas"`
 	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
 }
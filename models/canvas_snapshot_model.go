@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+const (
+	SnapshotStorageInline = "inline"
+	SnapshotStorageGridFS = "gridfs"
+)
+
+type CanvasSnapshot struct {
+	ID        string    `bson:"_id,omitempty" json:"id,omitempty"`
+	CanvasID  string    `bson:"canvas_id" json:"canvas_id"`
+	TeamID    string    `bson:"team_id" json:"team_id"`
+	Name      string    `bson:"name" json:"name"`
+	Title     string    `bson:"title" json:"title"`
+	Auto      bool      `bson:"auto" json:"auto"`
+	Size      int       `bson:"size" json:"size"`
+	Storage   string    `bson:"storage" json:"storage"`
+	AuthorID  string    `bson:"author_id" json:"author_id"`
+	Canvas    string    `bson:"-" json:"canvas,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
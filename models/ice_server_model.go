@@ -0,0 +1,14 @@
+package models
+
+// ICEServer: 브라우저의 RTCIceServer와 같은 모양
+type ICEServer struct {
+	URLs       []string `json:"urls"`
+	Username   string   `json:"username,omitempty"`
+	Credential string   `json:"credential,omitempty"`
+}
+
+// ICEServersResponse: GET /webrtc/ice-servers 응답. TTL(초)이 지나기 전에 다시 받아야 합니다(임시 자격 증명이 없으면 0).
+type ICEServersResponse struct {
+	ICEServers []ICEServer `json:"iceServers"`
+	TTL        int64       `json:"ttl,omitempty"`
+}
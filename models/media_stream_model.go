@@ -0,0 +1,27 @@
+package models
+
+// 미디어 트랙의 출처. 클라이언트는 offer의 sources에 자기 MediaStream id별 출처를 적습니다.
+// 적지 않은 스트림은 오디오면 microphone, 비디오면 camera로 봅니다.
+const (
+	MediaSourceMicrophone = "microphone"
+	MediaSourceCamera     = "camera"
+	MediaSourceScreen     = "screen"
+)
+
+// MediaTrackInfo: SFU가 중계하는 트랙 하나. 받는 쪽 MediaStreamTrack.id와 MediaStream.id가 TrackID, StreamID입니다.
+// 화면 공유는 참가자마다 마이크/카메라와 다른 스트림으로 갑니다.
+type MediaTrackInfo struct {
+	TrackID       string `json:"trackId"`
+	StreamID      string `json:"streamId"`
+	ParticipantID string `json:"participantId"`
+	ConnectionID  string `json:"connectionId"` // 같은 참가자의 여러 연결(탭)을 구분
+	Kind          string `json:"kind"`         // audio, video
+	Source        string `json:"source"`
+	MimeType      string `json:"mimeType"`
+}
+
+// MediaStreamsMessage: {"type":"streams"}. 이 연결이 받는(자기 것을 뺀) 팀의 트랙 전체이며 바뀔 때마다 다시 보냅니다.
+type MediaStreamsMessage struct {
+	Type   string           `json:"type"`
+	Tracks []MediaTrackInfo `json:"tracks"`
+}
@@ -5,7 +5,7 @@ import "time"
 type Note struct {
 	ID        string    `bson:"_id,omitempty" json:"id,omitempty"`
 	TeamID    string    `bson:"team_id" json:"team_id"`
-	Version   int       `bson:"version"`
+	Version   int       `bson:"version" json:"version"`
 	Title     string    `bson:"title" json:"title"`
 	Note      string    `bson:"note" json:"note"`
 	CreatedAt time.Time `bson:"created_at" json:"created_at"`
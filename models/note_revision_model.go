@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+type NoteRevision struct {
+	ID           string    `bson:"_id,omitempty" json:"id,omitempty"`
+	NoteID       string    `bson:"note_id" json:"note_id"`
+	TeamID       string    `bson:"team_id" json:"team_id"`
+	Version      int       `bson:"version" json:"version"`
+	Title        string    `bson:"title" json:"title"`
+	Note         string    `bson:"note,omitempty" json:"note,omitempty"`
+	AuthorID     string    `bson:"author_id" json:"author_id"`
+	RestoredFrom int       `bson:"restored_from,omitempty" json:"restored_from,omitempty"`
+	CreatedAt    time.Time `bson:"created_at" json:"created_at"`
+}
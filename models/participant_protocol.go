@@ -0,0 +1,159 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ParticipantProtocolVersion: /ws 참가자 프로토콜 버전. 호환되지 않게 바뀔 때만 올립니다.
+// "v"가 없는 메시지는 봉투 없이 필드를 평평하게 보내던 이전 클라이언트(버전 0)로 봅니다.
+const ParticipantProtocolVersion = 1
+
+// 클라이언트 → 서버 action
+const (
+	ActionAddParticipant         = "addParticipant"
+	ActionRemoveParticipant      = "removeParticipant"
+	ActionGetParticipants        = "getParticipants"
+	ActionAddAudioParticipant    = "addAudioParticipant"
+	ActionRemoveAudioParticipant = "removeAudioParticipant"
+	ActionGetAudioParticipants   = "getAudioParticipants"
+)
+
+// 서버 → 클라이언트 action (get* 응답은 요청과 같은 action을 씁니다)
+const (
+	ActionUpdateParticipants      = "updateParticipants"
+	ActionUpdateAudioParticipants = "updateAudioParticipants"
+	ActionAck                     = "ack"
+	ActionError                   = "error"
+)
+
+// ParticipantError.Code 값
+const (
+	ErrorCodeInvalidMessage     = "invalid_message"
+	ErrorCodeUnsupportedVersion = "unsupported_version"
+	ErrorCodeUnknownAction      = "unknown_action"
+	ErrorCodeForbidden          = "forbidden"
+	ErrorCodeInternal           = "internal"
+	ErrorCodeRateLimited        = "rate_limited"
+)
+
+// ParticipantRequest: 클라이언트가 보내는 메시지 봉투
+// 예) {"v":1,"action":"addParticipant","requestId":"r1","payload":{"team_id":"t1","kind":"canvas"}}
+type ParticipantRequest struct {
+	Version   int             `json:"v"`
+	Action    string          `json:"action"`
+	RequestID string          `json:"requestId,omitempty"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// ParticipantRoomPayload: getParticipants, getAudioParticipants의 payload
+type ParticipantRoomPayload struct {
+	TeamID string `json:"team_id"`
+	Kind   string `json:"kind"`
+}
+
+// ParticipantJoinPayload: addParticipant, addAudioParticipant의 payload
+// 인증된 연결에서는 participant와 name 대신 토큰의 사용자 ID와 이름이 쓰입니다.
+type ParticipantJoinPayload struct {
+	TeamID         string `json:"team_id"`
+	Kind           string `json:"kind"`
+	Participant    string `json:"participant,omitempty"`
+	Name           string `json:"name,omitempty"`
+	ProfilePicture string `json:"profilePicture,omitempty"`
+	Color          string `json:"color,omitempty"`
+}
+
+// ParticipantLeavePayload: removeParticipant, removeAudioParticipant의 payload
+type ParticipantLeavePayload struct {
+	TeamID      string `json:"team_id"`
+	Kind        string `json:"kind"`
+	Participant string `json:"participant"`
+}
+
+// ParticipantsMessage: updateParticipants 브로드캐스트와 getParticipants 응답
+type ParticipantsMessage struct {
+	Version      int           `json:"v"`
+	Action       string        `json:"action"`
+	RequestID    string        `json:"requestId,omitempty"`
+	Participants []Participant `json:"participants"`
+}
+
+// AudioParticipantsMessage: updateAudioParticipants 브로드캐스트와 getAudioParticipants 응답
+type AudioParticipantsMessage struct {
+	Version           int           `json:"v"`
+	Action            string        `json:"action"`
+	RequestID         string        `json:"requestId,omitempty"`
+	AudioParticipants []Participant `json:"audioParticipants"`
+}
+
+// ParticipantAck: requestId를 붙여 보낸 add*, remove* 요청이 처리되면 보냅니다.
+type ParticipantAck struct {
+	Version   int    `json:"v"`
+	Action    string `json:"action"`
+	RequestID string `json:"requestId"`
+	For       string `json:"for"`
+}
+
+// ParticipantError: 처리하지 못한 메시지에 대한 응답. For는 알아낸 경우 원래 action입니다.
+type ParticipantError struct {
+	Version   int    `json:"v"`
+	Action    string `json:"action"`
+	RequestID string `json:"requestId,omitempty"`
+	For       string `json:"for,omitempty"`
+	Code      string `json:"code"`
+	Error     string `json:"error"`
+}
+
+// ParticipantClientPayloads: action별 payload 타입. 디코딩과 JSON Schema 생성이 함께 씁니다.
+var ParticipantClientPayloads = map[string]interface{}{
+	ActionAddParticipant:         ParticipantJoinPayload{},
+	ActionRemoveParticipant:      ParticipantLeavePayload{},
+	ActionGetParticipants:        ParticipantRoomPayload{},
+	ActionAddAudioParticipant:    ParticipantJoinPayload{},
+	ActionRemoveAudioParticipant: ParticipantLeavePayload{},
+	ActionGetAudioParticipants:   ParticipantRoomPayload{},
+}
+
+// ParticipantServerMessages: 서버가 보내는 메시지 타입들
+var ParticipantServerMessages = []interface{}{
+	ParticipantsMessage{},
+	AudioParticipantsMessage{},
+	ParticipantAck{},
+	ParticipantError{},
+}
+
+var (
+	ErrMissingTeamID      = errors.New("team_id is required")
+	ErrInvalidKind        = errors.New("kind must be one of canvas, note, audio")
+	ErrMissingParticipant = errors.New("participant is required")
+)
+
+func validateRoom(teamID, kind string) error {
+	if teamID == "" {
+		return ErrMissingTeamID
+	}
+	switch kind {
+	case KindCanvas, KindNote, KindAudio:
+		return nil
+	default:
+		return ErrInvalidKind
+	}
+}
+
+func (p ParticipantRoomPayload) Validate() error {
+	return validateRoom(p.TeamID, p.Kind)
+}
+
+func (p ParticipantJoinPayload) Validate() error {
+	return validateRoom(p.TeamID, p.Kind)
+}
+
+func (p ParticipantLeavePayload) Validate() error {
+	if err := validateRoom(p.TeamID, p.Kind); err != nil {
+		return err
+	}
+	if p.Participant == "" {
+		return ErrMissingParticipant
+	}
+	return nil
+}
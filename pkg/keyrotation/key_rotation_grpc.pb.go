@@ -19,7 +19,12 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	KeyRotationNotifyService_NotifyKeyRolled_FullMethodName = "/KeyRotationNotifyService/NotifyKeyRolled"
+	KeyRotationNotifyService_NotifyKeyRolled_FullMethodName  = "/KeyRotationNotifyService/NotifyKeyRolled"
+	KeyRotationNotifyService_ListKeys_FullMethodName         = "/KeyRotationNotifyService/ListKeys"
+	KeyRotationNotifyService_RevokeKey_FullMethodName        = "/KeyRotationNotifyService/RevokeKey"
+	KeyRotationNotifyService_WatchKeys_FullMethodName        = "/KeyRotationNotifyService/WatchKeys"
+	KeyRotationNotifyService_RevokeToken_FullMethodName      = "/KeyRotationNotifyService/RevokeToken"
+	KeyRotationNotifyService_RevokeUserTokens_FullMethodName = "/KeyRotationNotifyService/RevokeUserTokens"
 )
 
 // KeyRotationNotifyServiceClient is the client API for KeyRotationNotifyService service.
@@ -27,6 +32,11 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type KeyRotationNotifyServiceClient interface {
 	NotifyKeyRolled(ctx context.Context, in *NotifyKeyRolledRequest, opts ...grpc.CallOption) (*NotifyKeyRolledResponse, error)
+	ListKeys(ctx context.Context, in *ListKeysRequest, opts ...grpc.CallOption) (*ListKeysResponse, error)
+	RevokeKey(ctx context.Context, in *RevokeKeyRequest, opts ...grpc.CallOption) (*RevokeKeyResponse, error)
+	WatchKeys(ctx context.Context, in *WatchKeysRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[KeyEvent], error)
+	RevokeToken(ctx context.Context, in *RevokeTokenRequest, opts ...grpc.CallOption) (*RevokeTokenResponse, error)
+	RevokeUserTokens(ctx context.Context, in *RevokeUserTokensRequest, opts ...grpc.CallOption) (*RevokeUserTokensResponse, error)
 }
 
 type keyRotationNotifyServiceClient struct {
@@ -47,11 +57,75 @@ func (c *keyRotationNotifyServiceClient) NotifyKeyRolled(ctx context.Context, in
 	return out, nil
 }
 
+func (c *keyRotationNotifyServiceClient) ListKeys(ctx context.Context, in *ListKeysRequest, opts ...grpc.CallOption) (*ListKeysResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListKeysResponse)
+	err := c.cc.Invoke(ctx, KeyRotationNotifyService_ListKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyRotationNotifyServiceClient) RevokeKey(ctx context.Context, in *RevokeKeyRequest, opts ...grpc.CallOption) (*RevokeKeyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RevokeKeyResponse)
+	err := c.cc.Invoke(ctx, KeyRotationNotifyService_RevokeKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyRotationNotifyServiceClient) WatchKeys(ctx context.Context, in *WatchKeysRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[KeyEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &KeyRotationNotifyService_ServiceDesc.Streams[0], KeyRotationNotifyService_WatchKeys_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchKeysRequest, KeyEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type KeyRotationNotifyService_WatchKeysClient = grpc.ServerStreamingClient[KeyEvent]
+
+func (c *keyRotationNotifyServiceClient) RevokeToken(ctx context.Context, in *RevokeTokenRequest, opts ...grpc.CallOption) (*RevokeTokenResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RevokeTokenResponse)
+	err := c.cc.Invoke(ctx, KeyRotationNotifyService_RevokeToken_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyRotationNotifyServiceClient) RevokeUserTokens(ctx context.Context, in *RevokeUserTokensRequest, opts ...grpc.CallOption) (*RevokeUserTokensResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RevokeUserTokensResponse)
+	err := c.cc.Invoke(ctx, KeyRotationNotifyService_RevokeUserTokens_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // KeyRotationNotifyServiceServer is the server API for KeyRotationNotifyService service.
 // All implementations must embed UnimplementedKeyRotationNotifyServiceServer
 // for forward compatibility.
 type KeyRotationNotifyServiceServer interface {
 	NotifyKeyRolled(context.Context, *NotifyKeyRolledRequest) (*NotifyKeyRolledResponse, error)
+	ListKeys(context.Context, *ListKeysRequest) (*ListKeysResponse, error)
+	RevokeKey(context.Context, *RevokeKeyRequest) (*RevokeKeyResponse, error)
+	WatchKeys(*WatchKeysRequest, grpc.ServerStreamingServer[KeyEvent]) error
+	RevokeToken(context.Context, *RevokeTokenRequest) (*RevokeTokenResponse, error)
+	RevokeUserTokens(context.Context, *RevokeUserTokensRequest) (*RevokeUserTokensResponse, error)
 	mustEmbedUnimplementedKeyRotationNotifyServiceServer()
 }
 
@@ -65,6 +139,21 @@ type UnimplementedKeyRotationNotifyServiceServer struct{}
 func (UnimplementedKeyRotationNotifyServiceServer) NotifyKeyRolled(context.Context, *NotifyKeyRolledRequest) (*NotifyKeyRolledResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method NotifyKeyRolled not implemented")
 }
+func (UnimplementedKeyRotationNotifyServiceServer) ListKeys(context.Context, *ListKeysRequest) (*ListKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListKeys not implemented")
+}
+func (UnimplementedKeyRotationNotifyServiceServer) RevokeKey(context.Context, *RevokeKeyRequest) (*RevokeKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeKey not implemented")
+}
+func (UnimplementedKeyRotationNotifyServiceServer) WatchKeys(*WatchKeysRequest, grpc.ServerStreamingServer[KeyEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchKeys not implemented")
+}
+func (UnimplementedKeyRotationNotifyServiceServer) RevokeToken(context.Context, *RevokeTokenRequest) (*RevokeTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeToken not implemented")
+}
+func (UnimplementedKeyRotationNotifyServiceServer) RevokeUserTokens(context.Context, *RevokeUserTokensRequest) (*RevokeUserTokensResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeUserTokens not implemented")
+}
 func (UnimplementedKeyRotationNotifyServiceServer) mustEmbedUnimplementedKeyRotationNotifyServiceServer() {
 }
 func (UnimplementedKeyRotationNotifyServiceServer) testEmbeddedByValue() {}
@@ -105,6 +194,89 @@ func _KeyRotationNotifyService_NotifyKeyRolled_Handler(srv interface{}, ctx cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _KeyRotationNotifyService_ListKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyRotationNotifyServiceServer).ListKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeyRotationNotifyService_ListKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyRotationNotifyServiceServer).ListKeys(ctx, req.(*ListKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeyRotationNotifyService_RevokeKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyRotationNotifyServiceServer).RevokeKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeyRotationNotifyService_RevokeKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyRotationNotifyServiceServer).RevokeKey(ctx, req.(*RevokeKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeyRotationNotifyService_WatchKeys_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchKeysRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(KeyRotationNotifyServiceServer).WatchKeys(m, &grpc.GenericServerStream[WatchKeysRequest, KeyEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type KeyRotationNotifyService_WatchKeysServer = grpc.ServerStreamingServer[KeyEvent]
+
+func _KeyRotationNotifyService_RevokeToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyRotationNotifyServiceServer).RevokeToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeyRotationNotifyService_RevokeToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyRotationNotifyServiceServer).RevokeToken(ctx, req.(*RevokeTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeyRotationNotifyService_RevokeUserTokens_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeUserTokensRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyRotationNotifyServiceServer).RevokeUserTokens(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeyRotationNotifyService_RevokeUserTokens_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyRotationNotifyServiceServer).RevokeUserTokens(ctx, req.(*RevokeUserTokensRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // KeyRotationNotifyService_ServiceDesc is the grpc.ServiceDesc for KeyRotationNotifyService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -116,7 +288,29 @@ var KeyRotationNotifyService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "NotifyKeyRolled",
 			Handler:    _KeyRotationNotifyService_NotifyKeyRolled_Handler,
 		},
+		{
+			MethodName: "ListKeys",
+			Handler:    _KeyRotationNotifyService_ListKeys_Handler,
+		},
+		{
+			MethodName: "RevokeKey",
+			Handler:    _KeyRotationNotifyService_RevokeKey_Handler,
+		},
+		{
+			MethodName: "RevokeToken",
+			Handler:    _KeyRotationNotifyService_RevokeToken_Handler,
+		},
+		{
+			MethodName: "RevokeUserTokens",
+			Handler:    _KeyRotationNotifyService_RevokeUserTokens_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchKeys",
+			Handler:       _KeyRotationNotifyService_WatchKeys_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "key_rotation.proto",
 }
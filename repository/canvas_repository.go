@@ -16,6 +16,9 @@ type CanvasRepositoryInterface interface {
 	FindCanvasByID(id string) (models.Canvas, error)
 	FindCanvasesByTeamID(teamID string) ([]models.Canvas, error)
 	UpdateCanvasTitle(id, newTitle string) error
+	// RestoreCanvas는 제목과 내용을 덮어씁니다(스냅샷 복원용).
+	// SaveCanvas와 마찬가지로 Yjs update 로그가 있으면 ErrYDocActive를 반환합니다.
+	RestoreCanvas(id, title, content string) error
 	DeleteCanvasByID(id string) error
 }
 
@@ -28,7 +31,7 @@ func NewCanvasRepository(collection *mongo.Collection) *CanvasRepository {
 }
 
 func (r *CanvasRepository) SaveCanvas(canvas models.Canvas) (string, error) {
-	canvas.CreatedAt = time.Now()
+	now := time.Now()
 
 	var filter bson.M
 	var objectID primitive.ObjectID
@@ -39,7 +42,8 @@ func (r *CanvasRepository) SaveCanvas(canvas models.Canvas) (string, error) {
 		if err != nil {
 			return "", err
 		}
-		filter = bson.M{"_id": objectID}
+		// 로그가 있는 문서는 조건에 맞지 않아 upsert가 같은 _id로 삽입을 시도하다 실패함
+		filter = withoutYDocLog(bson.M{"_id": objectID})
 	} else {
 		objectID = primitive.NewObjectID()
 		canvas.ID = objectID.Hex()
@@ -51,11 +55,18 @@ func (r *CanvasRepository) SaveCanvas(canvas models.Canvas) (string, error) {
 			"team_id":    canvas.TeamID,
 			"title":      canvas.Title,
 			"canvas":     canvas.Canvas,
-			"created_at": canvas.CreatedAt,
+			"updated_at": now,
 		},
+		// 덮어쓰기 저장이 생성 시각을 바꾸지 않도록 최초 생성 시에만 설정
+		"$setOnInsert": bson.M{"created_at": now},
+		// 로그가 있으면 쓰지 않으므로 남은 빈 로그 필드만 정리됨
+		"$unset": bson.M{"ydoc_updates": ""},
 	}
 	opts := options.Update().SetUpsert(true)
 	_, err = r.collection.UpdateOne(context.Background(), filter, update, opts)
+	if mongo.IsDuplicateKeyError(err) {
+		return "", ErrYDocActive
+	}
 	if err != nil {
 		return "", err
 	}
@@ -97,6 +108,31 @@ func (r *CanvasRepository) UpdateCanvasTitle(id, newTitle string) error {
 	return err
 }
 
+func (r *CanvasRepository) RestoreCanvas(id, title, content string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	filter := withoutYDocLog(bson.M{"_id": objectID})
+	update := bson.M{
+		"$set": bson.M{
+			"title":      title,
+			"canvas":     content,
+			"updated_at": time.Now(),
+		},
+		"$unset": bson.M{"ydoc_updates": ""},
+	}
+	result, err := r.collection.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(false))
+	if err != nil || result.MatchedCount > 0 {
+		return err
+	}
+	active, err := hasYDocLog(r.collection, objectID)
+	if err == nil && active {
+		err = ErrYDocActive
+	}
+	return err
+}
+
 func (r *CanvasRepository) DeleteCanvasByID(id string) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
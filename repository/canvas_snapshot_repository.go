@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-server/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CanvasSnapshotRepositoryInterface interface {
+	SaveSnapshot(snapshot models.CanvasSnapshot) (string, error)
+	FindSnapshotsByCanvasID(canvasID string) ([]models.CanvasSnapshot, error)
+	FindLatestAutoSnapshot(canvasID string) (models.CanvasSnapshot, error)
+	FindSnapshot(canvasID, snapshotID string) (models.CanvasSnapshot, error)
+	DeleteSnapshotsByCanvasID(canvasID string) error
+}
+
+var ErrSnapshotNotFound = errors.New("canvas snapshot not found")
+
+type CanvasSnapshotRepository struct {
+	collection      *mongo.Collection
+	stores          map[string]SnapshotContentStore
+	defaultStore    SnapshotContentStore
+	largeStore      SnapshotContentStore
+	largeStoreBytes int
+}
+
+// NewCanvasSnapshotRepository는 스냅샷 메타데이터 컬렉션과 본문 저장소를 묶습니다.
+// largeStore가 있으면 largeStoreBytes 이상인 본문은 largeStore(GridFS 등)에 저장됩니다.
+func NewCanvasSnapshotRepository(collection *mongo.Collection, defaultStore, largeStore SnapshotContentStore, largeStoreBytes int) *CanvasSnapshotRepository {
+	stores := map[string]SnapshotContentStore{defaultStore.Kind(): defaultStore}
+	if largeStore != nil {
+		stores[largeStore.Kind()] = largeStore
+	}
+	return &CanvasSnapshotRepository{
+		collection:      collection,
+		stores:          stores,
+		defaultStore:    defaultStore,
+		largeStore:      largeStore,
+		largeStoreBytes: largeStoreBytes,
+	}
+}
+
+func snapshotObjectID(id string) (primitive.ObjectID, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return objectID, ErrSnapshotNotFound
+	}
+	return objectID, nil
+}
+
+func (r *CanvasSnapshotRepository) storeFor(size int) SnapshotContentStore {
+	if r.largeStore != nil && size >= r.largeStoreBytes {
+		return r.largeStore
+	}
+	return r.defaultStore
+}
+
+func (r *CanvasSnapshotRepository) SaveSnapshot(snapshot models.CanvasSnapshot) (string, error) {
+	ctx := context.Background()
+	objectID := primitive.NewObjectID()
+	store := r.storeFor(len(snapshot.Canvas))
+
+	snapshot.ID = ""
+	snapshot.Size = len(snapshot.Canvas)
+	snapshot.Storage = store.Kind()
+	snapshot.CreatedAt = time.Now()
+
+	doc := bson.M{
+		"_id":        objectID,
+		"canvas_id":  snapshot.CanvasID,
+		"team_id":    snapshot.TeamID,
+		"name":       snapshot.Name,
+		"title":      snapshot.Title,
+		"auto":       snapshot.Auto,
+		"size":       snapshot.Size,
+		"storage":    snapshot.Storage,
+		"author_id":  snapshot.AuthorID,
+		"created_at": snapshot.CreatedAt,
+	}
+	if _, err := r.collection.InsertOne(ctx, doc); err != nil {
+		return "", err
+	}
+
+	if err := store.Put(ctx, objectID.Hex(), snapshot.Canvas); err != nil {
+		// 본문 없는 메타데이터가 목록에 남지 않도록 정리
+		r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+		return "", fmt.Errorf("failed to store snapshot content in %s: %w", store.Kind(), err)
+	}
+	return objectID.Hex(), nil
+}
+
+func (r *CanvasSnapshotRepository) FindSnapshotsByCanvasID(canvasID string) ([]models.CanvasSnapshot, error) {
+	var snapshots []models.CanvasSnapshot
+	projection := bson.M{"canvas": 0}
+	opts := options.Find().SetProjection(projection).SetSort(bson.M{"created_at": -1})
+	cursor, err := r.collection.Find(context.Background(), bson.M{"canvas_id": canvasID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(context.Background(), &snapshots); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+func (r *CanvasSnapshotRepository) FindLatestAutoSnapshot(canvasID string) (models.CanvasSnapshot, error) {
+	var snapshot models.CanvasSnapshot
+	opts := options.FindOne().SetProjection(bson.M{"canvas": 0}).SetSort(bson.M{"created_at": -1})
+	err := r.collection.FindOne(context.Background(), bson.M{"canvas_id": canvasID, "auto": true}, opts).Decode(&snapshot)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return snapshot, ErrSnapshotNotFound
+	}
+	return snapshot, err
+}
+
+// FindSnapshot은 메타데이터와 함께 저장소에서 본문까지 읽어 Canvas 필드를 채웁니다.
+func (r *CanvasSnapshotRepository) FindSnapshot(canvasID, snapshotID string) (models.CanvasSnapshot, error) {
+	var snapshot models.CanvasSnapshot
+	objectID, err := snapshotObjectID(snapshotID)
+	if err != nil {
+		return snapshot, err
+	}
+
+	filter := bson.M{"_id": objectID, "canvas_id": canvasID}
+	opts := options.FindOne().SetProjection(bson.M{"canvas": 0})
+	err = r.collection.FindOne(context.Background(), filter, opts).Decode(&snapshot)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return snapshot, ErrSnapshotNotFound
+		}
+		return snapshot, err
+	}
+
+	store, ok := r.stores[snapshot.Storage]
+	if !ok {
+		return snapshot, fmt.Errorf("unknown snapshot storage: %s", snapshot.Storage)
+	}
+	snapshot.Canvas, err = store.Get(context.Background(), snapshotID)
+	return snapshot, err
+}
+
+// DeleteSnapshotsByCanvasID는 canvas의 스냅샷 본문과 메타데이터를 모두 지웁니다.
+// 본문을 먼저 지우므로 중간에 실패해도 다시 호출하면 남은 것부터 이어서 지웁니다.
+func (r *CanvasSnapshotRepository) DeleteSnapshotsByCanvasID(canvasID string) error {
+	ctx := context.Background()
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "storage": 1})
+	cursor, err := r.collection.Find(ctx, bson.M{"canvas_id": canvasID}, opts)
+	if err != nil {
+		return err
+	}
+	var snapshots []models.CanvasSnapshot
+	if err = cursor.All(ctx, &snapshots); err != nil {
+		return err
+	}
+
+	for _, snapshot := range snapshots {
+		store, ok := r.stores[snapshot.Storage]
+		if !ok || snapshot.Storage == models.SnapshotStorageInline {
+			// inline 본문은 메타데이터 문서와 함께 지워짐
+			continue
+		}
+		if err := store.Delete(ctx, snapshot.ID); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+			return fmt.Errorf("failed to delete snapshot %s content from %s: %w", snapshot.ID, store.Kind(), err)
+		}
+	}
+	_, err = r.collection.DeleteMany(ctx, bson.M{"canvas_id": canvasID})
+	return err
+}
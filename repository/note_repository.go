@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"go-server/models"
+	"log"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,23 +13,38 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// 본문을 쓰는 메서드(SaveNote, UpdateNoteContent, RestoreNoteRevision)는 Yjs 로그가 있으면 ErrYDocActive를 반환합니다.
 type NoteRepositoryInterface interface {
-	SaveNote(note models.Note) (string, error)
+	SaveNote(note models.Note, authorID string) (string, error)
 	FindNoteByID(id string) (models.Note, error)
 	FindNotesByTeamID(teamID string) ([]models.Note, error)
 	UpdateNoteTitle(id, newTitle string) error
+	UpdateNoteContent(id, content string, expectedVersion int, authorID string) (int, error)
 	DeleteNoteByID(id string) error
+	FindNoteRevisions(noteID string) ([]models.NoteRevision, error)
+	FindNoteRevision(noteID string, version int) (models.NoteRevision, error)
+	RestoreNoteRevision(noteID string, version int, authorID string) (int, error)
 }
 
+var (
+	ErrNoteNotFound     = errors.New("note not found")
+	ErrVersionConflict  = errors.New("note version conflict")
+	ErrRevisionNotFound = errors.New("note revision not found")
+)
+
 type NoteRepository struct {
-	collection *mongo.Collection
+	collection          *mongo.Collection
+	revisionsCollection *mongo.Collection
 }
 
-func NewNoteRepository(collection *mongo.Collection) *NoteRepository {
-	return &NoteRepository{collection: collection}
+func NewNoteRepository(collection, revisionsCollection *mongo.Collection) *NoteRepository {
+	return &NoteRepository{
+		collection:          collection,
+		revisionsCollection: revisionsCollection,
+	}
 }
 
-func (r *NoteRepository) SaveNote(note models.Note) (string, error) {
+func (r *NoteRepository) SaveNote(note models.Note, authorID string) (string, error) {
 	note.CreatedAt = time.Now()
 
 	var filter bson.M
@@ -39,7 +56,8 @@ func (r *NoteRepository) SaveNote(note models.Note) (string, error) {
 		if err != nil {
 			return "", err
 		}
-		filter = bson.M{"_id": objectID}
+		// 로그가 있는 문서는 조건에 맞지 않아 upsert가 같은 _id로 삽입을 시도하다 실패함
+		filter = withoutYDocLog(bson.M{"_id": objectID})
 	} else {
 		objectID = primitive.NewObjectID()
 		note.ID = objectID.Hex()
@@ -53,12 +71,20 @@ func (r *NoteRepository) SaveNote(note models.Note) (string, error) {
 			"note":       note.Note,
 			"created_at": note.CreatedAt,
 		},
+		"$inc": bson.M{"version": 1},
+		// 로그가 있으면 쓰지 않으므로 남은 빈 로그 필드만 정리됨
+		"$unset": bson.M{"ydoc_updates": ""},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var saved models.Note
+	err = r.collection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&saved)
+	if mongo.IsDuplicateKeyError(err) {
+		return "", ErrYDocActive
 	}
-	opts := options.Update().SetUpsert(true)
-	_, err = r.collection.UpdateOne(context.Background(), filter, update, opts)
 	if err != nil {
 		return "", err
 	}
+	r.saveRevision(saved, authorID, 0)
 	return objectID.Hex(), nil
 }
 
@@ -97,11 +123,151 @@ func (r *NoteRepository) UpdateNoteTitle(id, newTitle string) error {
 	return err
 }
 
+// UpdateNoteContent는 expectedVersion이 현재 버전과 같을 때만 내용을 바꾸고 버전을 올립니다.
+// 성공하면 새 버전을, ErrVersionConflict이면 현재 버전을 반환합니다.
+// Yjs update 로그가 있으면 ErrYDocActive입니다.
+func (r *NoteRepository) UpdateNoteContent(id, content string, expectedVersion int, authorID string) (int, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		// 형식이 틀린 ID는 있을 수 없는 노트
+		return 0, ErrNoteNotFound
+	}
+
+	filter := bson.M{"_id": objectID, "version": expectedVersion}
+	if expectedVersion == 0 {
+		// version 필드가 없는 기존 문서는 0으로 취급
+		filter = bson.M{
+			"_id": objectID,
+			"$or": bson.A{
+				bson.M{"version": 0},
+				bson.M{"version": bson.M{"$exists": false}},
+			},
+		}
+	}
+	update := bson.M{
+		"$set":   bson.M{"note": content},
+		"$inc":   bson.M{"version": 1},
+		"$unset": bson.M{"ydoc_updates": ""},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var saved models.Note
+	err = r.collection.FindOneAndUpdate(context.Background(), withoutYDocLog(filter), update, opts).Decode(&saved)
+	if err == nil {
+		r.saveRevision(saved, authorID, 0)
+		return saved.Version, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, err
+	}
+
+	current, err := r.FindNoteByID(id)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, ErrNoteNotFound
+		}
+		return 0, err
+	}
+	if active, err := hasYDocLog(r.collection, objectID); err != nil || active {
+		if err == nil {
+			err = ErrYDocActive
+		}
+		return current.Version, err
+	}
+	return current.Version, ErrVersionConflict
+}
+
+// DeleteNoteByID는 노트와 그 리비전을 지웁니다.
+// 리비전을 먼저 지워, 실패해도 노트가 남아 같은 요청으로 다시 지울 수 있게 합니다.
 func (r *NoteRepository) DeleteNoteByID(id string) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
+	if _, err := r.revisionsCollection.DeleteMany(context.Background(), bson.M{"note_id": id}); err != nil {
+		return err
+	}
 	_, err = r.collection.DeleteOne(context.Background(), bson.M{"_id": objectID})
 	return err
 }
+
+// saveRevision은 저장된 노트의 현재 상태를 note_revisions에 남깁니다.
+// 본문 저장은 이미 끝났으므로 실패해도 로그만 남깁니다.
+func (r *NoteRepository) saveRevision(note models.Note, authorID string, restoredFrom int) {
+	revision := models.NoteRevision{
+		NoteID:       note.ID,
+		TeamID:       note.TeamID,
+		Version:      note.Version,
+		Title:        note.Title,
+		Note:         note.Note,
+		AuthorID:     authorID,
+		RestoredFrom: restoredFrom,
+		CreatedAt:    time.Now(),
+	}
+	if _, err := r.revisionsCollection.InsertOne(context.Background(), revision); err != nil {
+		log.Printf("Failed to save revision %d of note %s: %v\n", note.Version, note.ID, err)
+	}
+}
+
+func (r *NoteRepository) FindNoteRevisions(noteID string) ([]models.NoteRevision, error) {
+	var revisions []models.NoteRevision
+	projection := bson.M{"note": 0} // 목록에서는 본문 제외
+	opts := options.Find().SetProjection(projection).SetSort(bson.M{"version": -1})
+	cursor, err := r.revisionsCollection.Find(context.Background(), bson.M{"note_id": noteID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(context.Background(), &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (r *NoteRepository) FindNoteRevision(noteID string, version int) (models.NoteRevision, error) {
+	var revision models.NoteRevision
+	filter := bson.M{"note_id": noteID, "version": version}
+	err := r.revisionsCollection.FindOne(context.Background(), filter).Decode(&revision)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return revision, ErrRevisionNotFound
+	}
+	return revision, err
+}
+
+// RestoreNoteRevision은 지정한 리비전의 제목과 본문으로 노트를 되돌립니다.
+// 복원도 하나의 변경이므로 버전이 올라가고 새 리비전이 기록됩니다. Yjs update 로그가 있으면 ErrYDocActive입니다.
+func (r *NoteRepository) RestoreNoteRevision(noteID string, version int, authorID string) (int, error) {
+	revision, err := r.FindNoteRevision(noteID, version)
+	if err != nil {
+		return 0, err
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(noteID)
+	if err != nil {
+		return 0, ErrNoteNotFound
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"title": revision.Title,
+			"note":  revision.Note,
+		},
+		"$inc":   bson.M{"version": 1},
+		"$unset": bson.M{"ydoc_updates": ""},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var saved models.Note
+	err = r.collection.FindOneAndUpdate(context.Background(), withoutYDocLog(bson.M{"_id": objectID}), update, opts).Decode(&saved)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, err
+		}
+		active, err := hasYDocLog(r.collection, objectID)
+		if err != nil {
+			return 0, err
+		}
+		if active {
+			return 0, ErrYDocActive
+		}
+		return 0, ErrNoteNotFound
+	}
+	r.saveRevision(saved, authorID, version)
+	return saved.Version, nil
+}
@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"go-server/models"
 
@@ -13,19 +15,41 @@ import (
 )
 
 // ParticipantRepository manages participants using Redis
+//
+// Besides the participant hash, each room keeps
+//   - <key>:seen  sorted set of participant ID -> last heartbeat (unix ms)
+//   - <key>:conns hash of "<instance ID>/<connection ID>" -> participant ID
+//
+// <key>:conns reference-counts participants: one user with several tabs (or several
+// instances) stays in the room until the last of their connections leaves.
+// participantRoomsKey indexes every room so the reaper can find expired entries.
 type ParticipantRepository struct {
 	redisClient *redis.Client
+	instanceID  string
 }
 
 type ParticipantRepositoryInterface interface {
-	AddParticipant(ctx context.Context, teamID, kind string, p models.Participant) error
+	AddParticipant(ctx context.Context, teamID, kind, connID string, p models.Participant) error
+	LeaveParticipant(ctx context.Context, teamID, kind, connID string) (bool, error)
 	RemoveParticipant(ctx context.Context, teamID, kind, participantID string) error
 	GetParticipants(ctx context.Context, teamID, kind string) ([]models.Participant, error)
+	TouchParticipant(ctx context.Context, teamID, kind, participantID string) error
+	RemoveExpiredParticipants(ctx context.Context, ttl time.Duration) ([]ParticipantRoom, error)
+	RemoveOwnedParticipants(ctx context.Context) ([]ParticipantRoom, error)
 }
 
-// NewParticipantRepository creates a new ParticipantRepository
-func NewParticipantRepository(redisClient *redis.Client) *ParticipantRepository {
-	return &ParticipantRepository{redisClient: redisClient}
+// ParticipantRoom identifies a teamID:kind room whose participant list changed
+type ParticipantRoom struct {
+	TeamID string
+	Kind   string
+}
+
+const participantRoomsKey = "participants:rooms"
+
+// NewParticipantRepository creates a new ParticipantRepository.
+// instanceID marks the entries added by this server so they can be reconciled after a crash.
+func NewParticipantRepository(redisClient *redis.Client, instanceID string) *ParticipantRepository {
+	return &ParticipantRepository{redisClient: redisClient, instanceID: instanceID}
 }
 
 // validKind checks if the provided kind is valid
@@ -46,8 +70,14 @@ func generateKey(teamID, kind string) (string, error) {
 	return fmt.Sprintf("team:%s:%s:participants", teamID, kind), nil
 }
 
-// AddParticipant adds a participant to a specific team and kind
-func (pr *ParticipantRepository) AddParticipant(ctx context.Context, teamID, kind string, participant models.Participant) error {
+// connField is the <key>:conns field of a connection held by this instance
+func (pr *ParticipantRepository) connField(connID string) string {
+	return pr.instanceID + "/" + connID
+}
+
+// AddParticipant registers connection connID as participant in a specific team and kind.
+// Adding the same connection again only updates the participant data.
+func (pr *ParticipantRepository) AddParticipant(ctx context.Context, teamID, kind, connID string, participant models.Participant) error {
 	key, err := generateKey(teamID, kind)
 	if err != nil {
 		return err
@@ -58,7 +88,14 @@ func (pr *ParticipantRepository) AddParticipant(ctx context.Context, teamID, kin
 		return fmt.Errorf("failed to marshal participant: %w", err)
 	}
 
-	if err := pr.redisClient.HSet(ctx, key, participant.ID, participantData).Err(); err != nil {
+	_, err = pr.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, participant.ID, participantData)
+		pipe.ZAdd(ctx, key+":seen", &redis.Z{Score: float64(time.Now().UnixMilli()), Member: participant.ID})
+		pipe.HSet(ctx, key+":conns", pr.connField(connID), participant.ID)
+		pipe.SAdd(ctx, participantRoomsKey, key)
+		return nil
+	})
+	if err != nil {
 		return fmt.Errorf("failed to HSet participant in Redis: %w", err)
 	}
 
@@ -66,15 +103,34 @@ func (pr *ParticipantRepository) AddParticipant(ctx context.Context, teamID, kin
 	return nil
 }
 
-// RemoveParticipant removes a participant from a specific team and kind
+// LeaveParticipant releases connection connID from a specific team and kind. The participant
+// is removed, and true returned, only when it was their last connection in the room.
+func (pr *ParticipantRepository) LeaveParticipant(ctx context.Context, teamID, kind, connID string) (bool, error) {
+	key, err := generateKey(teamID, kind)
+	if err != nil {
+		return false, err
+	}
+
+	removed, err := leaveScript.Run(ctx, pr.redisClient, roomScriptKeys(key), key, pr.connField(connID)).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to release participant connection in Redis: %w", err)
+	}
+
+	if removed > 0 {
+		log.Printf("Last connection left, removed participant from team %s, kind %s\n", teamID, kind)
+	}
+	return removed > 0, nil
+}
+
+// RemoveParticipant removes a participant, with all of their connections, from a specific team and kind
 func (pr *ParticipantRepository) RemoveParticipant(ctx context.Context, teamID, kind, participantID string) error {
 	key, err := generateKey(teamID, kind)
 	if err != nil {
 		return err
 	}
 
-	if err := pr.redisClient.HDel(ctx, key, participantID).Err(); err != nil {
-		return fmt.Errorf("failed to HDel participant from Redis: %w", err)
+	if err := removeScript.Run(ctx, pr.redisClient, roomScriptKeys(key), key, participantID).Err(); err != nil {
+		return fmt.Errorf("failed to remove participant from Redis: %w", err)
 	}
 
 	log.Printf("Removed participant %s from team %s, kind %s\n", participantID, teamID, kind)
@@ -106,3 +162,182 @@ func (pr *ParticipantRepository) GetParticipants(ctx context.Context, teamID, ki
 	log.Printf("Retrieved %d participants from team %s, kind %s\n", len(participants), teamID, kind)
 	return participants, nil
 }
+
+// TouchParticipant records a heartbeat for a participant that is still in the room
+func (pr *ParticipantRepository) TouchParticipant(ctx context.Context, teamID, kind, participantID string) error {
+	key, err := generateKey(teamID, kind)
+	if err != nil {
+		return err
+	}
+
+	// XX: never resurrect a participant that already left
+	err = pr.redisClient.ZAddXX(ctx, key+":seen", &redis.Z{Score: float64(time.Now().UnixMilli()), Member: participantID}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to ZAdd participant heartbeat in Redis: %w", err)
+	}
+	return nil
+}
+
+// removeParticipantsLua removes the given participants and their connections from a room
+// atomically and drops the room from the index once it is empty.
+// KEYS: see roomScriptKeys. ARGV[1]: room index member.
+const removeParticipantsLua = `
+local function removeParticipants(ids)
+	if #ids > 0 then
+		redis.call('HDEL', KEYS[1], unpack(ids))
+		redis.call('ZREM', KEYS[2], unpack(ids))
+		local removed = {}
+		for _, id in ipairs(ids) do
+			removed[id] = true
+		end
+		local conns = redis.call('HGETALL', KEYS[3])
+		for i = 1, #conns, 2 do
+			if removed[conns[i + 1]] then
+				redis.call('HDEL', KEYS[3], conns[i])
+			end
+		end
+	end
+	if redis.call('HLEN', KEYS[1]) == 0 then
+		redis.call('SREM', KEYS[4], ARGV[1])
+	end
+	return #ids
+end
+`
+
+// hasConnectionsLua reports whether any connection is still registered for participant id.
+const hasConnectionsLua = `
+local function hasConnections(id)
+	for _, other in ipairs(redis.call('HVALS', KEYS[3])) do
+		if other == id then
+			return true
+		end
+	end
+	return false
+end
+`
+
+// roomScriptKeys returns the KEYS shared by the room scripts
+func roomScriptKeys(key string) []string {
+	return []string{key, key + ":seen", key + ":conns", participantRoomsKey}
+}
+
+// leaveScript releases connection field ARGV[2] and removes its participant if no other
+// connection holds them.
+var leaveScript = redis.NewScript(removeParticipantsLua + hasConnectionsLua + `
+local id = redis.call('HGET', KEYS[3], ARGV[2])
+if not id then
+	return 0
+end
+redis.call('HDEL', KEYS[3], ARGV[2])
+if hasConnections(id) then
+	return 0
+end
+return removeParticipants({id})
+`)
+
+// removeScript removes participant ARGV[2] regardless of their connections.
+var removeScript = redis.NewScript(removeParticipantsLua + `
+return removeParticipants({ARGV[2]})
+`)
+
+// reapScript removes participants whose last heartbeat is before ARGV[2] (unix ms), and
+// participants with no heartbeat at all (entries added before heartbeats existed).
+var reapScript = redis.NewScript(removeParticipantsLua + `
+local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[2])
+for _, id in ipairs(redis.call('HKEYS', KEYS[1])) do
+	if not redis.call('ZSCORE', KEYS[2], id) then
+		table.insert(expired, id)
+	end
+end
+return removeParticipants(expired)
+`)
+
+// removeOwnedScript releases the connections held by instance ARGV[2] and removes the
+// participants left without any connection.
+var removeOwnedScript = redis.NewScript(removeParticipantsLua + hasConnectionsLua + `
+local prefix = ARGV[2] .. '/'
+local conns = redis.call('HGETALL', KEYS[3])
+local released = {}
+for i = 1, #conns, 2 do
+	if string.sub(conns[i], 1, #prefix) == prefix then
+		redis.call('HDEL', KEYS[3], conns[i])
+		table.insert(released, conns[i + 1])
+	end
+end
+local orphaned, checked = {}, {}
+for _, id in ipairs(released) do
+	if not checked[id] and not hasConnections(id) then
+		table.insert(orphaned, id)
+	end
+	checked[id] = true
+end
+return removeParticipants(orphaned)
+`)
+
+// RemoveExpiredParticipants removes participants without a heartbeat for ttl and
+// returns the rooms that changed
+func (pr *ParticipantRepository) RemoveExpiredParticipants(ctx context.Context, ttl time.Duration) ([]ParticipantRoom, error) {
+	cutoff := time.Now().Add(-ttl).UnixMilli()
+	return pr.removeFromRooms(ctx, reapScript, cutoff)
+}
+
+// RemoveOwnedParticipants releases the connections registered by this instance and removes
+// participants that have no other connection. Call it on startup: a fresh process holds no
+// connections, so anything it owns was left behind by a crash.
+func (pr *ParticipantRepository) RemoveOwnedParticipants(ctx context.Context) ([]ParticipantRoom, error) {
+	if pr.instanceID == "" {
+		return nil, nil
+	}
+	// Index participant hashes created before the room index existed
+	iter := pr.redisClient.Scan(ctx, 0, "team:*:participants", 100).Iterator()
+	for iter.Next(ctx) {
+		if err := pr.redisClient.SAdd(ctx, participantRoomsKey, iter.Val()).Err(); err != nil {
+			return nil, fmt.Errorf("failed to SAdd participant room in Redis: %w", err)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan participant rooms in Redis: %w", err)
+	}
+	return pr.removeFromRooms(ctx, removeOwnedScript, pr.instanceID)
+}
+
+func (pr *ParticipantRepository) removeFromRooms(ctx context.Context, script *redis.Script, arg interface{}) ([]ParticipantRoom, error) {
+	keys, err := pr.redisClient.SMembers(ctx, participantRoomsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to SMembers participant rooms from Redis: %w", err)
+	}
+
+	var changed []ParticipantRoom
+	for _, key := range keys {
+		room, ok := parseParticipantKey(key)
+		if !ok {
+			continue
+		}
+		removed, err := script.Run(ctx, pr.redisClient, roomScriptKeys(key), key, arg).Int()
+		if err != nil {
+			return changed, fmt.Errorf("failed to remove participants from %s: %w", key, err)
+		}
+		if removed > 0 {
+			log.Printf("Removed %d stale participants from team %s, kind %s\n", removed, room.TeamID, room.Kind)
+			changed = append(changed, room)
+		}
+	}
+	return changed, nil
+}
+
+// parseParticipantKey is the inverse of generateKey
+func parseParticipantKey(key string) (ParticipantRoom, bool) {
+	rest, ok := strings.CutPrefix(key, "team:")
+	if !ok {
+		return ParticipantRoom{}, false
+	}
+	rest, ok = strings.CutSuffix(rest, ":participants")
+	if !ok {
+		return ParticipantRoom{}, false
+	}
+	i := strings.LastIndex(rest, ":")
+	if i < 0 || !validKind(rest[i+1:]) {
+		return ParticipantRoom{}, false
+	}
+	return ParticipantRoom{TeamID: rest[:i], Kind: rest[i+1:]}, true
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"go-server/models"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SnapshotContentStore는 스냅샷 본문(직렬화된 캔버스)을 저장하는 곳입니다.
+// 메타데이터는 항상 스냅샷 컬렉션에 있고, 본문만 저장소별로 나뉩니다.
+type SnapshotContentStore interface {
+	Kind() string
+	Put(ctx context.Context, snapshotID, content string) error
+	Get(ctx context.Context, snapshotID string) (string, error)
+	Delete(ctx context.Context, snapshotID string) error
+}
+
+// InlineSnapshotContentStore는 본문을 스냅샷 문서의 canvas 필드에 함께 저장합니다.
+type InlineSnapshotContentStore struct {
+	collection *mongo.Collection
+}
+
+func NewInlineSnapshotContentStore(collection *mongo.Collection) *InlineSnapshotContentStore {
+	return &InlineSnapshotContentStore{collection: collection}
+}
+
+func (s *InlineSnapshotContentStore) Kind() string {
+	return models.SnapshotStorageInline
+}
+
+func (s *InlineSnapshotContentStore) Put(ctx context.Context, snapshotID, content string) error {
+	objectID, err := snapshotObjectID(snapshotID)
+	if err != nil {
+		return err
+	}
+	_, err = s.collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": bson.M{"canvas": content}})
+	return err
+}
+
+func (s *InlineSnapshotContentStore) Get(ctx context.Context, snapshotID string) (string, error) {
+	objectID, err := snapshotObjectID(snapshotID)
+	if err != nil {
+		return "", err
+	}
+	var doc struct {
+		Canvas string `bson:"canvas"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"canvas": 1})
+	err = s.collection.FindOne(ctx, bson.M{"_id": objectID}, opts).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", ErrSnapshotNotFound
+	}
+	return doc.Canvas, err
+}
+
+func (s *InlineSnapshotContentStore) Delete(ctx context.Context, snapshotID string) error {
+	objectID, err := snapshotObjectID(snapshotID)
+	if err != nil {
+		return err
+	}
+	_, err = s.collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$unset": bson.M{"canvas": ""}})
+	return err
+}
+
+// GridFSSnapshotContentStore는 큰 캔버스 본문을 GridFS 버킷에 저장합니다. 파일 ID는 스냅샷 ID입니다.
+type GridFSSnapshotContentStore struct {
+	bucket *gridfs.Bucket
+}
+
+func NewGridFSSnapshotContentStore(bucket *gridfs.Bucket) *GridFSSnapshotContentStore {
+	return &GridFSSnapshotContentStore{bucket: bucket}
+}
+
+func (s *GridFSSnapshotContentStore) Kind() string {
+	return models.SnapshotStorageGridFS
+}
+
+func (s *GridFSSnapshotContentStore) Put(ctx context.Context, snapshotID, content string) error {
+	return s.bucket.UploadFromStreamWithID(snapshotID, snapshotID+".json", strings.NewReader(content))
+}
+
+func (s *GridFSSnapshotContentStore) Get(ctx context.Context, snapshotID string) (string, error) {
+	var buf bytes.Buffer
+	if _, err := s.bucket.DownloadToStream(snapshotID, &buf); err != nil {
+		if errors.Is(err, gridfs.ErrFileNotFound) {
+			return "", ErrSnapshotNotFound
+		}
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (s *GridFSSnapshotContentStore) Delete(ctx context.Context, snapshotID string) error {
+	return s.bucket.DeleteContext(ctx, snapshotID)
+}
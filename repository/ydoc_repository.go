@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// YDocRepositoryInterface는 note/canvas 문서에 Yjs update 로그를 저장합니다.
+// 로그를 순서대로 모두 적용하면 문서의 현재 상태가 됩니다. 서버는 Yjs 문서를 해석하지 않으므로
+// 공동 편집 내용은 본문(note, canvas)에 반영되지 않고, REST 조회·리비전·스냅샷에도 들어가지 않습니다.
+// 그래서 REST로 본문을 덮어쓰는 저장소 메서드는 로그가 비어 있을 때만 쓰고(아니면 ErrYDocActive),
+// 쓸 때 ydoc_updates를 지웁니다. 공동 편집 내용을 REST 쓰기가 조용히 버리지 않게 하기 위함입니다.
+type YDocRepositoryInterface interface {
+	LoadUpdates(id string) ([][]byte, error)
+	// AppendUpdate는 저장된 update 수가 expected일 때만 update를 덧붙입니다.
+	// 그 사이 로그가 바뀌었으면(REST 저장으로 지워졌거나 문서가 삭제되었으면) false를 반환합니다.
+	AppendUpdate(id string, update []byte, expected int) (bool, error)
+	// ReplaceUpdates는 로그 전체를 updates로 바꿉니다(압축용).
+	// 저장된 update 수가 expected와 다르면(다른 인스턴스가 그 사이 추가했으면) 아무 것도 하지 않고 false를 반환합니다.
+	ReplaceUpdates(id string, updates [][]byte, expected int) (bool, error)
+}
+
+var (
+	ErrYDocNotFound = errors.New("document not found")
+	// ErrYDocActive: Yjs 로그가 있는 문서의 본문을 REST로 덮어쓰려 함. 컨트롤러는 409로 응답합니다.
+	ErrYDocActive = errors.New("document has collaborative edits")
+)
+
+// withoutYDocLog는 filter에 Yjs 로그가 비어 있다는 조건을 더합니다.
+func withoutYDocLog(filter bson.M) bson.M {
+	filter["ydoc_updates.0"] = bson.M{"$exists": false}
+	return filter
+}
+
+// hasYDocLog는 REST 쓰기가 아무 문서도 바꾸지 못했을 때 그 이유가 Yjs 로그인지 확인합니다.
+func hasYDocLog(collection *mongo.Collection, objectID primitive.ObjectID) (bool, error) {
+	n, err := collection.CountDocuments(context.Background(), bson.M{"_id": objectID, "ydoc_updates.0": bson.M{"$exists": true}})
+	return n > 0, err
+}
+
+// YDocRepository는 notes, canvases 컬렉션 어디에나 쓸 수 있도록 ydoc_updates 필드만 다룹니다.
+type YDocRepository struct {
+	collection *mongo.Collection
+}
+
+func NewYDocRepository(collection *mongo.Collection) *YDocRepository {
+	return &YDocRepository{collection: collection}
+}
+
+type yDocUpdates struct {
+	Updates [][]byte `bson:"ydoc_updates"`
+}
+
+func (r *YDocRepository) LoadUpdates(id string) ([][]byte, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	var doc yDocUpdates
+	opts := options.FindOne().SetProjection(bson.M{"ydoc_updates": 1})
+	err = r.collection.FindOne(context.Background(), bson.M{"_id": objectID}, opts).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrYDocNotFound
+	}
+	return doc.Updates, err
+}
+
+func (r *YDocRepository) AppendUpdate(id string, update []byte, expected int) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+	result, err := r.collection.UpdateOne(context.Background(),
+		logLengthFilter(objectID, expected),
+		bson.M{"$push": bson.M{"ydoc_updates": update}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func (r *YDocRepository) ReplaceUpdates(id string, updates [][]byte, expected int) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+	result, err := r.collection.UpdateOne(context.Background(), logLengthFilter(objectID, expected), bson.M{"$set": bson.M{"ydoc_updates": updates}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// logLengthFilter는 update 로그 길이가 expected인 문서를 찾습니다.
+func logLengthFilter(objectID primitive.ObjectID, expected int) bson.M {
+	if expected == 0 {
+		// 한 번도 저장한 적 없거나 REST 저장으로 지워졌으면 필드 자체가 없음
+		return bson.M{"_id": objectID, "ydoc_updates": bson.M{"$in": bson.A{nil, bson.A{}}}}
+	}
+	return bson.M{"_id": objectID, "ydoc_updates": bson.M{"$size": expected}}
+}
@@ -8,13 +8,24 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func CanvasRoutes(app *fiber.App, canvasController *controllers.CanvasController, store *utils.PublicKeyStore) {
+func CanvasRoutes(app *fiber.App, canvasController *controllers.CanvasController, store *utils.PublicKeyStore, revocations *utils.TokenRevocationStore, membership middleware.TeamMembershipProvider, policy middleware.PermissionPolicy) {
 
-	canvasGroup := app.Group("/canvas", middleware.JWTParser(store))
+	byBody := middleware.RequireTeamMember(membership, canvasController.ResolveNewCanvasTeam)
+	byTeam := middleware.RequireTeamMember(membership, middleware.TeamFromParam("teamId"))
+	byCanvas := middleware.RequireTeamMember(membership, canvasController.ResolveCanvasTeam)
 
-	canvasGroup.Post("/", canvasController.CreateCanvas)
-	canvasGroup.Get("/:id", canvasController.GetCanvasByID)
-	canvasGroup.Get("/team/:teamId", canvasController.GetCanvasesByTeamID)
-	canvasGroup.Put("/:id/title", canvasController.UpdateCanvasTitle)
-	canvasGroup.Delete("/:id", canvasController.DeleteCanvasByID)
+	canRead := middleware.RequirePermission(policy, middleware.PermissionRead)
+	canWrite := middleware.RequirePermission(policy, middleware.PermissionWrite)
+	canDelete := middleware.RequirePermission(policy, middleware.PermissionDelete)
+
+	canvasGroup := app.Group("/canvas", middleware.JWTParser(store, revocations))
+
+	canvasGroup.Post("/", canWrite, byBody, canvasController.CreateCanvas)
+	canvasGroup.Get("/:id", canRead, byCanvas, canvasController.GetCanvasByID)
+	canvasGroup.Get("/team/:teamId", canRead, byTeam, canvasController.GetCanvasesByTeamID)
+	canvasGroup.Put("/:id/title", canWrite, byCanvas, canvasController.UpdateCanvasTitle)
+	canvasGroup.Delete("/:id", canDelete, byCanvas, canvasController.DeleteCanvasByID)
+	canvasGroup.Post("/:id/snapshots", canWrite, byCanvas, canvasController.CreateCanvasSnapshot)
+	canvasGroup.Get("/:id/snapshots", canRead, byCanvas, canvasController.GetCanvasSnapshots)
+	canvasGroup.Post("/:id/snapshots/:snapshotId/restore", canWrite, byCanvas, canvasController.RestoreCanvasSnapshot)
 }
@@ -8,12 +8,24 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func NoteRoutes(app *fiber.App, noteController *controllers.NoteController, store *utils.PublicKeyStore) {
+func NoteRoutes(app *fiber.App, noteController *controllers.NoteController, store *utils.PublicKeyStore, revocations *utils.TokenRevocationStore, membership middleware.TeamMembershipProvider, policy middleware.PermissionPolicy) {
 
-	noteGroup := app.Group("/note", middleware.JWTParser(store))
-	noteGroup.Post("/", noteController.CreateNote)
-	noteGroup.Get("/:id", noteController.GetNoteByID)
-	noteGroup.Get("/team/:teamId", noteController.GetNotesByTeamID)
-	noteGroup.Put("/:id/title", noteController.UpdateNoteTitle)
-	noteGroup.Delete("/:id", noteController.DeleteNoteByID)
+	byBody := middleware.RequireTeamMember(membership, noteController.ResolveNewNoteTeam)
+	byTeam := middleware.RequireTeamMember(membership, middleware.TeamFromParam("teamId"))
+	byNote := middleware.RequireTeamMember(membership, noteController.ResolveNoteTeam)
+
+	canRead := middleware.RequirePermission(policy, middleware.PermissionRead)
+	canWrite := middleware.RequirePermission(policy, middleware.PermissionWrite)
+	canDelete := middleware.RequirePermission(policy, middleware.PermissionDelete)
+
+	noteGroup := app.Group("/note", middleware.JWTParser(store, revocations))
+	noteGroup.Post("/", canWrite, byBody, noteController.CreateNote)
+	noteGroup.Get("/:id", canRead, byNote, noteController.GetNoteByID)
+	noteGroup.Get("/team/:teamId", canRead, byTeam, noteController.GetNotesByTeamID)
+	noteGroup.Put("/:id/title", canWrite, byNote, noteController.UpdateNoteTitle)
+	noteGroup.Put("/:id/content", canWrite, byNote, noteController.UpdateNoteContent)
+	noteGroup.Delete("/:id", canDelete, byNote, noteController.DeleteNoteByID)
+	noteGroup.Get("/:id/revisions", canRead, byNote, noteController.GetNoteRevisions)
+	noteGroup.Get("/:id/revisions/:rev", canRead, byNote, noteController.GetNoteRevision)
+	noteGroup.Post("/:id/revisions/:rev/restore", canWrite, byNote, noteController.RestoreNoteRevision)
 }
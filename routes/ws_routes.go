@@ -2,12 +2,24 @@ package routes
 
 import (
 	"go-server/controllers"
+	middleware "go-server/middlewares"
+	"go-server/utils"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/websocket/v2"
 )
 
-func WebSocketRoutes(app *fiber.App, participanstController *controllers.ParticipantsController, audioController *controllers.AudioSocketController) {
-	app.Get("/ws", websocket.New(participanstController.HandleWebSocket))
-	app.Get("/webrtc/audio", websocket.New(audioController.HandleWebRTC))
+func WebSocketRoutes(app *fiber.App, participanstController *controllers.ParticipantsController, mediaController *controllers.MediaSocketController, signalingController *controllers.WebSocketController, store *utils.PublicKeyStore, revocations *utils.TokenRevocationStore, sessions *middleware.SessionRegistry) {
+	wsConfig := websocket.Config{
+		Subprotocols: []string{middleware.WebSocketTokenProtocol},
+	}
+
+	// 프로토콜 스키마는 토큰 없이 받을 수 있음 (프론트엔드 타입 생성용)
+	app.Get("/ws/schema.json", participanstController.ProtocolSchema)
+	app.Get("/ws", middleware.WebSocketJWT(store, revocations), websocket.New(sessions.Track(participanstController.HandleWebSocket), wsConfig))
+	app.Get("/webrtc/ice-servers", middleware.JWTParser(store, revocations), mediaController.ICEServers)
+	app.Get("/webrtc/media", middleware.WebSocketJWT(store, revocations), websocket.New(sessions.Track(mediaController.HandleWebRTC), wsConfig))
+	// 오디오 전용이던 때의 경로. 같은 SFU로 갑니다
+	app.Get("/webrtc/audio", middleware.WebSocketJWT(store, revocations), websocket.New(sessions.Track(mediaController.HandleWebRTC), wsConfig))
+	app.Get("/signaling", middleware.WebSocketJWT(store, revocations), websocket.New(sessions.Track(signalingController.HandleYWebRTC), wsConfig))
 }
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"go-server/controllers"
+	middleware "go-server/middlewares"
+	"go-server/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+)
+
+// YDocRoutes: y-websocket 엔드포인트. room 이름은 "note/<id>" 또는 "canvas/<id>"입니다.
+func YDocRoutes(app *fiber.App, ydocController *controllers.YDocController, store *utils.PublicKeyStore, revocations *utils.TokenRevocationStore, sessions *middleware.SessionRegistry, membership middleware.TeamMembershipProvider, policy middleware.PermissionPolicy) {
+	wsConfig := websocket.Config{
+		Subprotocols: []string{middleware.WebSocketTokenProtocol},
+	}
+
+	byDocument := middleware.RequireTeamMember(membership, ydocController.ResolveDocumentTeam)
+	canRead := middleware.RequirePermission(policy, middleware.PermissionRead)
+
+	app.Get("/yjs/:kind/:id", middleware.WebSocketJWT(store, revocations), canRead, byDocument, websocket.New(sessions.Track(ydocController.HandleYWebSocket), wsConfig))
+}
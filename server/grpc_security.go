@@ -0,0 +1,371 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// HMAC 호출자 인증에 쓰는 메타데이터 키
+const (
+	CallerIDMetadataKey  = "x-caller-id"
+	TimestampMetadataKey = "x-timestamp"
+	NonceMetadataKey     = "x-nonce"
+	SignatureMetadataKey = "x-signature"
+)
+
+// hmacMaxClockSkew보다 오래되었거나 앞선 서명은 재사용으로 보고 거부합니다.
+// 그 안에서는 한 번 쓴 서명을 기억해 두었다가 다시 오면 거부합니다.
+const hmacMaxClockSkew = 5 * time.Minute
+
+// SecurityConfig는 키 교체 gRPC 서버의 전송 보안과 호출자 인증 설정입니다.
+type SecurityConfig struct {
+	// 서버 인증서와 키 (PEM 파일)
+	CertFile string
+	KeyFile  string
+	// 설정하면 이 CA로 서명된 클라이언트 인증서를 요구합니다 (mTLS)
+	ClientCAFile string
+	// 설정하면 모든 호출에 HMAC 서명을 요구합니다. 서버 인증서(TLS) 없이는 쓸 수 없습니다.
+	SharedSecret string
+	// 허용할 호출자 (클라이언트 인증서의 CN/SAN 또는 HMAC x-caller-id). 비어 있으면 인증된 호출자는 모두 허용
+	AllowedCallers []string
+	// 인증 없이 띄우는 것을 명시적으로 허용 (로컬 개발용)
+	AllowInsecure bool
+}
+
+// ServerOptions는 설정에 맞는 TLS 자격 증명과 호출자 인증 인터셉터를 만듭니다.
+// mTLS도 공유 비밀도 없으면 AllowInsecure가 아닌 한 에러를 반환합니다.
+// 공유 비밀은 평문 구간에서 가로채 재전송할 수 있으므로 TLS 없이 설정하면 에러입니다.
+func ServerOptions(config SecurityConfig) ([]grpc.ServerOption, error) {
+	var opts []grpc.ServerOption
+	mutualTLS := false
+
+	if config.SharedSecret != "" && config.CertFile == "" {
+		return nil, errors.New("gRPC shared secret requires TLS: configure a server certificate and key")
+	}
+
+	if config.CertFile != "" || config.KeyFile != "" {
+		tlsConfig, err := loadServerTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		mutualTLS = tlsConfig.ClientAuth == tls.RequireAndVerifyClientCert
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	} else if config.ClientCAFile != "" {
+		return nil, errors.New("client CA requires a server certificate and key")
+	}
+
+	if !mutualTLS && config.SharedSecret == "" {
+		if !config.AllowInsecure {
+			return nil, errors.New("gRPC server has no client authentication: configure mTLS or a shared secret")
+		}
+		log.Println("WARNING: gRPC key rotation server is running without client authentication")
+		return opts, nil
+	}
+
+	auth := newCallerAuthenticator(config)
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(auth.unaryInterceptor),
+		grpc.ChainStreamInterceptor(auth.streamInterceptor),
+	)
+	return opts, nil
+}
+
+func loadServerTLSConfig(config SecurityConfig) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if config.ClientCAFile != "" {
+		caPEM, err := os.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in %s", config.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+type callerAuthenticator struct {
+	secret  []byte
+	allowed map[string]bool
+	replays *replayCache
+}
+
+func newCallerAuthenticator(config SecurityConfig) *callerAuthenticator {
+	auth := &callerAuthenticator{replays: newReplayCache(2 * hmacMaxClockSkew)}
+	if config.SharedSecret != "" {
+		auth.secret = []byte(config.SharedSecret)
+	}
+	if len(config.AllowedCallers) > 0 {
+		auth.allowed = make(map[string]bool, len(config.AllowedCallers))
+		for _, caller := range config.AllowedCallers {
+			auth.allowed[caller] = true
+		}
+	}
+	return auth
+}
+
+func (a *callerAuthenticator) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return nil, status.Error(codes.Internal, "unexpected request type")
+	}
+	digest, err := RequestDigest(msg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to digest request")
+	}
+	if err := a.authenticate(ctx, info.FullMethod, digest); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// 스트리밍 호출은 헤더가 첫 메시지보다 먼저 나가므로 빈 본문의 digest로 서명합니다.
+// 유일한 스트리밍 메서드인 WatchKeys의 요청은 필드가 없어 직렬화하면 빈 바이트입니다.
+func (a *callerAuthenticator) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.authenticate(ss.Context(), info.FullMethod, emptyDigest); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// authenticate는 클라이언트 인증서와 HMAC 서명에서 호출자를 확인하고 허용 목록과 비교합니다.
+func (a *callerAuthenticator) authenticate(ctx context.Context, fullMethod string, digest []byte) error {
+	identities := peerCertificateIdentities(ctx)
+
+	if a.secret != nil {
+		callerID, err := a.verifySignature(ctx, fullMethod, digest)
+		if err != nil {
+			log.Printf("Rejected gRPC call to %s: %v", fullMethod, err)
+			return status.Error(codes.Unauthenticated, err.Error())
+		}
+		identities = append(identities, callerID)
+	}
+
+	if len(identities) == 0 {
+		return status.Error(codes.Unauthenticated, "no client credentials")
+	}
+	if a.allowed == nil {
+		return nil
+	}
+	for _, identity := range identities {
+		if a.allowed[identity] {
+			return nil
+		}
+	}
+
+	log.Printf("Rejected gRPC call to %s from %v: caller not allowed", fullMethod, identities)
+	return status.Error(codes.PermissionDenied, "caller not allowed")
+}
+
+func (a *callerAuthenticator) verifySignature(ctx context.Context, fullMethod string, digest []byte) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	callerID := firstMetadata(md, CallerIDMetadataKey)
+	timestamp := firstMetadata(md, TimestampMetadataKey)
+	nonce := firstMetadata(md, NonceMetadataKey)
+	signature := firstMetadata(md, SignatureMetadataKey)
+	if callerID == "" || timestamp == "" || nonce == "" || signature == "" {
+		return "", errors.New("missing HMAC signature")
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", errors.New("invalid timestamp")
+	}
+	skew := time.Since(time.Unix(unix, 0))
+	if skew > hmacMaxClockSkew || skew < -hmacMaxClockSkew {
+		return "", errors.New("timestamp out of range")
+	}
+
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return "", errors.New("invalid signature encoding")
+	}
+	if !hmac.Equal(got, SignCall(a.secret, callerID, fullMethod, timestamp, nonce, digest)) {
+		return "", errors.New("invalid signature")
+	}
+	// 서명 문자열은 hex 대소문자만 바꿔도 같은 MAC이 되므로 서명에 들어간 호출자와 nonce로 기억함
+	if !a.replays.add(callerID + "\n" + nonce) {
+		return "", errors.New("replayed nonce")
+	}
+	return callerID, nil
+}
+
+// SignCall은 HMAC-SHA256(secret, callerID + "\n" + fullMethod + "\n" + timestamp + "\n" + nonce + "\n" + hex(digest))을 계산합니다.
+// digest는 RequestDigest로 구한 요청 본문의 SHA-256입니다.
+// Spring 쪽 클라이언트도 같은 방식으로 x-signature를 hex로 보내야 합니다.
+func SignCall(secret []byte, callerID, fullMethod, timestamp, nonce string, digest []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(callerID + "\n" + fullMethod + "\n" + timestamp + "\n" + nonce + "\n" + hex.EncodeToString(digest)))
+	return mac.Sum(nil)
+}
+
+// emptyDigest는 빈 본문의 SHA-256입니다.
+var emptyDigest = func() []byte {
+	sum := sha256.Sum256(nil)
+	return sum[:]
+}()
+
+// RequestDigest는 요청 메시지를 필드 번호 순서로 직렬화한 바이트의 SHA-256입니다.
+// protobuf-java의 toByteArray()도 같은 바이트를 만듭니다.
+func RequestDigest(msg proto.Message) ([]byte, error) {
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	return sum[:], nil
+}
+
+// replayCache는 유효 기간 안에 이미 받은 호출자별 nonce를 기억합니다.
+type replayCache struct {
+	mu     sync.Mutex
+	ttl    time.Duration
+	seen   map[string]time.Time
+	pruned time.Time
+}
+
+func newReplayCache(ttl time.Duration) *replayCache {
+	return &replayCache{ttl: ttl, seen: make(map[string]time.Time)}
+}
+
+// add는 처음 보는 키면 기억하고 true를, 이미 본 키면 false를 반환합니다.
+func (c *replayCache) add(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.pruned) > time.Minute {
+		for key, expiresAt := range c.seen {
+			if now.After(expiresAt) {
+				delete(c.seen, key)
+			}
+		}
+		c.pruned = now
+	}
+	if expiresAt, ok := c.seen[key]; ok && now.Before(expiresAt) {
+		return false
+	}
+	c.seen[key] = now.Add(c.ttl)
+	return true
+}
+
+func firstMetadata(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// peerCertificateIdentities는 검증된 클라이언트 인증서의 CN과 SAN(DNS, URI)을 돌려줍니다.
+func peerCertificateIdentities(ctx context.Context) []string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 {
+		return nil
+	}
+
+	cert := tlsInfo.State.VerifiedChains[0][0]
+	var identities []string
+	if cert.Subject.CommonName != "" {
+		identities = append(identities, cert.Subject.CommonName)
+	}
+	identities = append(identities, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	return identities
+}
+
+// HMACCredentials는 Go 클라이언트가 호출마다 HMAC 메타데이터를 붙이게 하는 PerRPCCredentials입니다.
+// 요청 본문의 digest가 필요하므로 DialOptions로 인터셉터와 함께 등록합니다.
+type HMACCredentials struct {
+	CallerID string
+	Secret   []byte
+}
+
+type requestDigestKey struct{}
+
+// DialOptions는 HMACCredentials와 요청 digest를 계산하는 인터셉터를 함께 돌려줍니다.
+func (c HMACCredentials) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithPerRPCCredentials(c),
+		grpc.WithChainUnaryInterceptor(c.unaryInterceptor),
+	}
+}
+
+func (c HMACCredentials) unaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return errors.New("request is not a protobuf message")
+	}
+	digest, err := RequestDigest(msg)
+	if err != nil {
+		return err
+	}
+	return invoker(context.WithValue(ctx, requestDigestKey{}, digest), method, req, reply, cc, opts...)
+}
+
+func (c HMACCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	info, ok := credentials.RequestInfoFromContext(ctx)
+	if !ok {
+		return nil, errors.New("no request info in context")
+	}
+	// 인터셉터를 거치지 않은 호출(스트리밍)은 빈 본문으로 서명
+	digest, ok := ctx.Value(requestDigestKey{}).([]byte)
+	if !ok {
+		digest = emptyDigest
+	}
+
+	nonceBytes := make([]byte, 16)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return nil, err
+	}
+	nonce := hex.EncodeToString(nonceBytes)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	return map[string]string{
+		CallerIDMetadataKey:  c.CallerID,
+		TimestampMetadataKey: timestamp,
+		NonceMetadataKey:     nonce,
+		SignatureMetadataKey: hex.EncodeToString(SignCall(c.Secret, c.CallerID, info.Method, timestamp, nonce, digest)),
+	}, nil
+}
+
+func (c HMACCredentials) RequireTransportSecurity() bool {
+	return true
+}
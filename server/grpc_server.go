@@ -3,6 +3,7 @@ package server
 import (
 	"log"
 	"net"
+	"time"
 
 	"google.golang.org/grpc"
 
@@ -10,14 +11,31 @@ import (
 	"go-server/utils"
 )
 
-func RunGRPCServer(store *utils.PublicKeyStore) error {
-	lis, err := net.Listen("tcp", ":50051") // gRPC 서버 포트
+// NewGRPCServer는 보안 설정을 적용한 gRPC 서버에 키 교체 서비스를 등록합니다.
+// zone은 오프셋 없이 오는 시각을 해석할 시간대입니다(nil이면 UTC).
+func NewGRPCServer(store *utils.PublicKeyStore, revocations *utils.TokenRevocationStore, config SecurityConfig, zone *time.Location) (*grpc.Server, error) {
+	opts, err := ServerOptions(config)
+	if err != nil {
+		return nil, err
+	}
+
+	s := grpc.NewServer(opts...)
+	rotation := NewKeyRotationNotifyServer(store, revocations)
+	rotation.SetTimeZone(zone)
+	pb.RegisterKeyRotationNotifyServiceServer(s, rotation)
+	return s, nil
+}
+
+func RunGRPCServer(store *utils.PublicKeyStore, revocations *utils.TokenRevocationStore, config SecurityConfig, zone *time.Location) error {
+	s, err := NewGRPCServer(store, revocations, config, zone)
 	if err != nil {
 		return err
 	}
 
-	s := grpc.NewServer()
-	pb.RegisterKeyRotationNotifyServiceServer(s, NewKeyRotationNotifyServer(store))
+	lis, err := net.Listen("tcp", ":50051") // gRPC 서버 포트
+	if err != nil {
+		return err
+	}
 
 	log.Println("Starting gRPC server on port 50051...")
 	return s.Serve(lis)
@@ -4,31 +4,50 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strconv"
+	"time"
 
 	pb "go-server/pkg/keyrotation"
 	"go-server/utils"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type KeyRotationNotifyServer struct {
 	pb.UnimplementedKeyRotationNotifyServiceServer
-	store *utils.PublicKeyStore
+	store       *utils.PublicKeyStore
+	revocations *utils.TokenRevocationStore
+	// 오프셋 없는 시각(Java LocalDateTime)을 해석할 시간대
+	zone *time.Location
 }
 
-func NewKeyRotationNotifyServer(store *utils.PublicKeyStore) *KeyRotationNotifyServer {
+func NewKeyRotationNotifyServer(store *utils.PublicKeyStore, revocations *utils.TokenRevocationStore) *KeyRotationNotifyServer {
 	return &KeyRotationNotifyServer{
-		store: store,
+		store:       store,
+		revocations: revocations,
+		zone:        time.UTC,
 	}
 }
 
+// SetTimeZone: 오프셋 없이 오는 시각을 zone의 벽시계 시각으로 해석합니다. 기본은 UTC입니다.
+// 인증 서비스가 LocalDateTime을 보낸다면 그 서비스의 시간대를 설정해야 합니다.
+func (s *KeyRotationNotifyServer) SetTimeZone(zone *time.Location) {
+	if zone == nil {
+		zone = time.UTC
+	}
+	s.zone = zone
+}
+
 func (s *KeyRotationNotifyServer) NotifyKeyRolled(ctx context.Context, req *pb.NotifyKeyRolledRequest) (*pb.NotifyKeyRolledResponse, error) {
-	log.Printf("Received key rotation notification: prevKid=%s, currKid=%s, rolledAt=%s",
-		req.GetPreviousKid(), req.GetCurrentKid(), req.GetRolledAt())
+	log.Printf("Received key rotation notification: prevKid=%s, currKid=%s, algorithm=%s, rolledAt=%s",
+		req.GetPreviousKid(), req.GetCurrentKid(), req.GetAlgorithm(), req.GetRolledAt())
 
 	if req.GetCurrentPublicKeyPem() == "" {
 		return nil, fmt.Errorf("no public key pem provided")
 	}
 
-	err := s.store.AddOrUpdateKey(ctx, req.GetCurrentKid(), req.GetCurrentPublicKeyPem())
+	err := s.store.AddOrUpdateKey(ctx, req.GetCurrentKid(), req.GetCurrentPublicKeyPem(), req.GetAlgorithm(), s.parseRolledAt(req.GetRolledAt()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to add/update key in store: %v", err)
 	}
@@ -37,3 +56,212 @@ func (s *KeyRotationNotifyServer) NotifyKeyRolled(ctx context.Context, req *pb.N
 		Message: "Public key updated successfully.",
 	}, nil
 }
+
+// ListKeys는 현재 키와 유예 기간 중인 이전 키를 돌려줍니다.
+func (s *KeyRotationNotifyServer) ListKeys(ctx context.Context, req *pb.ListKeysRequest) (*pb.ListKeysResponse, error) {
+	keys, err := s.store.ListKeys(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list keys: %v", err)
+	}
+
+	resp := &pb.ListKeysResponse{}
+	for _, key := range keys {
+		resp.Keys = append(resp.Keys, keyInfoToProto(key))
+	}
+	return resp, nil
+}
+
+// RevokeKey는 유출된 kid를 즉시 제거합니다. 이 kid로 서명된 토큰은 바로 거부됩니다.
+func (s *KeyRotationNotifyServer) RevokeKey(ctx context.Context, req *pb.RevokeKeyRequest) (*pb.RevokeKeyResponse, error) {
+	if req.GetKid() == "" {
+		return nil, status.Error(codes.InvalidArgument, "kid is required")
+	}
+
+	log.Printf("Revoking public key: kid=%s, reason=%s", req.GetKid(), req.GetReason())
+	if err := s.store.RevokeKey(ctx, req.GetKid()); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to revoke key: %v", err)
+	}
+
+	return &pb.RevokeKeyResponse{
+		Message: "Public key revoked.",
+	}, nil
+}
+
+// RevokeToken은 로그아웃 등으로 jti 토큰 하나를 만료 전에 무효화합니다.
+func (s *KeyRotationNotifyServer) RevokeToken(ctx context.Context, req *pb.RevokeTokenRequest) (*pb.RevokeTokenResponse, error) {
+	if s.revocations == nil {
+		return nil, status.Error(codes.Unimplemented, "token revocation is not enabled")
+	}
+	if req.GetJti() == "" {
+		return nil, status.Error(codes.InvalidArgument, "jti is required")
+	}
+
+	var expiresAt time.Time
+	if req.GetExpiresAt() != "" {
+		t, ok := s.parseTimestamp(req.GetExpiresAt())
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid expiresAt %q", req.GetExpiresAt())
+		}
+		expiresAt = t
+	}
+
+	log.Printf("Revoking access token: jti=%s", req.GetJti())
+	if err := s.revocations.RevokeToken(ctx, req.GetJti(), expiresAt); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to revoke token: %v", err)
+	}
+
+	return &pb.RevokeTokenResponse{
+		Message: "Access token revoked.",
+	}, nil
+}
+
+// RevokeUserTokens는 userId에게 revokedBefore(기본값: 지금) 이전에 발급된 토큰을 모두 무효화하고 열린 세션을 끊습니다.
+func (s *KeyRotationNotifyServer) RevokeUserTokens(ctx context.Context, req *pb.RevokeUserTokensRequest) (*pb.RevokeUserTokensResponse, error) {
+	if s.revocations == nil {
+		return nil, status.Error(codes.Unimplemented, "token revocation is not enabled")
+	}
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "userId is required")
+	}
+
+	revokedBefore := time.Now()
+	if req.GetRevokedBefore() != "" {
+		t, ok := s.parseTimestamp(req.GetRevokedBefore())
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid revokedBefore %q", req.GetRevokedBefore())
+		}
+		revokedBefore = t
+	}
+
+	log.Printf("Revoking access tokens of user %s issued before %s", req.GetUserId(), revokedBefore.Format(time.RFC3339))
+	if err := s.revocations.RevokeUser(ctx, req.GetUserId(), revokedBefore); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to revoke user tokens: %v", err)
+	}
+
+	return &pb.RevokeUserTokensResponse{
+		Message: "User tokens revoked.",
+	}, nil
+}
+
+// watchResyncInterval마다 변경 알림이 없어도 상태를 다시 읽어 TTL로 만료된 키의 DELETE를 보냅니다.
+const watchResyncInterval = 30 * time.Second
+
+type watchedKey struct {
+	info utils.KeyInfo
+	pem  string
+}
+
+// WatchKeys는 먼저 현재 키 전체를 PUT으로 보내고, 이후 바뀐 키는 PUT, 사라진 키는 DELETE로 보냅니다.
+func (s *KeyRotationNotifyServer) WatchKeys(req *pb.WatchKeysRequest, stream pb.KeyRotationNotifyService_WatchKeysServer) error {
+	ctx := stream.Context()
+	changes, stop := s.store.Watch()
+	defer stop()
+
+	ticker := time.NewTicker(watchResyncInterval)
+	defer ticker.Stop()
+
+	sent := make(map[string]watchedKey)
+	for {
+		if err := s.sendKeyChanges(ctx, stream, sent); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changes:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *KeyRotationNotifyServer) sendKeyChanges(ctx context.Context, stream pb.KeyRotationNotifyService_WatchKeysServer, sent map[string]watchedKey) error {
+	keys, err := s.store.ListKeys(ctx)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to list keys: %v", err)
+	}
+
+	current := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		current[key.Kid] = true
+
+		pubKey, err := s.store.GetKey(ctx, key.Kid)
+		if err != nil {
+			// 목록을 읽은 뒤 만료되거나 폐기된 키는 다음 번에 DELETE로 처리
+			log.Printf("Skipping key kid=%s in watch: %v", key.Kid, err)
+			continue
+		}
+		pemBytes, err := utils.MarshalPublicKey(pubKey)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to marshal key: %v", err)
+		}
+
+		prev, ok := sent[key.Kid]
+		if ok && prev.pem == string(pemBytes) && prev.info.ExpiresAt.IsZero() == key.ExpiresAt.IsZero() {
+			continue
+		}
+
+		if err := stream.Send(&pb.KeyEvent{
+			Type:         pb.KeyEventType_KEY_EVENT_TYPE_PUT,
+			Key:          keyInfoToProto(key),
+			PublicKeyPem: string(pemBytes),
+		}); err != nil {
+			return err
+		}
+		sent[key.Kid] = watchedKey{info: key, pem: string(pemBytes)}
+	}
+
+	for kid, prev := range sent {
+		if current[kid] {
+			continue
+		}
+		if err := stream.Send(&pb.KeyEvent{
+			Type: pb.KeyEventType_KEY_EVENT_TYPE_DELETE,
+			Key:  keyInfoToProto(prev.info),
+		}); err != nil {
+			return err
+		}
+		delete(sent, kid)
+	}
+	return nil
+}
+
+func keyInfoToProto(key utils.KeyInfo) *pb.KeyInfo {
+	info := &pb.KeyInfo{
+		Kid:       key.Kid,
+		Algorithm: key.Algorithm,
+		AddedAt:   key.AddedAt.UTC().Format(time.RFC3339Nano),
+	}
+	if !key.ExpiresAt.IsZero() {
+		info.ExpiresAt = key.ExpiresAt.UTC().Format(time.RFC3339Nano)
+	}
+	return info
+}
+
+// parseRolledAt은 Spring 쪽 rolledAt을 해석합니다. 해석할 수 없으면 지금 시각을 교체 시각으로 봅니다.
+func (s *KeyRotationNotifyServer) parseRolledAt(rolledAt string) time.Time {
+	if rolledAt == "" {
+		return time.Now()
+	}
+	if t, ok := s.parseTimestamp(rolledAt); ok {
+		return t
+	}
+
+	log.Printf("Unrecognized rolledAt %q, using current time", rolledAt)
+	return time.Now()
+}
+
+// parseTimestamp는 Spring에서 오는 시각(ISO-8601 Instant/OffsetDateTime, LocalDateTime 또는 epoch millis)을 해석합니다.
+// 오프셋이 없는 LocalDateTime은 s.zone의 시각으로 봅니다.
+func (s *KeyRotationNotifyServer) parseTimestamp(value string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04:05.999999999", value, s.zone); err == nil {
+		return t, true
+	}
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(millis), true
+	}
+	return time.Time{}, false
+}
@@ -3,27 +3,39 @@ package tests
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
 
+	"go-server/configs"
 	"go-server/controllers"
 	"go-server/models"
 	"go-server/repository"
 )
 
 func setupCanvasApp() *fiber.App {
+	return setupCanvasAppWithSnapshots(NewMockCanvasSnapshotRepository(), configs.CanvasSnapshotConfig{})
+}
+
+func setupCanvasAppWithSnapshots(snapshotRepo repository.CanvasSnapshotRepositoryInterface, snapshotConfig configs.CanvasSnapshotConfig) *fiber.App {
+	return setupCanvasAppWithRepos(NewMockCanvasRepository(), snapshotRepo, snapshotConfig)
+}
+
+func setupCanvasAppWithRepos(repo repository.CanvasRepositoryInterface, snapshotRepo repository.CanvasSnapshotRepositoryInterface, snapshotConfig configs.CanvasSnapshotConfig) *fiber.App {
 	app := fiber.New()
-	var repo repository.CanvasRepositoryInterface = NewMockCanvasRepository()
-	canvasController := controllers.NewCanvasController(repo)
+	canvasController := controllers.NewCanvasController(repo, snapshotRepo, snapshotConfig)
 
 	app.Post("/canvas", canvasController.CreateCanvas)
 	app.Get("/canvas/:id", canvasController.GetCanvasByID)
 	app.Get("/canvases/team/:teamId", canvasController.GetCanvasesByTeamID)
 	app.Put("/canvas/:id/title", canvasController.UpdateCanvasTitle)
 	app.Delete("/canvas/:id", canvasController.DeleteCanvasByID)
+	app.Post("/canvas/:id/snapshots", canvasController.CreateCanvasSnapshot)
+	app.Get("/canvas/:id/snapshots", canvasController.GetCanvasSnapshots)
+	app.Post("/canvas/:id/snapshots/:snapshotId/restore", canvasController.RestoreCanvasSnapshot)
 
 	return app
 }
@@ -193,3 +205,186 @@ func TestDeleteCanvasByID_Failure(t *testing.T) {
 	json.NewDecoder(resp.Body).Decode(&respBody)
 	assert.Equal(t, "Failed to delete canvas", respBody["error"])
 }
+
+func TestDeleteCanvasByID_DeletesSnapshots(t *testing.T) {
+	snapshotRepo := NewMockCanvasSnapshotRepository()
+	app := setupCanvasAppWithSnapshots(snapshotRepo, configs.CanvasSnapshotConfig{})
+	id := saveTestCanvas(t, app, models.Canvas{Title: "Diagram", TeamID: "team1", Canvas: "content"})
+	other := saveTestCanvas(t, app, models.Canvas{Title: "Other", TeamID: "team1", Canvas: "content"})
+
+	body, _ := json.Marshal(map[string]string{"name": "checkpoint"})
+	req := httptest.NewRequest("POST", "/canvas/"+id+"/snapshots", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	_, _ = app.Test(req)
+	snapshots, _ := snapshotRepo.FindSnapshotsByCanvasID(id)
+	assert.NotEmpty(t, snapshots)
+
+	resp, err := app.Test(httptest.NewRequest("DELETE", "/canvas/"+id, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	snapshots, _ = snapshotRepo.FindSnapshotsByCanvasID(id)
+	assert.Empty(t, snapshots)
+	// 다른 canvas의 스냅샷은 남음
+	snapshots, _ = snapshotRepo.FindSnapshotsByCanvasID(other)
+	assert.NotEmpty(t, snapshots)
+}
+
+func TestDeleteCanvasByID_KeepsCanvasWhenSnapshotDeleteFails(t *testing.T) {
+	snapshotRepo := NewMockCanvasSnapshotRepository()
+	app := setupCanvasAppWithSnapshots(snapshotRepo, configs.CanvasSnapshotConfig{})
+	id := saveTestCanvas(t, app, models.Canvas{Title: "Diagram", TeamID: "team1", Canvas: "content"})
+
+	snapshotRepo.SetDeleteError(errors.New("gridfs unavailable"))
+	resp, err := app.Test(httptest.NewRequest("DELETE", "/canvas/"+id, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+
+	// 캔버스가 남아 있으므로 같은 요청을 다시 보내면 지워짐
+	getResp, _ := app.Test(httptest.NewRequest("GET", "/canvas/"+id, nil))
+	assert.Equal(t, fiber.StatusOK, getResp.StatusCode)
+
+	snapshotRepo.SetDeleteError(nil)
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/canvas/"+id, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+func saveTestCanvas(t *testing.T, app *fiber.App, canvas models.Canvas) string {
+	body, _ := json.Marshal(canvas)
+	req := httptest.NewRequest("POST", "/canvas", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+
+	var respBody map[string]string
+	_ = json.NewDecoder(resp.Body).Decode(&respBody)
+	return respBody["id"]
+}
+
+func TestCreateCanvas_AutoSnapshotBySizeDelta(t *testing.T) {
+	snapshotRepo := NewMockCanvasSnapshotRepository()
+	app := setupCanvasAppWithSnapshots(snapshotRepo, configs.CanvasSnapshotConfig{SizeDelta: 10})
+
+	id := saveTestCanvas(t, app, models.Canvas{Title: "Diagram", TeamID: "team1", Canvas: "abc"})
+	// 크기 차이가 작으면 새 자동 스냅샷 없음
+	saveTestCanvas(t, app, models.Canvas{ID: id, Title: "Diagram", TeamID: "team1", Canvas: "abcd"})
+	// 크기 차이가 SizeDelta 이상이면 자동 스냅샷
+	saveTestCanvas(t, app, models.Canvas{ID: id, Title: "Diagram", TeamID: "team1", Canvas: "abcdefghijklmnop"})
+
+	snapshots, _ := snapshotRepo.FindSnapshotsByCanvasID(id)
+	assert.Len(t, snapshots, 2)
+	for _, snapshot := range snapshots {
+		assert.True(t, snapshot.Auto)
+	}
+}
+
+func TestCreateCanvasSnapshot_AndList(t *testing.T) {
+	app := setupCanvasApp()
+	id := saveTestCanvas(t, app, models.Canvas{Title: "Diagram", TeamID: "team1", Canvas: "content"})
+
+	body, _ := json.Marshal(map[string]string{"name": "before refactor diagram"})
+	req := httptest.NewRequest("POST", "/canvas/"+id+"/snapshots", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	listResp, err := app.Test(httptest.NewRequest("GET", "/canvas/"+id+"/snapshots", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, listResp.StatusCode)
+
+	var snapshots []models.CanvasSnapshot
+	_ = json.NewDecoder(listResp.Body).Decode(&snapshots)
+
+	var names []string
+	for _, snapshot := range snapshots {
+		names = append(names, snapshot.Name)
+		assert.Empty(t, snapshot.Canvas)
+	}
+	assert.Contains(t, names, "before refactor diagram")
+}
+
+func TestCreateCanvasSnapshot_MissingName(t *testing.T) {
+	app := setupCanvasApp()
+	id := saveTestCanvas(t, app, models.Canvas{Title: "Diagram", TeamID: "team1", Canvas: "content"})
+
+	req := httptest.NewRequest("POST", "/canvas/"+id+"/snapshots", bytes.NewReader([]byte("{}")))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestRestoreCanvasSnapshot(t *testing.T) {
+	snapshotRepo := NewMockCanvasSnapshotRepository()
+	app := setupCanvasAppWithSnapshots(snapshotRepo, configs.CanvasSnapshotConfig{})
+	id := saveTestCanvas(t, app, models.Canvas{Title: "Diagram", TeamID: "team1", Canvas: "old"})
+
+	body, _ := json.Marshal(map[string]string{"name": "checkpoint"})
+	req := httptest.NewRequest("POST", "/canvas/"+id+"/snapshots", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	var created map[string]string
+	_ = json.NewDecoder(resp.Body).Decode(&created)
+
+	saveTestCanvas(t, app, models.Canvas{ID: id, Title: "Diagram", TeamID: "team1", Canvas: "new"})
+
+	restoreResp, err := app.Test(httptest.NewRequest("POST", "/canvas/"+id+"/snapshots/"+created["id"]+"/restore", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, restoreResp.StatusCode)
+
+	getResp, _ := app.Test(httptest.NewRequest("GET", "/canvas/"+id, nil))
+	var canvas models.Canvas
+	_ = json.NewDecoder(getResp.Body).Decode(&canvas)
+	assert.Equal(t, "old", canvas.Canvas)
+
+	// 복원 전 상태가 자동 스냅샷으로 남아 있어야 함
+	latest, err := snapshotRepo.FindLatestAutoSnapshot(id)
+	assert.NoError(t, err)
+	assert.Equal(t, "before restore of checkpoint", latest.Name)
+}
+
+func TestRestoreCanvasSnapshot_NotFound(t *testing.T) {
+	app := setupCanvasApp()
+	id := saveTestCanvas(t, app, models.Canvas{Title: "Diagram", TeamID: "team1", Canvas: "content"})
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/canvas/"+id+"/snapshots/missing/restore", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func TestCanvasContentWrites_ConflictWithYDocLog(t *testing.T) {
+	repo := NewMockCanvasRepository()
+	docs := NewMockYDocRepository()
+	repo.SetYDoc(docs)
+	app := setupCanvasAppWithRepos(repo, NewMockCanvasSnapshotRepository(), configs.CanvasSnapshotConfig{})
+	id := saveTestCanvas(t, app, models.Canvas{Title: "Diagram", TeamID: "team1", Canvas: "old"})
+
+	body, _ := json.Marshal(map[string]string{"name": "checkpoint"})
+	req := httptest.NewRequest("POST", "/canvas/"+id+"/snapshots", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	var created map[string]string
+	_ = json.NewDecoder(resp.Body).Decode(&created)
+
+	// 공동 편집으로 Yjs 로그가 쌓인 캔버스
+	_, err := docs.AppendUpdate(id, []byte{1}, 0)
+	assert.NoError(t, err)
+
+	overwrite, _ := json.Marshal(models.Canvas{ID: id, Title: "Diagram", TeamID: "team1", Canvas: "rest"})
+	saveReq := httptest.NewRequest("POST", "/canvas", bytes.NewReader(overwrite))
+	saveReq.Header.Set("Content-Type", "application/json")
+	saveResp, err := app.Test(saveReq)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusConflict, saveResp.StatusCode)
+
+	restoreResp, err := app.Test(httptest.NewRequest("POST", "/canvas/"+id+"/snapshots/"+created["id"]+"/restore", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusConflict, restoreResp.StatusCode)
+
+	canvas, _ := repo.FindCanvasByID(id)
+	assert.Equal(t, "old", canvas.Canvas)
+	assert.Equal(t, [][]byte{{1}}, docs.Updates(id))
+}
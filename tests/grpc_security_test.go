@@ -0,0 +1,308 @@
+package tests
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	pb "go-server/pkg/keyrotation"
+	"go-server/server"
+	"go-server/utils"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// testPKI는 메모리에서 만든 CA와 서버 인증서입니다. 서버 쪽은 파일 경로로 넘깁니다.
+type testPKI struct {
+	caCert   *x509.Certificate
+	caKey    *ecdsa.PrivateKey
+	caPool   *x509.CertPool
+	caFile   string
+	certFile string
+	keyFile  string
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	pki := &testPKI{caCert: caCert, caKey: caKey, caPool: x509.NewCertPool()}
+	pki.caPool.AddCert(caCert)
+
+	dir := t.TempDir()
+	pki.caFile = filepath.Join(dir, "ca.pem")
+	writePEM(t, pki.caFile, "CERTIFICATE", caDER)
+
+	serverCert := pki.issue(t, "localhost", x509.ExtKeyUsageServerAuth)
+	pki.certFile = filepath.Join(dir, "server.pem")
+	pki.keyFile = filepath.Join(dir, "server-key.pem")
+	writePEM(t, pki.certFile, "CERTIFICATE", serverCert.Certificate[0])
+	keyDER, _ := x509.MarshalECPrivateKey(serverCert.PrivateKey.(*ecdsa.PrivateKey))
+	writePEM(t, pki.keyFile, "EC PRIVATE KEY", keyDER)
+
+	return pki
+}
+
+func (pki *testPKI) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, pki.caCert, &key.PublicKey, pki.caKey)
+	if err != nil {
+		t.Fatalf("failed to issue certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+// dialSecuredKeyRotation은 config로 만든 서버를 bufconn 위에 띄우고 dialOpts로 연결합니다.
+func dialSecuredKeyRotation(t *testing.T, config server.SecurityConfig, dialOpts ...grpc.DialOption) pb.KeyRotationNotifyServiceClient {
+	t.Helper()
+
+	store, _, _ := newRetentionStore(t, utils.DefaultKeyRetention())
+	s, err := server.NewGRPCServer(store, nil, config, nil)
+	if err != nil {
+		t.Fatalf("failed to create gRPC server: %v", err)
+	}
+	lis := bufconn.Listen(1024 * 1024)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	dialOpts = append(dialOpts, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}))
+	conn, err := grpc.NewClient("passthrough:///localhost", dialOpts...)
+	if err != nil {
+		t.Fatalf("failed to dial bufnet: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewKeyRotationNotifyServiceClient(conn)
+}
+
+func (pki *testPKI) clientCreds(t *testing.T, commonName string) grpc.DialOption {
+	tlsConfig := &tls.Config{RootCAs: pki.caPool, ServerName: "localhost"}
+	if commonName != "" {
+		tlsConfig.Certificates = []tls.Certificate{pki.issue(t, commonName, x509.ExtKeyUsageClientAuth)}
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+}
+
+func (pki *testPKI) mTLSConfig(allowed ...string) server.SecurityConfig {
+	return server.SecurityConfig{
+		CertFile:       pki.certFile,
+		KeyFile:        pki.keyFile,
+		ClientCAFile:   pki.caFile,
+		AllowedCallers: allowed,
+	}
+}
+
+func TestGRPCSecurity_MutualTLSAllowedCaller(t *testing.T) {
+	pki := newTestPKI(t)
+	client := dialSecuredKeyRotation(t, pki.mTLSConfig("spring-backend"), pki.clientCreds(t, "spring-backend"))
+
+	_, err := client.NotifyKeyRolled(context.Background(), &pb.NotifyKeyRolledRequest{CurrentKid: "kid1", CurrentPublicKeyPem: newRSAPublicKeyPEM(t)})
+	assert.NoError(t, err)
+}
+
+func TestGRPCSecurity_MutualTLSCallerNotInAllowlist(t *testing.T) {
+	pki := newTestPKI(t)
+	client := dialSecuredKeyRotation(t, pki.mTLSConfig("spring-backend"), pki.clientCreds(t, "intruder"))
+
+	_, err := client.NotifyKeyRolled(context.Background(), &pb.NotifyKeyRolledRequest{CurrentKid: "kid1", CurrentPublicKeyPem: newRSAPublicKeyPEM(t)})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestGRPCSecurity_MutualTLSRequiresClientCertificate(t *testing.T) {
+	pki := newTestPKI(t)
+	client := dialSecuredKeyRotation(t, pki.mTLSConfig(), pki.clientCreds(t, ""))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err := client.ListKeys(ctx, &pb.ListKeysRequest{})
+	assert.Error(t, err)
+}
+
+// hmacConfig는 서버 인증서(TLS)와 공유 비밀을 함께 쓰는 설정입니다.
+func (pki *testPKI) hmacConfig(allowed ...string) server.SecurityConfig {
+	return server.SecurityConfig{
+		CertFile:       pki.certFile,
+		KeyFile:        pki.keyFile,
+		SharedSecret:   "secret",
+		AllowedCallers: allowed,
+	}
+}
+
+func (pki *testPKI) hmacDialOptions(t *testing.T, callerID, secret string) []grpc.DialOption {
+	creds := server.HMACCredentials{CallerID: callerID, Secret: []byte(secret)}
+	return append(creds.DialOptions(), pki.clientCreds(t, ""))
+}
+
+// signedContext는 req에 대한 HMAC 메타데이터를 직접 붙입니다.
+func signedContext(t *testing.T, fullMethod string, timestamp time.Time, nonce string, req proto.Message) context.Context {
+	t.Helper()
+	digest, err := server.RequestDigest(req)
+	assert.NoError(t, err)
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	signature := server.SignCall([]byte("secret"), "spring-backend", fullMethod, unix, nonce, digest)
+	return metadata.AppendToOutgoingContext(context.Background(),
+		server.CallerIDMetadataKey, "spring-backend",
+		server.TimestampMetadataKey, unix,
+		server.NonceMetadataKey, nonce,
+		server.SignatureMetadataKey, hex.EncodeToString(signature),
+	)
+}
+
+func TestGRPCSecurity_HMAC(t *testing.T) {
+	pki := newTestPKI(t)
+	config := pki.hmacConfig("spring-backend")
+	ctx := context.Background()
+
+	valid := dialSecuredKeyRotation(t, config, pki.hmacDialOptions(t, "spring-backend", "secret")...)
+	_, err := valid.ListKeys(ctx, &pb.ListKeysRequest{})
+	assert.NoError(t, err)
+	// 같은 요청을 다시 보내도 nonce가 달라 통과
+	_, err = valid.ListKeys(ctx, &pb.ListKeysRequest{})
+	assert.NoError(t, err)
+	_, err = valid.NotifyKeyRolled(ctx, &pb.NotifyKeyRolledRequest{CurrentKid: "kid1", CurrentPublicKeyPem: newRSAPublicKeyPEM(t)})
+	assert.NoError(t, err)
+
+	wrongSecret := dialSecuredKeyRotation(t, config, pki.hmacDialOptions(t, "spring-backend", "guess")...)
+	_, err = wrongSecret.ListKeys(ctx, &pb.ListKeysRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	notAllowed := dialSecuredKeyRotation(t, config, pki.hmacDialOptions(t, "other-service", "secret")...)
+	_, err = notAllowed.ListKeys(ctx, &pb.ListKeysRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestGRPCSecurity_HMACRejectsMissingAndStaleSignature(t *testing.T) {
+	pki := newTestPKI(t)
+	client := dialSecuredKeyRotation(t, pki.hmacConfig(), pki.clientCreds(t, ""))
+
+	_, err := client.ListKeys(context.Background(), &pb.ListKeysRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	req := &pb.ListKeysRequest{}
+	_, err = client.ListKeys(signedContext(t, pb.KeyRotationNotifyService_ListKeys_FullMethodName, time.Now().Add(-time.Hour), "nonce-1", req), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestGRPCSecurity_HMACRejectsReplay(t *testing.T) {
+	pki := newTestPKI(t)
+	client := dialSecuredKeyRotation(t, pki.hmacConfig(), pki.clientCreds(t, ""))
+
+	req := &pb.ListKeysRequest{}
+	ctx := signedContext(t, pb.KeyRotationNotifyService_ListKeys_FullMethodName, time.Now(), "nonce-1", req)
+	_, err := client.ListKeys(ctx, req)
+	assert.NoError(t, err)
+
+	// 가로챈 메타데이터를 그대로 다시 보내면 거부
+	_, err = client.ListKeys(ctx, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// 서명의 hex 대소문자만 바꿔 다시 보내도 거부
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(server.SignatureMetadataKey, strings.ToUpper(md.Get(server.SignatureMetadataKey)[0]))
+	_, err = client.ListKeys(metadata.NewOutgoingContext(context.Background(), md), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestGRPCSecurity_HMACCoversRequestBody(t *testing.T) {
+	pki := newTestPKI(t)
+	client := dialSecuredKeyRotation(t, pki.hmacConfig(), pki.clientCreds(t, ""))
+
+	// 서명한 본문과 다른 본문을 보내면 거부
+	signed := &pb.RevokeKeyRequest{Kid: "kid1"}
+	_, err := client.RevokeKey(signedContext(t, pb.KeyRotationNotifyService_RevokeKey_FullMethodName, time.Now(), "nonce-1", signed), &pb.RevokeKeyRequest{Kid: "kid2"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// 서명한 본문 그대로면 인증은 통과(키가 없어서 NotFound)
+	_, err = client.RevokeKey(signedContext(t, pb.KeyRotationNotifyService_RevokeKey_FullMethodName, time.Now(), "nonce-2", signed), signed)
+	assert.NotEqual(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestGRPCSecurity_StreamingRequiresAuthentication(t *testing.T) {
+	pki := newTestPKI(t)
+	client := dialSecuredKeyRotation(t, pki.hmacConfig(), pki.clientCreds(t, ""))
+
+	stream, err := client.WatchKeys(context.Background(), &pb.WatchKeysRequest{})
+	if assert.NoError(t, err) {
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	}
+}
+
+func TestGRPCSecurity_RefusesUnauthenticatedServer(t *testing.T) {
+	_, err := server.ServerOptions(server.SecurityConfig{})
+	assert.Error(t, err)
+
+	_, err = server.ServerOptions(server.SecurityConfig{AllowInsecure: true})
+	assert.NoError(t, err)
+}
+
+func TestGRPCSecurity_SharedSecretRequiresTLS(t *testing.T) {
+	_, err := server.ServerOptions(server.SecurityConfig{SharedSecret: "secret"})
+	assert.Error(t, err)
+
+	pki := newTestPKI(t)
+	_, err = server.ServerOptions(pki.hmacConfig())
+	assert.NoError(t, err)
+
+	// 평문 연결에는 HMAC 자격 증명을 붙일 수 없음
+	creds := server.HMACCredentials{CallerID: "spring-backend", Secret: []byte("secret")}
+	_, err = grpc.NewClient("passthrough:///localhost", append(creds.DialOptions(), grpc.WithTransportCredentials(insecure.NewCredentials()))...)
+	assert.Error(t, err)
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go-server/configs"
+	"go-server/controllers"
+	middleware "go-server/middlewares"
+	"go-server/models"
+	"go-server/routes"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pion/logging"
+	"github.com/pion/turn/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+const testTURNSecret = "turn-shared-secret"
+
+// setupICEApp: ice 설정을 쓰는 오디오 컨트롤러 서버. app과 "ws://host"를 반환합니다.
+func setupICEApp(t *testing.T, ice configs.ICEConfig) (*testKeyStore, *fiber.App, string) {
+	t.Helper()
+	keys := newTestKeyStore(t)
+	membership := middleware.ClaimsTeamMembership{}
+	mediaController := controllers.NewMediaSocketController(membership)
+	mediaController.SetICE(ice)
+
+	app := fiber.New()
+	routes.WebSocketRoutes(app, controllers.NewParticipantsController(NewMockParticipantRepository(), nil, membership), mediaController, controllers.NewWebSocketController(nil, membership), keys.store, nil, middleware.NewSessionRegistry())
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go app.Listener(ln)
+	t.Cleanup(func() { _ = app.Shutdown() })
+	return keys, app, "ws://" + ln.Addr().String()
+}
+
+func TestICEServers_EphemeralTURNCredentials(t *testing.T) {
+	keys, app, _ := setupICEApp(t, configs.ICEConfig{
+		Servers: []configs.ICEServerConfig{
+			{URLs: []string{"stun:stun.example.com:3478"}},
+			{URLs: []string{"turn:turn.example.com:3478", "turns:turn.example.com:5349"}, Ephemeral: true},
+		},
+		TURNSecret:    testTURNSecret,
+		CredentialTTL: 10 * time.Minute,
+	})
+
+	req := httptest.NewRequest("GET", "/webrtc/ice-servers", nil)
+	req.Header.Set("Authorization", "Bearer "+keys.sign(t, middleware.CustomClaims{UserID: "user-a"}))
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "no-store", resp.Header.Get(fiber.HeaderCacheControl))
+
+	var body models.ICEServersResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, int64(600), body.TTL)
+	if !assert.Len(t, body.ICEServers, 2) {
+		return
+	}
+	assert.Equal(t, models.ICEServer{URLs: []string{"stun:stun.example.com:3478"}}, body.ICEServers[0])
+
+	// username은 "<만료 시각>:<사용자 ID>"이고, TURN 서버가 같은 secret으로 검증할 수 있음
+	turnServer := body.ICEServers[1]
+	expiry, userID, _ := strings.Cut(turnServer.Username, ":")
+	assert.Equal(t, "user-a", userID)
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Now().Add(10*time.Minute).Unix(), expiresAt, 5)
+
+	auth := turn.LongTermTURNRESTAuthHandler(testTURNSecret, logging.NewDefaultLoggerFactory().NewLogger("turn"))
+	key, ok := auth(turnServer.Username, "example.com", &net.UDPAddr{})
+	assert.True(t, ok)
+	assert.Equal(t, turn.GenerateAuthKey(turnServer.Username, "example.com", turnServer.Credential), key)
+}
+
+func TestICEServers_RequiresToken(t *testing.T) {
+	_, app, _ := setupICEApp(t, configs.DefaultICEConfig())
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/webrtc/ice-servers", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestICEServers_ServerPeerConnectionUsesEphemeralTURN(t *testing.T) {
+	// TURN REST API로 인증하는 실제 TURN 서버
+	udp, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	usernames := make(chan string, 16)
+	auth := turn.LongTermTURNRESTAuthHandler(testTURNSecret, logging.NewDefaultLoggerFactory().NewLogger("turn"))
+	server, err := turn.NewServer(turn.ServerConfig{
+		Realm: "example.com",
+		AuthHandler: func(username, realm string, srcAddr net.Addr) ([]byte, bool) {
+			key, ok := auth(username, realm, srcAddr)
+			if ok {
+				select {
+				case usernames <- username:
+				default:
+				}
+			}
+			return key, ok
+		},
+		PacketConnConfigs: []turn.PacketConnConfig{{
+			PacketConn:            udp,
+			RelayAddressGenerator: &turn.RelayAddressGeneratorStatic{RelayAddress: net.ParseIP("127.0.0.1"), Address: "127.0.0.1"},
+		}},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	t.Cleanup(func() { _ = server.Close() })
+
+	keys, _, base := setupICEApp(t, configs.ICEConfig{
+		Servers:       []configs.ICEServerConfig{{URLs: []string{"turn:" + udp.LocalAddr().String() + "?transport=udp"}, Ephemeral: true}},
+		TURNSecret:    testTURNSecret,
+		CredentialTTL: time.Minute,
+	})
+	conn := dialAs(t, keys, base+"/webrtc/audio", "user-a")
+
+	client := newAudioClient(t)
+	offer, err := client.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, client.SetLocalDescription(offer))
+	assert.NoError(t, conn.WriteJSON(audioMessage{Type: "offer", SDP: offer.SDP, TeamID: "team1"}))
+	assert.Equal(t, "answer", readAudioMessage(t, conn).Type)
+
+	// 서버 PeerConnection이 이 연결의 사용자에 묶인 자격 증명으로 relay를 할당함
+	select {
+	case username := <-usernames:
+		assert.True(t, strings.HasSuffix(username, ":user-a"), username)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server PeerConnection did not authenticate to the TURN server")
+	}
+
+	// relay candidate도 trickle로 옴
+	for {
+		msg := readAudioMessage(t, conn)
+		if msg.Type == "iceCandidate" && msg.Candidate != nil && strings.Contains(msg.Candidate.Candidate, "typ relay") {
+			break
+		}
+	}
+}
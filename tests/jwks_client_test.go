@@ -0,0 +1,216 @@
+package tests
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	middleware "go-server/middlewares"
+	"go-server/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// testJWKSServer는 kid → 공개키 목록을 JWKS로 내려주고 요청 수를 셉니다.
+type testJWKSServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	keys     map[string]*rsa.PublicKey
+	requests int32
+}
+
+func newTestJWKSServer(t *testing.T) *testJWKSServer {
+	s := &testJWKSServer{keys: make(map[string]*rsa.PublicKey)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.requests, 1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		keys := make([]map[string]string, 0, len(s.keys))
+		for kid, key := range s.keys {
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testJWKSServer) setKeys(keys map[string]*rsa.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	return privateKey
+}
+
+func TestJWKSClient_RefreshStoresKeys(t *testing.T) {
+	store, _, _ := newRetentionStore(t, utils.DefaultKeyRetention())
+	server := newTestJWKSServer(t)
+	key1, key2 := newRSAKey(t), newRSAKey(t)
+	server.setKeys(map[string]*rsa.PublicKey{"kid1": &key1.PublicKey, "kid2": &key2.PublicKey})
+
+	client := utils.NewJWKSClient(server.URL, store, 0, time.Minute)
+	assert.NoError(t, client.Refresh(context.Background()))
+
+	got, err := store.GetKey(context.Background(), "kid1")
+	assert.NoError(t, err)
+	assert.Equal(t, &key1.PublicKey, got.Key)
+	_, err = store.GetKey(context.Background(), "kid2")
+	assert.NoError(t, err)
+}
+
+func TestJWKSClient_RemovedKeyRetiredWithGracePeriod(t *testing.T) {
+	store, mr, _ := newRetentionStore(t, utils.KeyRetention{GracePeriod: 10 * time.Minute, MaxPreviousKeys: 2})
+	server := newTestJWKSServer(t)
+	key1, key2 := newRSAKey(t), newRSAKey(t)
+	client := utils.NewJWKSClient(server.URL, store, 0, time.Minute)
+	ctx := context.Background()
+
+	server.setKeys(map[string]*rsa.PublicKey{"kid1": &key1.PublicKey})
+	assert.NoError(t, client.Refresh(ctx))
+	server.setKeys(map[string]*rsa.PublicKey{"kid2": &key2.PublicKey})
+	assert.NoError(t, client.Refresh(ctx))
+
+	// 목록에서 빠진 키도 유예 기간 동안은 유효
+	_, err := store.GetKey(ctx, "kid1")
+	assert.NoError(t, err)
+
+	mr.FastForward(11 * time.Minute)
+	_, err = store.GetKey(ctx, "kid1")
+	assert.Error(t, err)
+	_, err = store.GetKey(ctx, "kid2")
+	assert.NoError(t, err)
+}
+
+func TestJWKSClient_EmptyJWKSKeepsExistingKeys(t *testing.T) {
+	store, _, _ := newRetentionStore(t, utils.DefaultKeyRetention())
+	server := newTestJWKSServer(t)
+	key := newRSAKey(t)
+	client := utils.NewJWKSClient(server.URL, store, 0, time.Minute)
+	ctx := context.Background()
+
+	server.setKeys(map[string]*rsa.PublicKey{"kid1": &key.PublicKey})
+	assert.NoError(t, client.Refresh(ctx))
+	server.setKeys(map[string]*rsa.PublicKey{})
+	assert.Error(t, client.Refresh(ctx))
+
+	keys, err := store.ListKeys(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, keys, 1) {
+		assert.True(t, keys[0].ExpiresAt.IsZero())
+	}
+}
+
+func TestJWKSClient_UnknownKidTriggersRateLimitedRefresh(t *testing.T) {
+	store, _, _ := newRetentionStore(t, utils.DefaultKeyRetention())
+	server := newTestJWKSServer(t)
+	client := utils.NewJWKSClient(server.URL, store, 0, time.Hour)
+	store.SetMissingKeyLoader(client.LoadMissingKey)
+	ctx := context.Background()
+
+	// 아직 한 번도 받아오지 않았으므로 모르는 kid에서 바로 조회
+	key := newRSAKey(t)
+	server.setKeys(map[string]*rsa.PublicKey{"new-kid": &key.PublicKey})
+	_, err := store.GetKey(ctx, "new-kid")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.requests))
+
+	// 최소 간격 안에서는 모르는 kid가 와도 다시 조회하지 않음
+	_, err = store.GetKey(ctx, "random-kid")
+	assert.Error(t, err)
+	_, err = store.GetKey(ctx, "another-kid")
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.requests))
+}
+
+func TestJWKSClient_ParseJWTWithFetchedKey(t *testing.T) {
+	store, _, _ := newRetentionStore(t, utils.DefaultKeyRetention())
+	server := newTestJWKSServer(t)
+	client := utils.NewJWKSClient(server.URL, store, 0, 0)
+	store.SetMissingKeyLoader(client.LoadMissingKey)
+
+	key := newRSAKey(t)
+	server.setKeys(map[string]*rsa.PublicKey{"jwks-kid": &key.PublicKey})
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, middleware.CustomClaims{
+		UserID: "user-1",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	token.Header["kid"] = "jwks-kid"
+	signed, err := token.SignedString(key)
+	assert.NoError(t, err)
+
+	claims, err := middleware.ParseJWT(signed, store, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "user-1", claims.UserID)
+	}
+}
+
+func TestJWKSClient_ECAndOKPKeys(t *testing.T) {
+	store, _, _ := newRetentionStore(t, utils.DefaultKeyRetention())
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edKey, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{
+				"kty": "EC",
+				"kid": "ec-kid",
+				"crv": "P-256",
+				"alg": "ES256",
+				"x":   base64.RawURLEncoding.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))),
+				"y":   base64.RawURLEncoding.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+			{
+				"kty": "OKP",
+				"kid": "ed-kid",
+				"crv": "Ed25519",
+				"x":   base64.RawURLEncoding.EncodeToString(edKey),
+			},
+		}})
+	}))
+	t.Cleanup(server.Close)
+
+	client := utils.NewJWKSClient(server.URL, store, 0, time.Minute)
+	assert.NoError(t, client.Refresh(context.Background()))
+
+	key, err := store.GetKey(context.Background(), "ec-kid")
+	if assert.NoError(t, err) {
+		assert.Equal(t, utils.AlgorithmES256, key.Algorithm)
+	}
+	key, err = store.GetKey(context.Background(), "ed-kid")
+	if assert.NoError(t, err) {
+		assert.Equal(t, utils.AlgorithmEdDSA, key.Algorithm)
+	}
+}
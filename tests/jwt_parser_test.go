@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	middleware "go-server/middlewares"
+	"go-server/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func pkixPublicKeyPEM(t *testing.T, pubKey crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(pubKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func signTestToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, middleware.CustomClaims{
+		UserID: "user-1",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func TestParseJWT_ES256(t *testing.T) {
+	store, _, _ := newRetentionStore(t, utils.DefaultKeyRetention())
+	privateKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, store.AddOrUpdateKey(context.Background(), "ec-kid", pkixPublicKeyPEM(t, &privateKey.PublicKey), utils.AlgorithmES256, time.Now()))
+
+	claims, err := middleware.ParseJWT(signTestToken(t, jwt.SigningMethodES256, "ec-kid", privateKey), store, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "user-1", claims.UserID)
+	}
+}
+
+func TestParseJWT_EdDSA(t *testing.T) {
+	store, _, _ := newRetentionStore(t, utils.DefaultKeyRetention())
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	// 알고리즘을 비워 두면 키 타입에서 EdDSA로 정해짐
+	assert.NoError(t, store.AddOrUpdateKey(context.Background(), "ed-kid", pkixPublicKeyPEM(t, publicKey), "", time.Now()))
+
+	key, err := store.GetKey(context.Background(), "ed-kid")
+	if assert.NoError(t, err) {
+		assert.Equal(t, utils.AlgorithmEdDSA, key.Algorithm)
+	}
+
+	claims, err := middleware.ParseJWT(signTestToken(t, jwt.SigningMethodEdDSA, "ed-kid", privateKey), store, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "user-1", claims.UserID)
+	}
+}
+
+func TestParseJWT_RejectsHMACWithPublicKeyAsSecret(t *testing.T) {
+	keys := newTestKeyStore(t)
+	pubKeyPEM := pkixPublicKeyPEM(t, &keys.privateKey.PublicKey)
+
+	_, err := middleware.ParseJWT(signTestToken(t, jwt.SigningMethodHS256, keys.kid, []byte(pubKeyPEM)), keys.store, nil)
+	assert.Error(t, err)
+}
+
+func TestParseJWT_RejectsAlgorithmOtherThanRegistered(t *testing.T) {
+	keys := newTestKeyStore(t)
+
+	// 같은 RSA 키라도 등록된 RS256이 아닌 PS256 서명은 거부
+	_, err := middleware.ParseJWT(signTestToken(t, jwt.SigningMethodPS256, keys.kid, keys.privateKey), keys.store, nil)
+	assert.Error(t, err)
+
+	_, err = middleware.ParseJWT(signTestToken(t, jwt.SigningMethodRS256, keys.kid, keys.privateKey), keys.store, nil)
+	assert.NoError(t, err)
+}
+
+func TestParseJWT_RejectsNone(t *testing.T) {
+	keys := newTestKeyStore(t)
+
+	_, err := middleware.ParseJWT(signTestToken(t, jwt.SigningMethodNone, keys.kid, jwt.UnsafeAllowNoneSignatureType), keys.store, nil)
+	assert.Error(t, err)
+}
+
+func TestPublicKeyStore_RejectsMismatchedAlgorithm(t *testing.T) {
+	store, _, _ := newRetentionStore(t, utils.DefaultKeyRetention())
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ctx := context.Background()
+
+	assert.Error(t, store.AddOrUpdateKey(ctx, "kid1", pkixPublicKeyPEM(t, &ecKey.PublicKey), utils.AlgorithmES384, time.Now()))
+	assert.Error(t, store.AddOrUpdateKey(ctx, "kid1", pkixPublicKeyPEM(t, &ecKey.PublicKey), utils.AlgorithmRS256, time.Now()))
+	assert.Error(t, store.AddOrUpdateKey(ctx, "kid1", newRSAPublicKeyPEM(t), utils.AlgorithmEdDSA, time.Now()))
+}
+
+func TestPublicKeyStore_LegacyRSAKeyDefaultsToRS256(t *testing.T) {
+	store, _, redisClient := newRetentionStore(t, utils.DefaultKeyRetention())
+	ctx := context.Background()
+
+	// 알고리즘 헤더 없이 저장된 기존 키
+	assert.NoError(t, redisClient.Set(ctx, "legacy-kid", newRSAPublicKeyPEM(t), 0).Err())
+
+	key, err := store.GetKey(ctx, "legacy-kid")
+	if assert.NoError(t, err) {
+		assert.Equal(t, utils.AlgorithmRS256, key.Algorithm)
+	}
+}
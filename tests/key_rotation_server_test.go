@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	middleware "go-server/middlewares"
+	pb "go-server/pkg/keyrotation"
+	"go-server/server"
+	"go-server/utils"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newKeyRotationClient는 store를 쓰는 키 교체 gRPC 서버를 메모리 리스너 위에 띄웁니다.
+func newKeyRotationClient(t *testing.T, store *utils.PublicKeyStore, revocations *utils.TokenRevocationStore) pb.KeyRotationNotifyServiceClient {
+	t.Helper()
+	return serveKeyRotation(t, server.NewKeyRotationNotifyServer(store, revocations))
+}
+
+// serveKeyRotation은 rotation을 메모리 리스너 위에 띄우고 클라이언트를 돌려줍니다.
+func serveKeyRotation(t *testing.T, rotation *server.KeyRotationNotifyServer) pb.KeyRotationNotifyServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	pb.RegisterKeyRotationNotifyServiceServer(s, rotation)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial bufnet: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return pb.NewKeyRotationNotifyServiceClient(conn)
+}
+
+func TestKeyRotationServer_ListKeys(t *testing.T) {
+	store, _, _ := newRetentionStore(t, utils.DefaultKeyRetention())
+	client := newKeyRotationClient(t, store, nil)
+	ctx := context.Background()
+
+	_, err := client.NotifyKeyRolled(ctx, &pb.NotifyKeyRolledRequest{CurrentKid: "kid1", CurrentPublicKeyPem: newRSAPublicKeyPEM(t)})
+	assert.NoError(t, err)
+	_, err = client.NotifyKeyRolled(ctx, &pb.NotifyKeyRolledRequest{CurrentKid: "kid2", CurrentPublicKeyPem: newRSAPublicKeyPEM(t), Algorithm: utils.AlgorithmPS256})
+	assert.NoError(t, err)
+
+	resp, err := client.ListKeys(ctx, &pb.ListKeysRequest{})
+	assert.NoError(t, err)
+	if assert.Len(t, resp.GetKeys(), 2) {
+		assert.Equal(t, "kid1", resp.Keys[0].GetKid())
+		assert.Equal(t, utils.AlgorithmRS256, resp.Keys[0].GetAlgorithm())
+		assert.NotEmpty(t, resp.Keys[0].GetExpiresAt())
+		assert.Equal(t, "kid2", resp.Keys[1].GetKid())
+		assert.Equal(t, utils.AlgorithmPS256, resp.Keys[1].GetAlgorithm())
+		assert.Empty(t, resp.Keys[1].GetExpiresAt())
+		assert.NotEmpty(t, resp.Keys[1].GetAddedAt())
+	}
+}
+
+func TestKeyRotationServer_LocalDateTimeUsesConfiguredZone(t *testing.T) {
+	retention := utils.KeyRetention{GracePeriod: 10 * time.Minute, MaxPreviousKeys: 2}
+	store, _, _ := newRetentionStore(t, retention)
+	kst := time.FixedZone("KST", 9*60*60)
+	rotation := server.NewKeyRotationNotifyServer(store, nil)
+	rotation.SetTimeZone(kst)
+	client := serveKeyRotation(t, rotation)
+	ctx := context.Background()
+
+	_, err := client.NotifyKeyRolled(ctx, &pb.NotifyKeyRolledRequest{CurrentKid: "kid1", CurrentPublicKeyPem: newRSAPublicKeyPEM(t)})
+	assert.NoError(t, err)
+
+	// 오프셋 없는 KST 벽시계 시각. UTC로 읽으면 이전 키가 9시간 더 살아남음
+	rolledAt := time.Now().Truncate(time.Second)
+	_, err = client.NotifyKeyRolled(ctx, &pb.NotifyKeyRolledRequest{
+		CurrentKid:          "kid2",
+		CurrentPublicKeyPem: newRSAPublicKeyPEM(t),
+		RolledAt:            rolledAt.In(kst).Format("2006-01-02T15:04:05"),
+	})
+	assert.NoError(t, err)
+
+	resp, err := client.ListKeys(ctx, &pb.ListKeysRequest{})
+	assert.NoError(t, err)
+	if assert.Len(t, resp.GetKeys(), 2) {
+		assert.Equal(t, "kid1", resp.Keys[0].GetKid())
+		expiresAt, err := time.Parse(time.RFC3339Nano, resp.Keys[0].GetExpiresAt())
+		assert.NoError(t, err)
+		assert.WithinDuration(t, rolledAt.Add(retention.GracePeriod), expiresAt, time.Second)
+	}
+}
+
+func TestKeyRotationServer_RevokeKey(t *testing.T) {
+	keys := newTestKeyStore(t)
+	client := newKeyRotationClient(t, keys.store, nil)
+	ctx := context.Background()
+	token := keys.sign(t, middleware.CustomClaims{UserID: "user-1"})
+	_, err := middleware.ParseJWT(token, keys.store, nil)
+	assert.NoError(t, err)
+
+	_, err = client.RevokeKey(ctx, &pb.RevokeKeyRequest{Kid: keys.kid, Reason: "leaked"})
+	assert.NoError(t, err)
+
+	_, err = middleware.ParseJWT(token, keys.store, nil)
+	assert.Error(t, err)
+
+	// 폐기된 kid는 다시 푸시해도 등록되지 않음
+	_, err = client.NotifyKeyRolled(ctx, &pb.NotifyKeyRolledRequest{CurrentKid: keys.kid, CurrentPublicKeyPem: newRSAPublicKeyPEM(t)})
+	assert.Error(t, err)
+	_, err = keys.store.GetKey(ctx, keys.kid)
+	assert.Error(t, err)
+}
+
+func TestKeyRotationServer_RevokeKeyRequiresKid(t *testing.T) {
+	store, _, _ := newRetentionStore(t, utils.DefaultKeyRetention())
+	client := newKeyRotationClient(t, store, nil)
+
+	_, err := client.RevokeKey(context.Background(), &pb.RevokeKeyRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestKeyRotationServer_WatchKeys(t *testing.T) {
+	store, _, _ := newRetentionStore(t, utils.DefaultKeyRetention())
+	client := newKeyRotationClient(t, store, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	assert.NoError(t, store.AddOrUpdateKey(ctx, "kid1", newRSAPublicKeyPEM(t), "", time.Now()))
+
+	stream, err := client.WatchKeys(ctx, &pb.WatchKeysRequest{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// 처음에는 현재 키 전체
+	event, err := stream.Recv()
+	if assert.NoError(t, err) {
+		assert.Equal(t, pb.KeyEventType_KEY_EVENT_TYPE_PUT, event.GetType())
+		assert.Equal(t, "kid1", event.GetKey().GetKid())
+		assert.NotEmpty(t, event.GetPublicKeyPem())
+	}
+
+	// 교체: 새 키 PUT, 이전 키는 만료 시각이 붙어 다시 PUT
+	assert.NoError(t, store.AddOrUpdateKey(ctx, "kid2", newRSAPublicKeyPEM(t), "", time.Now()))
+	got := map[string]*pb.KeyEvent{}
+	for len(got) < 2 {
+		event, err := stream.Recv()
+		if !assert.NoError(t, err) {
+			return
+		}
+		got[event.GetKey().GetKid()] = event
+	}
+	assert.Equal(t, pb.KeyEventType_KEY_EVENT_TYPE_PUT, got["kid2"].GetType())
+	assert.Empty(t, got["kid2"].GetKey().GetExpiresAt())
+	assert.NotEmpty(t, got["kid1"].GetKey().GetExpiresAt())
+
+	// 폐기: DELETE
+	assert.NoError(t, store.RevokeKey(ctx, "kid1"))
+	event, err = stream.Recv()
+	if assert.NoError(t, err) {
+		assert.Equal(t, pb.KeyEventType_KEY_EVENT_TYPE_DELETE, event.GetType())
+		assert.Equal(t, "kid1", event.GetKey().GetKid())
+	}
+}
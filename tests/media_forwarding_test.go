@@ -0,0 +1,215 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"go-server/configs"
+	"go-server/models"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// waitForStreams: 조건을 만족하는 트랙 목록이 올 때까지 기다립니다. 마지막으로 받은 목록을 반환합니다.
+func (c *politeClient) waitForStreams(timeout time.Duration, ok func([]models.MediaTrackInfo) bool) []models.MediaTrackInfo {
+	var last []models.MediaTrackInfo
+	deadline := time.After(timeout)
+	for {
+		select {
+		case tracks := <-c.streams:
+			last = tracks
+			if ok(tracks) {
+				return tracks
+			}
+		case <-deadline:
+			return last
+		}
+	}
+}
+
+func receivedStreamID(c *politeClient, trackID string) string {
+	if track, ok := c.received.Load(trackID); ok {
+		return track.(*webrtc.TrackRemote).StreamID()
+	}
+	return ""
+}
+
+func TestMediaForwarding_CameraAndScreenShareWithMetadata(t *testing.T) {
+	keys, base := setupLimitedWebSocketApp(t, configs.DefaultLimitConfig())
+
+	a := newPoliteClient(t, keys, base, "user-a", "team1", false)
+	a.addVideoTrack("user-a-camera", "a-camera", models.MediaSourceCamera)
+	a.addVideoTrack("user-a-screen", "a-screen", models.MediaSourceScreen)
+	a.join()
+	// a의 트랙이 서버에 올라올 때까지 기다린 뒤 b가 들어옴
+	time.Sleep(500 * time.Millisecond)
+
+	requests := counterValue(t, "webrtc_keyframe_requests_total")
+	b := newPoliteClient(t, keys, base, "user-b", "team1", false)
+	b.join()
+
+	got := b.waitForTracks(map[string]bool{"user-a-mic1": true, "user-a-camera": true, "user-a-screen": true}, 15*time.Second)
+	assert.True(t, got["user-a-mic1"])
+	assert.True(t, got["user-a-camera"])
+	assert.True(t, got["user-a-screen"])
+
+	// 메타데이터가 트랙 소유자와 출처, 보낸 연결을 알려 줌
+	tracks := b.waitForStreams(5*time.Second, func(tracks []models.MediaTrackInfo) bool { return len(tracks) == 3 })
+	if !assert.Len(t, tracks, 3) {
+		return
+	}
+	conn := tracks[0].ConnectionID
+	assert.NotEmpty(t, conn)
+	userStream, screenStream := "user-a:"+conn+":user", "user-a:"+conn+":screen"
+	assert.ElementsMatch(t, []models.MediaTrackInfo{
+		{TrackID: "user-a-mic1", StreamID: userStream, ParticipantID: "user-a", ConnectionID: conn, Kind: "audio", Source: models.MediaSourceMicrophone, MimeType: webrtc.MimeTypeOpus},
+		{TrackID: "user-a-camera", StreamID: userStream, ParticipantID: "user-a", ConnectionID: conn, Kind: "video", Source: models.MediaSourceCamera, MimeType: webrtc.MimeTypeVP8},
+		{TrackID: "user-a-screen", StreamID: screenStream, ParticipantID: "user-a", ConnectionID: conn, Kind: "video", Source: models.MediaSourceScreen, MimeType: webrtc.MimeTypeVP8},
+	}, tracks)
+
+	// 마이크와 카메라는 한 스트림, 화면 공유는 따로
+	assert.Equal(t, userStream, receivedStreamID(b, "user-a-mic1"))
+	assert.Equal(t, userStream, receivedStreamID(b, "user-a-camera"))
+	assert.Equal(t, screenStream, receivedStreamID(b, "user-a-screen"))
+
+	tracks = a.waitForStreams(5*time.Second, func(tracks []models.MediaTrackInfo) bool { return len(tracks) == 1 })
+	if assert.Len(t, tracks, 1) {
+		assert.Equal(t, "user-b", tracks[0].ParticipantID)
+	}
+
+	// 새 subscriber와 재협상이 끝나면 publisher에게 키프레임을 요청함
+	assert.Eventually(t, func() bool { return a.plis.Load() > 0 }, 5*time.Second, 20*time.Millisecond)
+	assert.Greater(t, counterValue(t, "webrtc_keyframe_requests_total"), requests)
+
+	// subscriber가 보낸 PLI/FIR은 publisher에게 넘어감(간격 제한이 지난 뒤)
+	time.Sleep(600 * time.Millisecond)
+	before := a.plis.Load()
+	screen, ok := b.received.Load("user-a-screen")
+	if !assert.True(t, ok) {
+		return
+	}
+	ssrc := uint32(screen.(*webrtc.TrackRemote).SSRC())
+	assert.NoError(t, b.pc.WriteRTCP([]rtcp.Packet{&rtcp.FullIntraRequest{MediaSSRC: ssrc, FIR: []rtcp.FIREntry{{SSRC: ssrc, SequenceNumber: 1}}}}))
+	assert.Eventually(t, func() bool { return a.plis.Load() > before }, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestMediaForwarding_LeavingParticipantIsRemovedFromStreams(t *testing.T) {
+	keys, base := setupLimitedWebSocketApp(t, configs.DefaultLimitConfig())
+
+	a := newPoliteClient(t, keys, base, "user-a", "team1", false)
+	a.join()
+	b := newPoliteClient(t, keys, base, "user-b", "team1", false)
+	b.addVideoTrack("user-b-camera", "b-camera", models.MediaSourceCamera)
+	b.join()
+
+	tracks := a.waitForStreams(10*time.Second, func(tracks []models.MediaTrackInfo) bool { return len(tracks) == 2 })
+	assert.Len(t, tracks, 2)
+
+	_ = b.conn.Close()
+	tracks = a.waitForStreams(10*time.Second, func(tracks []models.MediaTrackInfo) bool { return len(tracks) == 0 })
+	assert.NotNil(t, tracks)
+	assert.Empty(t, tracks)
+}
+
+func TestMediaForwarding_StoppedScreenShareIsRemovedFromStreams(t *testing.T) {
+	keys, base := setupLimitedWebSocketApp(t, configs.DefaultLimitConfig())
+
+	a := newPoliteClient(t, keys, base, "user-a", "team1", false)
+	a.addVideoTrack("user-a-camera", "a-camera", models.MediaSourceCamera)
+	a.addVideoTrack("user-a-screen", "a-screen", models.MediaSourceScreen)
+	a.join()
+	b := newPoliteClient(t, keys, base, "user-b", "team1", false)
+	b.join()
+
+	tracks := b.waitForStreams(10*time.Second, func(tracks []models.MediaTrackInfo) bool { return len(tracks) == 3 })
+	if !assert.Len(t, tracks, 3) {
+		return
+	}
+	got := b.waitForTracks(map[string]bool{"user-a-screen": true}, 10*time.Second)
+	if !assert.True(t, got["user-a-screen"]) {
+		return
+	}
+	screen, _ := b.received.Load("user-a-screen")
+	screenEnded := make(chan struct{})
+	go func() {
+		defer close(screenEnded)
+		for {
+			if _, _, err := screen.(*webrtc.TrackRemote).ReadRTP(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// a는 나가지 않고 화면 공유만 끔
+	a.removeVideoTrack("user-a-screen")
+	tracks = b.waitForStreams(10*time.Second, func(tracks []models.MediaTrackInfo) bool { return len(tracks) == 2 })
+	if assert.Len(t, tracks, 2) {
+		for _, track := range tracks {
+			assert.NotEqual(t, models.MediaSourceScreen, track.Source)
+			assert.Equal(t, "user-a", track.ParticipantID)
+		}
+	}
+	// 서버가 b에게 보내던 화면 트랙도 재협상으로 빠짐
+	select {
+	case <-screenEnded:
+	case <-time.After(10 * time.Second):
+		t.Fatal("user-b still receives user-a's screen track")
+	}
+
+	// a는 팀에 남아 있어 나중에 들어온 사람도 마이크와 카메라만 받음
+	c := newPoliteClient(t, keys, base, "user-c", "team1", false)
+	c.join()
+	tracks = c.waitForStreams(10*time.Second, func(tracks []models.MediaTrackInfo) bool { return len(tracks) == 3 })
+	if assert.Len(t, tracks, 3) {
+		sources := map[string]int{}
+		for _, track := range tracks {
+			sources[track.Source]++
+		}
+		assert.Equal(t, map[string]int{models.MediaSourceMicrophone: 2, models.MediaSourceCamera: 1}, sources)
+	}
+}
+
+func TestMediaForwarding_SameUserOnTwoConnectionsGetsSeparateStreams(t *testing.T) {
+	keys, base := setupLimitedWebSocketApp(t, configs.DefaultLimitConfig())
+
+	first := newPoliteClient(t, keys, base, "user-a", "team1", false)
+	first.join()
+	// 같은 사용자가 다른 탭에서 들어옴. 트랙 id는 브라우저가 탭마다 따로 만듦
+	second := newPoliteClient(t, keys, base, "user-a", "team1", false)
+	second.addVideoTrack("user-a-camera", "a-camera", models.MediaSourceCamera)
+	second.join()
+	b := newPoliteClient(t, keys, base, "user-b", "team1", false)
+	b.join()
+
+	tracks := b.waitForStreams(10*time.Second, func(tracks []models.MediaTrackInfo) bool { return len(tracks) == 3 })
+	if !assert.Len(t, tracks, 3) {
+		return
+	}
+	streams := map[string]string{}
+	for _, track := range tracks {
+		assert.Equal(t, "user-a", track.ParticipantID)
+		if track.Source == models.MediaSourceMicrophone {
+			streams[track.ConnectionID] = track.StreamID
+		}
+	}
+	// 두 연결의 마이크가 서로 다른 스트림으로 감
+	assert.Len(t, streams, 2)
+	for conn, stream := range streams {
+		assert.Equal(t, "user-a:"+conn+":user", stream)
+	}
+}
+
+func TestMediaForwarding_RejectsUnknownSource(t *testing.T) {
+	keys, base := setupLimitedWebSocketApp(t, configs.DefaultLimitConfig())
+	conn := dialAs(t, keys, base+"/webrtc/media", "user-a")
+
+	client := newAudioClient(t)
+	offer, err := client.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, conn.WriteJSON(audioMessage{Type: "offer", SDP: offer.SDP, TeamID: "team1", Sources: map[string]string{"s": "hologram"}}))
+	msg := readAudioMessage(t, conn)
+	assert.Equal(t, "error", msg.Type)
+	assert.Contains(t, msg.Error, "Invalid sources")
+}
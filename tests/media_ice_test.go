@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"go-server/configs"
+	"go-server/models"
+
+	"github.com/fasthttp/websocket"
+	"github.com/pion/webrtc/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// audioMessage: /webrtc/audio(/webrtc/media)에서 오가는 메시지
+type audioMessage struct {
+	Type      string                   `json:"type"`
+	SDP       string                   `json:"sdp,omitempty"`
+	TeamID    string                   `json:"teamId,omitempty"`
+	Sources   map[string]string        `json:"sources,omitempty"`
+	Candidate *webrtc.ICECandidateInit `json:"candidate,omitempty"`
+	Tracks    []models.MediaTrackInfo  `json:"tracks,omitempty"`
+	Error     string                   `json:"error,omitempty"`
+}
+
+func readAudioMessage(t *testing.T, conn *websocket.Conn) audioMessage {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg audioMessage
+	if !assert.NoError(t, conn.ReadJSON(&msg)) {
+		t.FailNow()
+	}
+	return msg
+}
+
+func newAudioClient(t *testing.T) *webrtc.PeerConnection {
+	t.Helper()
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = pc.Close() })
+	_, err = pc.AddTransceiverFromKind(webrtc.RTPCodecTypeAudio, webrtc.RTPTransceiverInit{Direction: webrtc.RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+	return pc
+}
+
+func TestAudioICE_TrickleBothWays(t *testing.T) {
+	keys, base := setupLimitedWebSocketApp(t, configs.DefaultLimitConfig())
+	conn := dialAs(t, keys, base+"/webrtc/audio", "user-a")
+	client := newAudioClient(t)
+
+	// 클라이언트 candidate는 모이는 대로 보냄
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	writes := make(chan audioMessage, 64)
+	client.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+		if candidate == nil {
+			return
+		}
+		init := candidate.ToJSON()
+		mid := client.GetTransceivers()[0].Mid()
+		init.SDPMid = &mid
+		select {
+		case writes <- audioMessage{Type: "iceCandidate", Candidate: &init}:
+		case <-done:
+		}
+	})
+	connected := make(chan struct{})
+	var connectedOnce sync.Once
+	client.OnICEConnectionStateChange(func(state webrtc.ICEConnectionState) {
+		if state == webrtc.ICEConnectionStateConnected {
+			connectedOnce.Do(func() { close(connected) })
+		}
+	})
+
+	offer, err := client.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, client.SetLocalDescription(offer))
+	assert.NoError(t, conn.WriteJSON(audioMessage{Type: "offer", SDP: offer.SDP, TeamID: "team1"}))
+	go func() {
+		for {
+			select {
+			case msg := <-writes:
+				_ = conn.WriteJSON(msg)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// answer가 candidate보다 먼저 옴
+	answer := readAudioMessage(t, conn)
+	assert.Equal(t, "answer", answer.Type)
+	assert.NoError(t, client.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: answer.SDP}))
+
+	reads := make(chan audioMessage)
+	go func() {
+		for {
+			var msg audioMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			select {
+			case reads <- msg:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// 서버 candidate에는 mid와 ufrag가 채워져 있음
+	candidates := 0
+	deadline := time.After(10 * time.Second)
+	waitConnected := connected
+	for candidates == 0 || waitConnected != nil {
+		select {
+		case <-waitConnected:
+			waitConnected = nil
+		case <-deadline:
+			t.Fatalf("ICE did not connect (server candidates: %d)", candidates)
+		case msg := <-reads:
+			if msg.Type == "streams" {
+				continue
+			}
+			if !assert.Equal(t, "iceCandidate", msg.Type) || !assert.NotNil(t, msg.Candidate) {
+				return
+			}
+			candidates++
+			if assert.NotNil(t, msg.Candidate.SDPMid) {
+				assert.Equal(t, client.GetTransceivers()[0].Mid(), *msg.Candidate.SDPMid)
+			}
+			assert.NotNil(t, msg.Candidate.UsernameFragment)
+			assert.NoError(t, client.AddICECandidate(*msg.Candidate))
+		}
+	}
+}
+
+func TestAudioICE_MalformedCandidatesDoNotPanic(t *testing.T) {
+	keys, base := setupLimitedWebSocketApp(t, configs.DefaultLimitConfig())
+	conn := dialAs(t, keys, base+"/webrtc/audio", "user-a")
+
+	for _, raw := range []string{
+		`{"type":"iceCandidate","candidate":"candidate:1 1 udp 1 127.0.0.1 9 typ host"}`,
+		`{"type":"iceCandidate","candidate":{"candidate":"candidate:1 1 udp 1 127.0.0.1 9 typ host","sdpMid":0}}`,
+		`{"type":"iceCandidate","candidate":{"candidate":"x","sdpMLineIndex":-1}}`,
+	} {
+		assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(raw)))
+		msg := readAudioMessage(t, conn)
+		assert.Equal(t, "error", msg.Type, raw)
+	}
+
+	// sdpMid 없이 sdpMLineIndex만 있거나 candidate가 빠진 메시지는 offer 전이라 모아 둠(응답 없음)
+	for _, raw := range []string{
+		`{"type":"iceCandidate","candidate":{"candidate":"candidate:1 1 udp 1 127.0.0.1 9 typ host","sdpMLineIndex":0}}`,
+		`{"type":"iceCandidate","candidate":{}}`,
+		`{"type":"iceCandidate"}`,
+	} {
+		assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(raw)))
+	}
+
+	// 연결이 살아 있고 offer도 처리됨
+	client := newAudioClient(t)
+	offer, err := client.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, client.SetLocalDescription(offer))
+	assert.NoError(t, conn.WriteJSON(audioMessage{Type: "offer", SDP: offer.SDP, TeamID: "team1"}))
+	assert.Equal(t, "answer", readAudioMessage(t, conn).Type)
+}
@@ -0,0 +1,480 @@
+package tests
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-server/configs"
+	"go-server/models"
+
+	"github.com/fasthttp/websocket"
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v4"
+	"github.com/pion/webrtc/v4/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+// opus 20ms 무음 프레임
+var opusSilence = []byte{0xf8, 0xff, 0xfe}
+
+// newSilentTrack: 무음을 계속 보내는 opus 트랙. done이 닫히면 멈춥니다.
+func newSilentTrack(t *testing.T, trackID, streamID string, done <-chan struct{}) *webrtc.TrackLocalStaticSample {
+	t.Helper()
+	track, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus}, trackID, streamID)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				_ = track.WriteSample(media.Sample{Data: opusSilence, Duration: 20 * time.Millisecond})
+			}
+		}
+	}()
+	return track
+}
+
+// newVideoTrack: 가짜 VP8 프레임을 30fps로 보내는 트랙. SFU는 내용을 보지 않고 RTP만 넘깁니다.
+func newVideoTrack(t *testing.T, trackID, streamID string, done <-chan struct{}) *webrtc.TrackLocalStaticSample {
+	t.Helper()
+	track, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, trackID, streamID)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	frame := []byte{0x10, 0x02, 0x00, 0x9d, 0x01, 0x2a, 0x10, 0x00, 0x10, 0x00, 0x00, 0x00}
+	go func() {
+		ticker := time.NewTicker(33 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				_ = track.WriteSample(media.Sample{Data: frame, Duration: 33 * time.Millisecond})
+			}
+		}
+	}()
+	return track
+}
+
+// politeClient: perfect negotiation의 polite 쪽 클라이언트.
+// 서버 offer와 자기 offer가 부딪히면 자기 offer를 버리고(rollback), 서버 offer에 답한 뒤 다시 offer합니다.
+// pion은 have-local-offer에서 rollback을 지원하지 않으므로, 보낸 offer는 answer가 올 때까지 적용하지 않고 들고 있다가
+// 부딪히면 그냥 버립니다. 서버 입장에서는 브라우저의 rollback과 같습니다.
+// pc 조작과 웹소켓 쓰기는 run goroutine 하나에서만 합니다.
+type politeClient struct {
+	t          *testing.T
+	userID     string
+	teamID     string
+	conn       *websocket.Conn
+	pc         *webrtc.PeerConnection
+	negotiate  chan struct{}
+	candidates chan webrtc.ICECandidateInit
+	reads      chan audioMessage
+	done       chan struct{}
+
+	remoteTracks chan string
+	// received: 받은 원격 트랙(트랙 ID -> *webrtc.TrackRemote)
+	received  sync.Map
+	rollbacks atomic.Int32
+	// extraTrack: 첫 answer를 받은 뒤 트랙을 하나 더 붙일지
+	extraTrack bool
+
+	// sources: 입장 offer에 실어 보낼 MediaStream id별 출처
+	sources map[string]string
+	// streams: 서버가 보낸 트랙 목록(type "streams")
+	streams chan []models.MediaTrackInfo
+	// plis: 이 클라이언트의 비디오 트랙에 들어온 키프레임 요청(PLI/FIR) 수
+	plis atomic.Int32
+	// videoSenders: addVideoTrack으로 붙인 트랙 ID -> sender
+	videoSenders map[string]*webrtc.RTPSender
+	// actions: run goroutine에서 실행할 pc 조작
+	actions chan func()
+}
+
+func newPoliteClient(t *testing.T, keys *testKeyStore, base, userID, teamID string, extraTrack bool) *politeClient {
+	t.Helper()
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	client := &politeClient{
+		t:            t,
+		userID:       userID,
+		teamID:       teamID,
+		conn:         dialAs(t, keys, base+"/webrtc/media", userID),
+		pc:           pc,
+		negotiate:    make(chan struct{}, 1),
+		candidates:   make(chan webrtc.ICECandidateInit, 64),
+		reads:        make(chan audioMessage),
+		done:         make(chan struct{}),
+		remoteTracks: make(chan string, 64),
+		extraTrack:   extraTrack,
+		sources:      make(map[string]string),
+		streams:      make(chan []models.MediaTrackInfo, 64),
+		videoSenders: make(map[string]*webrtc.RTPSender),
+		actions:      make(chan func()),
+	}
+	t.Cleanup(func() {
+		close(client.done)
+		_ = pc.Close()
+	})
+
+	if _, err := pc.AddTrack(newSilentTrack(t, userID+"-mic1", userID, client.done)); !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	pc.OnNegotiationNeeded(func() {
+		select {
+		case client.negotiate <- struct{}{}:
+		default:
+		}
+	})
+	pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+		if candidate == nil {
+			return
+		}
+		init := candidate.ToJSON()
+		mid := pc.GetTransceivers()[0].Mid()
+		init.SDPMid = &mid
+		select {
+		case client.candidates <- init:
+		case <-client.done:
+		}
+	})
+	pc.OnTrack(func(track *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+		client.received.Store(track.ID(), track)
+		select {
+		case client.remoteTracks <- track.ID():
+		case <-client.done:
+		}
+	})
+
+	go func() {
+		for {
+			var msg audioMessage
+			if err := client.conn.ReadJSON(&msg); err != nil {
+				close(client.reads)
+				return
+			}
+			select {
+			case client.reads <- msg:
+			case <-client.done:
+				return
+			}
+		}
+	}()
+	return client
+}
+
+// addVideoTrack: join 전에 VP8 비디오 트랙을 붙이고 출처를 적습니다. 들어온 PLI/FIR은 plis로 셉니다.
+func (c *politeClient) addVideoTrack(trackID, streamID, source string) {
+	sender, err := c.pc.AddTrack(newVideoTrack(c.t, trackID, streamID, c.done))
+	if !assert.NoError(c.t, err) {
+		c.t.FailNow()
+	}
+	c.sources[streamID] = source
+	c.videoSenders[trackID] = sender
+	go func() {
+		for {
+			packets, _, err := sender.ReadRTCP()
+			if err != nil {
+				return
+			}
+			for _, packet := range packets {
+				switch packet.(type) {
+				case *rtcp.PictureLossIndication, *rtcp.FullIntraRequest:
+					c.plis.Add(1)
+				}
+			}
+		}
+	}()
+}
+
+// removeVideoTrack: 입장한 뒤 addVideoTrack으로 붙인 트랙을 뺍니다(화면 공유 중지). 재협상은 run이 합니다.
+func (c *politeClient) removeVideoTrack(trackID string) {
+	sender := c.videoSenders[trackID]
+	select {
+	case c.actions <- func() {
+		if err := c.pc.RemoveTrack(sender); err != nil {
+			c.errorf("%s: remove %s: %v", c.userID, trackID, err)
+		}
+	}:
+	case <-c.done:
+	}
+}
+
+// join: 팀에 들어가는 offer를 보내고 협상 goroutine을 시작합니다.
+func (c *politeClient) join() {
+	offer, err := c.pc.CreateOffer(nil)
+	if !assert.NoError(c.t, err) {
+		return
+	}
+	assert.NoError(c.t, c.conn.WriteJSON(audioMessage{Type: "offer", SDP: offer.SDP, TeamID: c.teamID, Sources: c.sources}))
+	go c.run(&offer)
+}
+
+// errorf: 테스트가 끝나 pc를 닫은 뒤 생긴 오류는 무시합니다.
+func (c *politeClient) errorf(format string, args ...interface{}) {
+	select {
+	case <-c.done:
+	default:
+		c.t.Errorf(format, args...)
+	}
+}
+
+// run: outstanding은 보냈지만 아직 적용하지 않은 자기 offer입니다.
+func (c *politeClient) run(outstanding *webrtc.SessionDescription) {
+	joined := false
+	pending := false
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-c.negotiate:
+			pending = true
+		case action := <-c.actions:
+			action()
+		case candidate := <-c.candidates:
+			_ = c.conn.WriteJSON(audioMessage{Type: "iceCandidate", Candidate: &candidate})
+		case msg, ok := <-c.reads:
+			if !ok {
+				return
+			}
+			switch msg.Type {
+			case "offer":
+				if outstanding != nil {
+					// glare: polite 쪽이 양보. 서버는 이 offer를 무시했음
+					outstanding = nil
+					c.rollbacks.Add(1)
+					pending = true
+				}
+				if err := c.pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: msg.SDP}); err != nil {
+					c.errorf("%s: set server offer: %v", c.userID, err)
+					return
+				}
+				answer, err := c.pc.CreateAnswer(nil)
+				if err != nil {
+					c.errorf("%s: create answer: %v", c.userID, err)
+					return
+				}
+				_ = c.pc.SetLocalDescription(answer)
+				_ = c.conn.WriteJSON(audioMessage{Type: "answer", SDP: answer.SDP})
+			case "answer":
+				if outstanding == nil {
+					c.errorf("%s: unexpected answer without an outstanding offer", c.userID)
+					return
+				}
+				if err := c.pc.SetLocalDescription(*outstanding); err != nil {
+					c.errorf("%s: apply own offer: %v", c.userID, err)
+					return
+				}
+				outstanding = nil
+				if err := c.pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: msg.SDP}); err != nil {
+					c.errorf("%s: set server answer: %v", c.userID, err)
+					return
+				}
+				if !joined {
+					joined = true
+					if c.extraTrack {
+						// 입장 직후 트랙을 하나 더 붙여 서버 re-offer와 부딪히게 함
+						if _, err := c.pc.AddTrack(newSilentTrack(c.t, c.userID+"-mic2", c.userID, c.done)); err != nil {
+							c.errorf("%s: add second track: %v", c.userID, err)
+						}
+					}
+				}
+			case "iceCandidate":
+				if msg.Candidate != nil {
+					_ = c.pc.AddICECandidate(*msg.Candidate)
+				}
+			case "streams":
+				select {
+				case c.streams <- msg.Tracks:
+				default:
+				}
+			case "error":
+				c.errorf("%s: server error: %s", c.userID, msg.Error)
+			}
+		}
+
+		if pending && joined && outstanding == nil && c.pc.SignalingState() == webrtc.SignalingStateStable {
+			pending = false
+			offer, err := c.pc.CreateOffer(nil)
+			if err != nil {
+				c.errorf("%s: create offer: %v", c.userID, err)
+				return
+			}
+			outstanding = &offer
+			_ = c.conn.WriteJSON(audioMessage{Type: "offer", SDP: offer.SDP})
+		}
+	}
+}
+
+// waitForTracks: 원격 트랙 ID가 want를 모두 포함할 때까지 기다립니다.
+func (c *politeClient) waitForTracks(want map[string]bool, timeout time.Duration) map[string]bool {
+	got := make(map[string]bool)
+	deadline := time.After(timeout)
+	for {
+		missing := false
+		for id := range want {
+			if !got[id] {
+				missing = true
+				break
+			}
+		}
+		if !missing {
+			return got
+		}
+		select {
+		case id := <-c.remoteTracks:
+			got[id] = true
+		case <-deadline:
+			return got
+		}
+	}
+}
+
+func TestAudioNegotiation_ConcurrentJoins(t *testing.T) {
+	keys, base := setupLimitedWebSocketApp(t, configs.DefaultLimitConfig())
+
+	const peers = 6
+	clients := make([]*politeClient, peers)
+	for i := range clients {
+		clients[i] = newPoliteClient(t, keys, base, fmt.Sprintf("user-%d", i), "team1", true)
+	}
+
+	// 모두 동시에 입장
+	var wg sync.WaitGroup
+	for _, client := range clients {
+		wg.Add(1)
+		go func(client *politeClient) {
+			defer wg.Done()
+			client.join()
+		}(client)
+	}
+	wg.Wait()
+
+	// 각자 다른 사람의 트랙 두 개씩을 모두 받음
+	var checks sync.WaitGroup
+	for _, client := range clients {
+		want := make(map[string]bool)
+		for _, other := range clients {
+			if other != client {
+				want[other.userID+"-mic1"] = true
+				want[other.userID+"-mic2"] = true
+			}
+		}
+		checks.Add(1)
+		go func(client *politeClient) {
+			defer checks.Done()
+			got := client.waitForTracks(want, 20*time.Second)
+			for id := range want {
+				assert.True(t, got[id], "%s did not receive %s", client.userID, id)
+			}
+			// 자기 트랙은 돌려받지 않음
+			assert.False(t, got[client.userID+"-mic1"] || got[client.userID+"-mic2"], client.userID)
+		}(client)
+	}
+	checks.Wait()
+}
+
+func TestAudioNegotiation_ServerIgnoresClientOfferDuringGlare(t *testing.T) {
+	keys, base := setupLimitedWebSocketApp(t, configs.DefaultLimitConfig())
+
+	// a는 협상을 테스트에서 직접 진행. candidate는 SDP에 모아 보냄
+	conn := dialAs(t, keys, base+"/webrtc/audio", "user-a")
+	a, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	t.Cleanup(func() { _ = a.Close() })
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	_, err = a.AddTrack(newSilentTrack(t, "user-a-mic1", "user-a", done))
+	assert.NoError(t, err)
+	aTracks := make(chan string, 8)
+	a.OnTrack(func(track *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+		select {
+		case aTracks <- track.ID():
+		case <-done:
+		}
+	})
+
+	sendOffer := func(teamID string) {
+		offer, err := a.CreateOffer(nil)
+		assert.NoError(t, err)
+		gathered := webrtc.GatheringCompletePromise(a)
+		assert.NoError(t, a.SetLocalDescription(offer))
+		<-gathered
+		assert.NoError(t, conn.WriteJSON(audioMessage{Type: "offer", SDP: a.LocalDescription().SDP, TeamID: teamID}))
+	}
+	// nextSignal: 서버 candidate는 적용하고 트랙 목록은 건너뛰어 다음 offer/answer를 반환
+	nextSignal := func() audioMessage {
+		for {
+			msg := readAudioMessage(t, conn)
+			switch msg.Type {
+			case "iceCandidate":
+				_ = a.AddICECandidate(*msg.Candidate)
+			case "streams":
+			default:
+				return msg
+			}
+		}
+	}
+
+	sendOffer("team1")
+	answer := nextSignal()
+	if !assert.Equal(t, "answer", answer.Type) {
+		return
+	}
+	assert.NoError(t, a.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: answer.SDP}))
+
+	// b가 들어와 트랙을 보내면 서버가 a에게 re-offer함
+	b := newPoliteClient(t, keys, base, "user-b", "team1", false)
+	b.join()
+	serverOffer := nextSignal()
+	if !assert.Equal(t, "offer", serverOffer.Type) {
+		return
+	}
+
+	// 서버 offer에 답하기 전에 a도 offer를 보냄 → 서버(impolite)는 무시하고 아무 응답도 보내지 않음
+	// pion은 rollback을 지원하지 않으므로 이 offer는 적용하지 않고 보내기만 함
+	collisions := counterValue(t, "webrtc_offer_collisions_total")
+	glareOffer, err := a.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, conn.WriteJSON(audioMessage{Type: "offer", SDP: glareOffer.SDP}))
+	assert.Eventually(t, func() bool {
+		return counterValue(t, "webrtc_offer_collisions_total") == collisions+1
+	}, 3*time.Second, 20*time.Millisecond)
+
+	// a(polite)는 보낸 offer를 버리고 서버 offer에 답한 뒤 다시 offer
+	assert.NoError(t, a.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: serverOffer.SDP}))
+	reply, err := a.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, a.SetLocalDescription(reply))
+	assert.NoError(t, conn.WriteJSON(audioMessage{Type: "answer", SDP: reply.SDP}))
+
+	sendOffer("")
+	answer = nextSignal()
+	if assert.Equal(t, "answer", answer.Type) {
+		assert.NoError(t, a.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: answer.SDP}))
+	}
+	assert.Equal(t, webrtc.SignalingStateStable, a.SignalingState())
+
+	// 협상이 끝난 뒤 b의 트랙이 a에게 옴
+	select {
+	case id := <-aTracks:
+		assert.Equal(t, "user-b-mic1", id)
+	case <-time.After(10 * time.Second):
+		t.Fatal("user-a did not receive user-b's track")
+	}
+	got := b.waitForTracks(map[string]bool{"user-a-mic1": true}, 10*time.Second)
+	assert.True(t, got["user-a-mic1"])
+}
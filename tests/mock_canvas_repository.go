@@ -6,12 +6,15 @@ import (
 	"time"
 
 	"go-server/models"
+	"go-server/repository"
 	"go-server/utils"
 )
 
 type MockCanvasRepository struct {
 	data map[string]models.Canvas
 	mu   sync.RWMutex
+	// ydoc를 설정하면 본문 쓰기가 실제 저장소처럼 Yjs 로그가 있을 때 ErrYDocActive로 실패하고, 성공하면 로그를 비웁니다.
+	ydoc *MockYDocRepository
 }
 
 func NewMockCanvasRepository() *MockCanvasRepository {
@@ -20,6 +23,25 @@ func NewMockCanvasRepository() *MockCanvasRepository {
 	}
 }
 
+// SetYDoc은 canvases 컬렉션의 ydoc_updates를 docs로 흉내 냅니다.
+func (m *MockCanvasRepository) SetYDoc(docs *MockYDocRepository) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ydoc = docs
+}
+
+// writeContent: 본문을 쓰기 전에 부릅니다. Yjs 로그가 있으면 ErrYDocActive, 없으면 빈 로그를 정리합니다.
+func (m *MockCanvasRepository) writeContent(id string) error {
+	if m.ydoc == nil {
+		return nil
+	}
+	if len(m.ydoc.Updates(id)) > 0 {
+		return repository.ErrYDocActive
+	}
+	m.ydoc.Clear(id)
+	return nil
+}
+
 func (m *MockCanvasRepository) SaveCanvas(canvas models.Canvas) (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -27,6 +49,9 @@ func (m *MockCanvasRepository) SaveCanvas(canvas models.Canvas) (string, error)
 	if canvas.Title == "fail" {
 		return "", errors.New("failed to save canvas")
 	}
+	if err := m.writeContent(canvas.ID); err != nil {
+		return "", err
+	}
 	if canvas.ID == "" {
 		canvas.ID = utils.GenerateID()
 	}
@@ -75,6 +100,23 @@ func (m *MockCanvasRepository) UpdateCanvasTitle(id, newTitle string) error {
 	return nil
 }
 
+func (m *MockCanvasRepository) RestoreCanvas(id, title, content string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	canvas, ok := m.data[id]
+	if !ok {
+		return errors.New("canvas not found")
+	}
+	if err := m.writeContent(id); err != nil {
+		return err
+	}
+	canvas.Title = title
+	canvas.Canvas = content
+	m.data[id] = canvas
+	return nil
+}
+
 func (m *MockCanvasRepository) DeleteCanvasByID(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
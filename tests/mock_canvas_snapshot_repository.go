@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go-server/models"
+	"go-server/repository"
+	"go-server/utils"
+)
+
+type MockCanvasSnapshotRepository struct {
+	data map[string]models.CanvasSnapshot
+	mu   sync.RWMutex
+	// deleteErr를 설정하면 DeleteSnapshotsByCanvasID가 실패합니다.
+	deleteErr error
+}
+
+func NewMockCanvasSnapshotRepository() *MockCanvasSnapshotRepository {
+	return &MockCanvasSnapshotRepository{
+		data: make(map[string]models.CanvasSnapshot),
+	}
+}
+
+func (m *MockCanvasSnapshotRepository) SaveSnapshot(snapshot models.CanvasSnapshot) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot.ID = utils.GenerateID()
+	snapshot.Size = len(snapshot.Canvas)
+	snapshot.Storage = models.SnapshotStorageInline
+	snapshot.CreatedAt = time.Now()
+	m.data[snapshot.ID] = snapshot
+	return snapshot.ID, nil
+}
+
+func (m *MockCanvasSnapshotRepository) FindSnapshotsByCanvasID(canvasID string) ([]models.CanvasSnapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshots := make([]models.CanvasSnapshot, 0)
+	for _, snapshot := range m.data {
+		if snapshot.CanvasID == canvasID {
+			snapshot.Canvas = ""
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+func (m *MockCanvasSnapshotRepository) FindLatestAutoSnapshot(canvasID string) (models.CanvasSnapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var latest models.CanvasSnapshot
+	for _, snapshot := range m.data {
+		if snapshot.CanvasID == canvasID && snapshot.Auto && snapshot.CreatedAt.After(latest.CreatedAt) {
+			latest = snapshot
+		}
+	}
+	if latest.ID == "" {
+		return latest, repository.ErrSnapshotNotFound
+	}
+	latest.Canvas = ""
+	return latest, nil
+}
+
+func (m *MockCanvasSnapshotRepository) FindSnapshot(canvasID, snapshotID string) (models.CanvasSnapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshot, ok := m.data[snapshotID]
+	if !ok || snapshot.CanvasID != canvasID {
+		return models.CanvasSnapshot{}, repository.ErrSnapshotNotFound
+	}
+	return snapshot, nil
+}
+
+func (m *MockCanvasSnapshotRepository) SetDeleteError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteErr = err
+}
+
+func (m *MockCanvasSnapshotRepository) DeleteSnapshotsByCanvasID(canvasID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.deleteErr != nil {
+		return m.deleteErr
+	}
+	for id, snapshot := range m.data {
+		if snapshot.CanvasID == canvasID {
+			delete(m.data, id)
+		}
+	}
+	return nil
+}
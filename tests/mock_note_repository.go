@@ -6,32 +6,61 @@ import (
 	"time"
 
 	"go-server/models"
+	"go-server/repository"
 	"go-server/utils"
 )
 
 type MockNoteRepository struct {
-	data map[string]models.Note
-	mu   sync.RWMutex
+	data      map[string]models.Note
+	revisions map[string][]models.NoteRevision
+	mu        sync.RWMutex
+	// ydoc를 설정하면 본문 쓰기가 실제 저장소처럼 Yjs 로그가 있을 때 ErrYDocActive로 실패하고, 성공하면 로그를 비웁니다.
+	ydoc *MockYDocRepository
 }
 
 func NewMockNoteRepository() *MockNoteRepository {
 	return &MockNoteRepository{
-		data: make(map[string]models.Note),
+		data:      make(map[string]models.Note),
+		revisions: make(map[string][]models.NoteRevision),
 	}
 }
 
-func (m *MockNoteRepository) SaveNote(note models.Note) (string, error) {
+// SetYDoc은 notes 컬렉션의 ydoc_updates를 docs로 흉내 냅니다.
+func (m *MockNoteRepository) SetYDoc(docs *MockYDocRepository) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ydoc = docs
+}
+
+// writeContent: 본문을 쓰기 전에 부릅니다. Yjs 로그가 있으면 ErrYDocActive, 없으면 빈 로그를 정리합니다.
+func (m *MockNoteRepository) writeContent(id string) error {
+	if m.ydoc == nil {
+		return nil
+	}
+	if len(m.ydoc.Updates(id)) > 0 {
+		return repository.ErrYDocActive
+	}
+	m.ydoc.Clear(id)
+	return nil
+}
+
+func (m *MockNoteRepository) SaveNote(note models.Note, authorID string) (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if note.Title == "fail" {
 		return "", errors.New("failed to save note")
 	}
+	if err := m.writeContent(note.ID); err != nil {
+		return "", err
+	}
 	if note.ID == "" {
 		note.ID = utils.GenerateID()
 	}
+	note.Version = m.data[note.ID].Version + 1
 	note.CreatedAt = time.Now()
 	m.data[note.ID] = note
+	m.saveRevision(note, authorID, 0)
 	return note.ID, nil
 }
 
@@ -76,6 +105,30 @@ func (m *MockNoteRepository) UpdateNoteTitle(id, newTitle string) error {
 	return nil
 }
 
+func (m *MockNoteRepository) UpdateNoteContent(id, content string, expectedVersion int, authorID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id == "fail" {
+		return 0, errors.New("failed to update note content")
+	}
+	note, ok := m.data[id]
+	if !ok {
+		return 0, repository.ErrNoteNotFound
+	}
+	if note.Version != expectedVersion {
+		return note.Version, repository.ErrVersionConflict
+	}
+	if err := m.writeContent(id); err != nil {
+		return note.Version, err
+	}
+	note.Note = content
+	note.Version++
+	m.data[id] = note
+	m.saveRevision(note, authorID, 0)
+	return note.Version, nil
+}
+
 func (m *MockNoteRepository) DeleteNoteByID(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -88,5 +141,72 @@ func (m *MockNoteRepository) DeleteNoteByID(id string) error {
 		return errors.New("note not found")
 	}
 	delete(m.data, id)
+	delete(m.revisions, id)
 	return nil
 }
+
+func (m *MockNoteRepository) saveRevision(note models.Note, authorID string, restoredFrom int) {
+	m.revisions[note.ID] = append(m.revisions[note.ID], models.NoteRevision{
+		ID:           utils.GenerateID(),
+		NoteID:       note.ID,
+		TeamID:       note.TeamID,
+		Version:      note.Version,
+		Title:        note.Title,
+		Note:         note.Note,
+		AuthorID:     authorID,
+		RestoredFrom: restoredFrom,
+		CreatedAt:    time.Now(),
+	})
+}
+
+func (m *MockNoteRepository) FindNoteRevisions(noteID string) ([]models.NoteRevision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if noteID == "fail" {
+		return nil, errors.New("failed to find note revisions")
+	}
+	revisions := make([]models.NoteRevision, 0, len(m.revisions[noteID]))
+	for i := len(m.revisions[noteID]) - 1; i >= 0; i-- {
+		revision := m.revisions[noteID][i]
+		revision.Note = ""
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+func (m *MockNoteRepository) FindNoteRevision(noteID string, version int) (models.NoteRevision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, revision := range m.revisions[noteID] {
+		if revision.Version == version {
+			return revision, nil
+		}
+	}
+	return models.NoteRevision{}, repository.ErrRevisionNotFound
+}
+
+func (m *MockNoteRepository) RestoreNoteRevision(noteID string, version int, authorID string) (int, error) {
+	revision, err := m.FindNoteRevision(noteID, version)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	note, ok := m.data[noteID]
+	if !ok {
+		return 0, repository.ErrNoteNotFound
+	}
+	if err := m.writeContent(noteID); err != nil {
+		return 0, err
+	}
+	note.Title = revision.Title
+	note.Note = revision.Note
+	note.Version++
+	m.data[noteID] = note
+	m.saveRevision(note, authorID, version)
+	return note.Version, nil
+}
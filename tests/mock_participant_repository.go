@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go-server/models"
+	"go-server/repository"
+)
+
+type MockParticipantRepository struct {
+	participants map[string]map[string]models.Participant // key: "teamID:kind"
+	lastSeen     map[string]map[string]time.Time
+	conns        map[string]map[string]string // key: "teamID:kind", value: map[connID]participantID
+	mu           sync.Mutex
+}
+
+func NewMockParticipantRepository() *MockParticipantRepository {
+	return &MockParticipantRepository{
+		participants: make(map[string]map[string]models.Participant),
+		lastSeen:     make(map[string]map[string]time.Time),
+		conns:        make(map[string]map[string]string),
+	}
+}
+
+func (m *MockParticipantRepository) AddParticipant(ctx context.Context, teamID, kind, connID string, p models.Participant) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := fmt.Sprintf("%s:%s", teamID, kind)
+	if _, exists := m.participants[key]; !exists {
+		m.participants[key] = make(map[string]models.Participant)
+	}
+	m.participants[key][p.ID] = p
+	if _, exists := m.lastSeen[key]; !exists {
+		m.lastSeen[key] = make(map[string]time.Time)
+	}
+	m.lastSeen[key][p.ID] = time.Now()
+	if _, exists := m.conns[key]; !exists {
+		m.conns[key] = make(map[string]string)
+	}
+	m.conns[key][connID] = p.ID
+	return nil
+}
+
+func (m *MockParticipantRepository) LeaveParticipant(ctx context.Context, teamID, kind, connID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := fmt.Sprintf("%s:%s", teamID, kind)
+	participantID, exists := m.conns[key][connID]
+	if !exists {
+		return false, nil
+	}
+	delete(m.conns[key], connID)
+	for _, other := range m.conns[key] {
+		if other == participantID {
+			return false, nil
+		}
+	}
+	m.removeLocked(key, participantID)
+	return true, nil
+}
+
+func (m *MockParticipantRepository) RemoveParticipant(ctx context.Context, teamID, kind, participantID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeLocked(fmt.Sprintf("%s:%s", teamID, kind), participantID)
+	return nil
+}
+
+func (m *MockParticipantRepository) removeLocked(key, participantID string) {
+	for connID, id := range m.conns[key] {
+		if id == participantID {
+			delete(m.conns[key], connID)
+		}
+	}
+	delete(m.lastSeen[key], participantID)
+	if participants, exists := m.participants[key]; exists {
+		delete(participants, participantID)
+		if len(participants) == 0 {
+			delete(m.participants, key)
+		}
+	}
+}
+
+func (m *MockParticipantRepository) GetParticipants(ctx context.Context, teamID, kind string) ([]models.Participant, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := fmt.Sprintf("%s:%s", teamID, kind)
+	participants := make([]models.Participant, 0)
+	for _, p := range m.participants[key] {
+		participants = append(participants, p)
+	}
+	return participants, nil
+}
+
+func (m *MockParticipantRepository) TouchParticipant(ctx context.Context, teamID, kind, participantID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := fmt.Sprintf("%s:%s", teamID, kind)
+	if _, exists := m.lastSeen[key][participantID]; exists {
+		m.lastSeen[key][participantID] = time.Now()
+	}
+	return nil
+}
+
+func (m *MockParticipantRepository) RemoveExpiredParticipants(ctx context.Context, ttl time.Duration) ([]repository.ParticipantRoom, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var rooms []repository.ParticipantRoom
+	for key, seen := range m.lastSeen {
+		removed := false
+		for id, at := range seen {
+			if time.Since(at) > ttl {
+				m.removeLocked(key, id)
+				removed = true
+			}
+		}
+		if removed {
+			i := strings.LastIndex(key, ":")
+			rooms = append(rooms, repository.ParticipantRoom{TeamID: key[:i], Kind: key[i+1:]})
+		}
+	}
+	return rooms, nil
+}
+
+func (m *MockParticipantRepository) RemoveOwnedParticipants(ctx context.Context) ([]repository.ParticipantRoom, error) {
+	return nil, nil
+}
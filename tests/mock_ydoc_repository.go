@@ -0,0 +1,61 @@
+package tests
+
+import "sync"
+
+type MockYDocRepository struct {
+	data map[string][][]byte
+	mu   sync.Mutex
+	// appendErr를 설정하면 AppendUpdate가 실패합니다.
+	appendErr error
+}
+
+func NewMockYDocRepository() *MockYDocRepository {
+	return &MockYDocRepository{data: make(map[string][][]byte)}
+}
+
+func (m *MockYDocRepository) LoadUpdates(id string) ([][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([][]byte(nil), m.data[id]...), nil
+}
+
+func (m *MockYDocRepository) AppendUpdate(id string, update []byte, expected int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.appendErr != nil {
+		return false, m.appendErr
+	}
+	if len(m.data[id]) != expected {
+		return false, nil
+	}
+	m.data[id] = append(m.data[id], update)
+	return true, nil
+}
+
+func (m *MockYDocRepository) SetAppendError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.appendErr = err
+}
+
+// Clear는 문서가 지워지는 등 밖에서 ydoc_updates가 없어진 것처럼 로그를 비웁니다.
+func (m *MockYDocRepository) Clear(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, id)
+}
+
+func (m *MockYDocRepository) ReplaceUpdates(id string, updates [][]byte, expected int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.data[id]) != expected {
+		return false, nil
+	}
+	m.data[id] = append([][]byte(nil), updates...)
+	return true, nil
+}
+
+func (m *MockYDocRepository) Updates(id string) [][]byte {
+	updates, _ := m.LoadUpdates(id)
+	return updates
+}
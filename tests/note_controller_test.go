@@ -3,28 +3,46 @@ package tests
 import (
 	"bytes"
 	"encoding/json"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"go-server/controllers"
+	middleware "go-server/middlewares"
 	"go-server/models"
 	"go-server/repository"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 )
 
 func setupNoteApp() *fiber.App {
-	app := fiber.New()
-	var repo repository.NoteRepositoryInterface = NewMockNoteRepository()
+	return setupNoteAppWithRepo(NewMockNoteRepository())
+}
 
+func setupNoteAppWithRepo(repo repository.NoteRepositoryInterface) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if userID := c.Get("X-Test-User"); userID != "" {
+			c.Locals("user", &middleware.CustomClaims{
+				RegisteredClaims: jwt.RegisteredClaims{Subject: userID},
+				UserID:           userID,
+			})
+		}
+		return c.Next()
+	})
 	noteController := controllers.NewNoteController(repo)
 
 	app.Post("/notes", noteController.CreateNote)
 	app.Get("/notes/:id", noteController.GetNoteByID)
 	app.Get("/notes/team/:teamId", noteController.GetNotesByTeamID)
 	app.Put("/notes/:id/title", noteController.UpdateNoteTitle)
+	app.Put("/notes/:id/content", noteController.UpdateNoteContent)
 	app.Delete("/notes/:id", noteController.DeleteNoteByID)
+	app.Get("/notes/:id/revisions", noteController.GetNoteRevisions)
+	app.Get("/notes/:id/revisions/:rev", noteController.GetNoteRevision)
+	app.Post("/notes/:id/revisions/:rev/restore", noteController.RestoreNoteRevision)
 
 	return app
 }
@@ -209,3 +227,227 @@ func TestDeleteNoteByID_Failure(t *testing.T) {
 	_ = json.NewDecoder(resp.Body).Decode(&respBody)
 	assert.Equal(t, "Failed to delete note", respBody["error"])
 }
+
+func TestDeleteNoteByID_DeletesRevisions(t *testing.T) {
+	app := setupNoteApp()
+	id := createTestNote(t, app, models.Note{Title: "New Note", TeamID: "team123", Note: "v1"})
+	updateTestNoteContent(t, app, id, "v2", 1, "user1")
+
+	resp, err := app.Test(httptest.NewRequest("DELETE", "/notes/"+id, nil), -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/notes/"+id+"/revisions", nil), -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var revisions []models.NoteRevision
+	_ = json.NewDecoder(resp.Body).Decode(&revisions)
+	assert.Empty(t, revisions)
+}
+
+func createTestNote(t *testing.T, app *fiber.App, note models.Note) string {
+	body, _ := json.Marshal(note)
+	req := httptest.NewRequest("POST", "/notes", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req, -1)
+	assert.NoError(t, err)
+
+	var respBody map[string]string
+	_ = json.NewDecoder(resp.Body).Decode(&respBody)
+	return respBody["id"]
+}
+
+func TestUpdateNoteContent_Success(t *testing.T) {
+	app := setupNoteApp()
+	id := createTestNote(t, app, models.Note{Title: "New Note", TeamID: "team123", Note: "v1"})
+
+	reqBody := map[string]interface{}{"note": "v2", "version": 1}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("PUT", "/notes/"+id+"/content", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var respBody map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.Equal(t, "success", respBody["status"])
+	assert.Equal(t, float64(2), respBody["version"])
+
+	getResp, _ := app.Test(httptest.NewRequest("GET", "/notes/"+id, nil), -1)
+	var note models.Note
+	_ = json.NewDecoder(getResp.Body).Decode(&note)
+	assert.Equal(t, "v2", note.Note)
+	assert.Equal(t, 2, note.Version)
+}
+
+func TestUpdateNoteContent_Conflict(t *testing.T) {
+	app := setupNoteApp()
+	id := createTestNote(t, app, models.Note{Title: "New Note", TeamID: "team123", Note: "v1"})
+
+	// 첫 번째 저장은 성공하고 버전이 2가 됨
+	first, _ := json.Marshal(map[string]interface{}{"note": "mine", "version": 1})
+	req1 := httptest.NewRequest("PUT", "/notes/"+id+"/content", bytes.NewReader(first))
+	req1.Header.Set("Content-Type", "application/json")
+	app.Test(req1, -1)
+
+	// 같은 버전으로 다시 저장하면 409
+	second, _ := json.Marshal(map[string]interface{}{"note": "theirs", "version": 1})
+	req2 := httptest.NewRequest("PUT", "/notes/"+id+"/content", bytes.NewReader(second))
+	req2.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req2, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+
+	var respBody map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.Equal(t, float64(2), respBody["current_version"])
+
+	getResp, _ := app.Test(httptest.NewRequest("GET", "/notes/"+id, nil), -1)
+	var note models.Note
+	_ = json.NewDecoder(getResp.Body).Decode(&note)
+	assert.Equal(t, "mine", note.Note)
+}
+
+func TestUpdateNoteContent_MissingVersion(t *testing.T) {
+	app := setupNoteApp()
+
+	body, _ := json.Marshal(map[string]string{"note": "content"})
+	req := httptest.NewRequest("PUT", "/notes/1/content", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestUpdateNoteContent_NotFound(t *testing.T) {
+	app := setupNoteApp()
+
+	body, _ := json.Marshal(map[string]interface{}{"note": "content", "version": 1})
+	req := httptest.NewRequest("PUT", "/notes/not-found/content", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func updateTestNoteContent(t *testing.T, app *fiber.App, id, content string, version int, userID string) {
+	body, _ := json.Marshal(map[string]interface{}{"note": content, "version": version})
+	req := httptest.NewRequest("PUT", "/notes/"+id+"/content", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-User", userID)
+
+	resp, err := app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+func TestGetNoteRevisions(t *testing.T) {
+	app := setupNoteApp()
+	id := createTestNote(t, app, models.Note{Title: "New Note", TeamID: "team123", Note: "v1"})
+	updateTestNoteContent(t, app, id, "v2", 1, "alice")
+	updateTestNoteContent(t, app, id, "v3", 2, "bob")
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/notes/"+id+"/revisions", nil), -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var revisions []models.NoteRevision
+	_ = json.NewDecoder(resp.Body).Decode(&revisions)
+	assert.Len(t, revisions, 3)
+	assert.Equal(t, 3, revisions[0].Version)
+	assert.Equal(t, "bob", revisions[0].AuthorID)
+	assert.Empty(t, revisions[0].Note)
+}
+
+func TestGetNoteRevision(t *testing.T) {
+	app := setupNoteApp()
+	id := createTestNote(t, app, models.Note{Title: "New Note", TeamID: "team123", Note: "v1"})
+	updateTestNoteContent(t, app, id, "v2", 1, "alice")
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/notes/"+id+"/revisions/2", nil), -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var revision models.NoteRevision
+	_ = json.NewDecoder(resp.Body).Decode(&revision)
+	assert.Equal(t, "v2", revision.Note)
+	assert.Equal(t, "alice", revision.AuthorID)
+}
+
+func TestGetNoteRevision_NotFound(t *testing.T) {
+	app := setupNoteApp()
+	id := createTestNote(t, app, models.Note{Title: "New Note", TeamID: "team123", Note: "v1"})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/notes/"+id+"/revisions/9", nil), -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/notes/"+id+"/revisions/abc", nil), -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestRestoreNoteRevision(t *testing.T) {
+	app := setupNoteApp()
+	id := createTestNote(t, app, models.Note{Title: "New Note", TeamID: "team123", Note: "v1"})
+	updateTestNoteContent(t, app, id, "v2", 1, "alice")
+
+	req := httptest.NewRequest("POST", "/notes/"+id+"/revisions/1/restore", nil)
+	req.Header.Set("X-Test-User", "bob")
+	resp, err := app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var respBody map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.Equal(t, float64(3), respBody["version"])
+
+	getResp, _ := app.Test(httptest.NewRequest("GET", "/notes/"+id, nil), -1)
+	var note models.Note
+	_ = json.NewDecoder(getResp.Body).Decode(&note)
+	assert.Equal(t, "v1", note.Note)
+	assert.Equal(t, 3, note.Version)
+
+	revResp, _ := app.Test(httptest.NewRequest("GET", "/notes/"+id+"/revisions/3", nil), -1)
+	var revision models.NoteRevision
+	_ = json.NewDecoder(revResp.Body).Decode(&revision)
+	assert.Equal(t, "bob", revision.AuthorID)
+	assert.Equal(t, 1, revision.RestoredFrom)
+}
+
+func TestNoteContentWrites_ConflictWithYDocLog(t *testing.T) {
+	repo := NewMockNoteRepository()
+	docs := NewMockYDocRepository()
+	repo.SetYDoc(docs)
+	app := setupNoteAppWithRepo(repo)
+	id := createTestNote(t, app, models.Note{Title: "New Note", TeamID: "team123", Note: "v1"})
+
+	// 공동 편집으로 Yjs 로그가 쌓인 노트
+	_, err := docs.AppendUpdate(id, []byte{1}, 0)
+	assert.NoError(t, err)
+
+	content, _ := json.Marshal(map[string]interface{}{"note": "rest", "version": 1})
+	overwrite, _ := json.Marshal(models.Note{ID: id, Title: "New Note", TeamID: "team123", Note: "rest"})
+	for _, req := range []*http.Request{
+		httptest.NewRequest("PUT", "/notes/"+id+"/content", bytes.NewReader(content)),
+		httptest.NewRequest("POST", "/notes", bytes.NewReader(overwrite)),
+		httptest.NewRequest("POST", "/notes/"+id+"/revisions/1/restore", nil),
+	} {
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusConflict, resp.StatusCode, req.URL.Path)
+	}
+
+	// 본문도 로그도 그대로
+	note, _ := repo.FindNoteByID(id)
+	assert.Equal(t, "v1", note.Note)
+	assert.Equal(t, 1, note.Version)
+	assert.Equal(t, [][]byte{{1}}, docs.Updates(id))
+}
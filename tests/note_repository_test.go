@@ -0,0 +1,35 @@
+package tests
+
+import (
+	"testing"
+
+	"go-server/repository"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestNoteRepository_DeleteRemovesRevisions(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("delete", func(mt *mtest.T) {
+		id := primitive.NewObjectID()
+		revisions := mt.DB.Collection("note_revisions")
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+
+		repo := repository.NewNoteRepository(mt.Coll, revisions)
+		assert.NoError(mt, repo.DeleteNoteByID(id.Hex()))
+
+		// 리비전을 note_id로 먼저 지우고 노트를 지움
+		first := mt.GetStartedEvent()
+		assert.Equal(mt, "delete", first.CommandName)
+		assert.Equal(mt, "note_revisions", first.Command.Lookup("delete").StringValue())
+		assert.Equal(mt, id.Hex(), first.Command.Lookup("deletes", "0", "q", "note_id").StringValue())
+		assert.Equal(mt, int32(0), first.Command.Lookup("deletes", "0", "limit").Int32())
+
+		second := mt.GetStartedEvent()
+		assert.Equal(mt, mt.Coll.Name(), second.Command.Lookup("delete").StringValue())
+		assert.Equal(mt, id, second.Command.Lookup("deletes", "0", "q", "_id").ObjectID())
+	})
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"go-server/configs"
+	"go-server/controllers"
+	middleware "go-server/middlewares"
+	"go-server/models"
+	"go-server/repository"
+	"go-server/routes"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func participantIDs(t *testing.T, repo *repository.ParticipantRepository, teamID, kind string) []string {
+	t.Helper()
+	participants, err := repo.GetParticipants(context.Background(), teamID, kind)
+	assert.NoError(t, err)
+	ids := []string{}
+	for _, p := range participants {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func TestParticipantPresence_RemovesExpired(t *testing.T) {
+	keys := newTestKeyStore(t)
+	ctx := context.Background()
+	repo := repository.NewParticipantRepository(keys.redisClient, "instance-1")
+
+	assert.NoError(t, repo.AddParticipant(ctx, "team1", models.KindCanvas, "conn-stale", models.Participant{ID: "stale"}))
+	assert.NoError(t, repo.AddParticipant(ctx, "team1", models.KindCanvas, "conn-alive", models.Participant{ID: "alive"}))
+	// stale의 마지막 heartbeat를 1분 전으로
+	keys.redisClient.ZAdd(ctx, "team:team1:canvas:participants:seen", &redis.Z{Score: float64(time.Now().Add(-time.Minute).UnixMilli()), Member: "stale"})
+	assert.NoError(t, repo.TouchParticipant(ctx, "team1", models.KindCanvas, "alive"))
+
+	rooms, err := repo.RemoveExpiredParticipants(ctx, 45*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.ParticipantRoom{{TeamID: "team1", Kind: models.KindCanvas}}, rooms)
+	assert.Equal(t, []string{"alive"}, participantIDs(t, repo, "team1", models.KindCanvas))
+
+	// 두 번째 실행은 바뀐 방이 없음
+	rooms, err = repo.RemoveExpiredParticipants(ctx, 45*time.Second)
+	assert.NoError(t, err)
+	assert.Empty(t, rooms)
+}
+
+func TestParticipantPresence_TouchDoesNotResurrect(t *testing.T) {
+	keys := newTestKeyStore(t)
+	ctx := context.Background()
+	repo := repository.NewParticipantRepository(keys.redisClient, "instance-1")
+
+	assert.NoError(t, repo.AddParticipant(ctx, "team1", models.KindNote, "conn-user1", models.Participant{ID: "user1"}))
+	assert.NoError(t, repo.RemoveParticipant(ctx, "team1", models.KindNote, "user1"))
+	assert.NoError(t, repo.TouchParticipant(ctx, "team1", models.KindNote, "user1"))
+
+	exists, err := keys.redisClient.Exists(ctx, "team:team1:note:participants:seen").Result()
+	assert.NoError(t, err)
+	assert.Zero(t, exists)
+
+	// 비어 있는 방은 정리 대상 색인에서도 빠짐
+	_, err = repo.RemoveExpiredParticipants(ctx, time.Minute)
+	assert.NoError(t, err)
+	indexed, err := keys.redisClient.SIsMember(ctx, "participants:rooms", "team:team1:note:participants").Result()
+	assert.NoError(t, err)
+	assert.False(t, indexed)
+}
+
+func TestParticipantPresence_ReconcilesOwnedEntries(t *testing.T) {
+	keys := newTestKeyStore(t)
+	ctx := context.Background()
+	crashed := repository.NewParticipantRepository(keys.redisClient, "instance-1")
+	other := repository.NewParticipantRepository(keys.redisClient, "instance-2")
+
+	assert.NoError(t, crashed.AddParticipant(ctx, "team1", models.KindCanvas, "conn-ghost", models.Participant{ID: "ghost"}))
+	assert.NoError(t, other.AddParticipant(ctx, "team1", models.KindCanvas, "conn-live", models.Participant{ID: "live"}))
+
+	// 같은 INSTANCE_ID로 재시작한 프로세스
+	restarted := repository.NewParticipantRepository(keys.redisClient, "instance-1")
+	rooms, err := restarted.RemoveOwnedParticipants(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.ParticipantRoom{{TeamID: "team1", Kind: models.KindCanvas}}, rooms)
+	assert.Equal(t, []string{"live"}, participantIDs(t, restarted, "team1", models.KindCanvas))
+}
+
+func TestParticipantPresence_ReapsLegacyEntries(t *testing.T) {
+	keys := newTestKeyStore(t)
+	ctx := context.Background()
+	repo := repository.NewParticipantRepository(keys.redisClient, "instance-1")
+
+	// heartbeat 기록도 방 색인도 없는, 이 기능 이전에 남은 항목
+	keys.redisClient.HSet(ctx, "team:team1:audio:participants", "ghost", `{"id":"ghost"}`)
+
+	_, err := repo.RemoveOwnedParticipants(ctx)
+	assert.NoError(t, err)
+	rooms, err := repo.RemoveExpiredParticipants(ctx, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.ParticipantRoom{{TeamID: "team1", Kind: models.KindAudio}}, rooms)
+	assert.Empty(t, participantIDs(t, repo, "team1", models.KindAudio))
+}
+
+func TestParticipantPresence_ReaperBroadcastsAndHeartbeatKeepsAlive(t *testing.T) {
+	keys := newTestKeyStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := repository.NewParticipantRepository(keys.redisClient, "instance-1")
+	membership := middleware.ClaimsTeamMembership{}
+	participantsController := controllers.NewParticipantsController(repo, middleware.DefaultPermissionPolicy(), membership)
+	participantsController.SetPresence(configs.PresenceConfig{
+		HeartbeatInterval: 50 * time.Millisecond,
+		TTL:               300 * time.Millisecond,
+		ReapInterval:      50 * time.Millisecond,
+	})
+	signalingController := controllers.NewWebSocketController(nil, membership)
+
+	app := fiber.New()
+	routes.WebSocketRoutes(app, participantsController, controllers.NewMediaSocketController(membership), signalingController, keys.store, nil, middleware.NewSessionRegistry())
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go app.Listener(ln)
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	// 다른 인스턴스가 죽으며 남긴 참가자
+	ghosts := repository.NewParticipantRepository(keys.redisClient, "instance-2")
+	assert.NoError(t, ghosts.AddParticipant(ctx, "team1", models.KindCanvas, "conn-ghost", models.Participant{ID: "ghost"}))
+
+	conn := dialAs(t, keys, "ws://"+ln.Addr().String()+"/ws", "user-a")
+	assert.NoError(t, conn.WriteJSON(map[string]string{"action": "addParticipant", "team_id": "team1", "kind": "canvas"}))
+	assert.ElementsMatch(t, []string{"ghost", "user-a"}, readParticipantIDs(t, conn))
+
+	go participantsController.RunPresenceReaper(ctx)
+
+	// 읽는 동안 클라이언트가 pong을 보내므로 user-a는 남고 ghost만 지워짐
+	assert.Equal(t, []string{"user-a"}, readParticipantIDs(t, conn))
+
+	deadline := time.Now().Add(600 * time.Millisecond)
+	_ = conn.SetReadDeadline(deadline)
+	for time.Now().Before(deadline) {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+	assert.Equal(t, []string{"user-a"}, participantIDs(t, repo, "team1", models.KindCanvas))
+}
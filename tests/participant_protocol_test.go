@@ -0,0 +1,209 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-server/controllers"
+	middleware "go-server/middlewares"
+	"go-server/models"
+	"go-server/routes"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func readProtocolMessage(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg map[string]interface{}
+	if !assert.NoError(t, conn.ReadJSON(&msg)) {
+		t.FailNow()
+	}
+	return msg
+}
+
+func TestParticipantProtocol_AckAndVersionedBroadcast(t *testing.T) {
+	keys, _, url := setupParticipantSessionsApp(t)
+	conn := dialAs(t, keys, url, "user-a")
+
+	assert.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"v":         models.ParticipantProtocolVersion,
+		"action":    models.ActionAddParticipant,
+		"requestId": "req-1",
+		"payload":   map[string]string{"team_id": "team1", "kind": "canvas", "color": "red"},
+	}))
+
+	ack := readProtocolMessage(t, conn)
+	assert.Equal(t, map[string]interface{}{"v": float64(1), "action": "ack", "requestId": "req-1", "for": "addParticipant"}, ack)
+
+	update := readProtocolMessage(t, conn)
+	assert.Equal(t, float64(1), update["v"])
+	assert.Equal(t, "updateParticipants", update["action"])
+	assert.Len(t, update["participants"], 1)
+
+	// get 응답은 요청 ID를 그대로 돌려줌
+	assert.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"v":         1,
+		"action":    models.ActionGetParticipants,
+		"requestId": "req-2",
+		"payload":   map[string]string{"team_id": "team1", "kind": "canvas"},
+	}))
+	get := readProtocolMessage(t, conn)
+	assert.Equal(t, "getParticipants", get["action"])
+	assert.Equal(t, "req-2", get["requestId"])
+	assert.Len(t, get["participants"], 1)
+}
+
+func TestParticipantProtocol_StructuredErrors(t *testing.T) {
+	keys, _, url := setupParticipantSessionsApp(t)
+	conn := dialAs(t, keys, url, "user-a")
+
+	cases := []struct {
+		name    string
+		message string
+		code    string
+		request string
+		action  string
+	}{
+		{"malformed", `{"action":`, models.ErrorCodeInvalidMessage, "", ""},
+		{"newer version", `{"v":99,"action":"addParticipant","requestId":"r1","payload":{}}`, models.ErrorCodeUnsupportedVersion, "r1", "addParticipant"},
+		{"unknown action", `{"v":1,"action":"dance","requestId":"r2","payload":{}}`, models.ErrorCodeUnknownAction, "r2", "dance"},
+		{"missing team", `{"v":1,"action":"getParticipants","requestId":"r3","payload":{"kind":"canvas"}}`, models.ErrorCodeInvalidMessage, "r3", "getParticipants"},
+		{"invalid kind", `{"v":1,"action":"addParticipant","requestId":"r4","payload":{"team_id":"team1","kind":"video"}}`, models.ErrorCodeInvalidMessage, "r4", "addParticipant"},
+		{"payload type", `{"v":1,"action":"removeParticipant","requestId":"r5","payload":{"team_id":1}}`, models.ErrorCodeInvalidMessage, "r5", "removeParticipant"},
+		{"forbidden team", `{"v":1,"action":"addParticipant","requestId":"r6","payload":{"team_id":"team2","kind":"canvas"}}`, models.ErrorCodeForbidden, "r6", "addParticipant"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(tc.message)))
+			msg := readProtocolMessage(t, conn)
+			assert.Equal(t, "error", msg["action"])
+			assert.Equal(t, tc.code, msg["code"])
+			assert.NotEmpty(t, msg["error"])
+			if tc.request != "" {
+				assert.Equal(t, tc.request, msg["requestId"])
+				assert.Equal(t, tc.action, msg["for"])
+			}
+		})
+	}
+}
+
+func TestParticipantProtocol_LegacyFlatMessages(t *testing.T) {
+	keys, _, url := setupParticipantSessionsApp(t)
+	conn := dialAs(t, keys, url, "user-a")
+
+	// "v"와 payload 없이 보내던 이전 클라이언트는 ack 없이 브로드캐스트만 받음
+	assert.NoError(t, conn.WriteJSON(map[string]string{"action": "addAudioParticipant", "team_id": "team1", "kind": "audio"}))
+	msg := readProtocolMessage(t, conn)
+	assert.Equal(t, "updateAudioParticipants", msg["action"])
+	assert.Len(t, msg["audioParticipants"], 1)
+
+	assert.NoError(t, conn.WriteJSON(map[string]string{"action": "removeAudioParticipant", "team_id": "team1", "kind": "audio", "participant": "user-a"}))
+	msg = readProtocolMessage(t, conn)
+	assert.Equal(t, "updateAudioParticipants", msg["action"])
+	assert.Equal(t, []interface{}{}, msg["audioParticipants"])
+}
+
+func TestParticipantProtocol_SchemaCoversGoTypes(t *testing.T) {
+	keys := newTestKeyStore(t)
+	membership := middleware.ClaimsTeamMembership{}
+	app := fiber.New()
+	routes.WebSocketRoutes(app, controllers.NewParticipantsController(NewMockParticipantRepository(), nil, membership), controllers.NewMediaSocketController(membership), controllers.NewWebSocketController(nil, membership), keys.store, nil, middleware.NewSessionRegistry())
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/ws/schema.json", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var schema struct {
+		Version float64                    `json:"x-protocol-version"`
+		Defs    map[string]json.RawMessage `json:"$defs"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&schema))
+	assert.Equal(t, float64(models.ParticipantProtocolVersion), schema.Version)
+
+	var client struct {
+		OneOf []struct {
+			Properties struct {
+				Action  struct{ Const string }
+				Payload struct {
+					Ref string `json:"$ref"`
+				}
+			}
+		}
+	}
+	assert.NoError(t, json.Unmarshal(schema.Defs["ClientMessage"], &client))
+	assert.Len(t, client.OneOf, len(models.ParticipantClientPayloads))
+	for _, variant := range client.OneOf {
+		assert.Contains(t, models.ParticipantClientPayloads, variant.Properties.Action.Const)
+		assert.NotEmpty(t, variant.Properties.Payload.Ref)
+	}
+
+	var join struct {
+		Properties map[string]interface{}
+		Required   []string
+	}
+	assert.NoError(t, json.Unmarshal(schema.Defs["ParticipantJoinPayload"], &join))
+	assert.ElementsMatch(t, []string{"team_id", "kind"}, join.Required)
+	assert.Contains(t, join.Properties, "profilePicture")
+
+	for _, name := range []string{"ServerMessage", "ParticipantsMessage", "AudioParticipantsMessage", "ParticipantAck", "ParticipantError", "Participant"} {
+		assert.Contains(t, schema.Defs, name)
+	}
+}
+
+// dialWithClaims: claims로 서명한 토큰으로 url에 연결합니다.
+func dialWithClaims(t *testing.T, keys *testKeyStore, url string, claims middleware.CustomClaims) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?token="+keys.sign(t, claims), nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestParticipantProtocol_OwnerCannotKickFromOtherTeam(t *testing.T) {
+	keys, repo, url := setupParticipantSessionsApp(t)
+	victim := dialWithClaims(t, keys, url, middleware.CustomClaims{UserID: "user-b", Teams: []string{"team2"}})
+	assert.NoError(t, victim.WriteJSON(map[string]interface{}{
+		"v": 1, "action": models.ActionAddParticipant, "requestId": "join",
+		"payload": map[string]string{"team_id": "team2", "kind": "canvas"},
+	}))
+	assert.Equal(t, "ack", readProtocolMessage(t, victim)["action"])
+	assert.Equal(t, "updateParticipants", readProtocolMessage(t, victim)["action"])
+
+	// team1의 owner가 team2 방에서 내보내려 함
+	owner := dialWithClaims(t, keys, url, middleware.CustomClaims{UserID: "user-a", Role: middleware.RoleOwner, Teams: []string{"team1"}})
+	assert.NoError(t, owner.WriteJSON(map[string]interface{}{
+		"v": 1, "action": models.ActionRemoveParticipant, "requestId": "kick",
+		"payload": map[string]string{"team_id": "team2", "kind": "canvas", "participant": "user-b"},
+	}))
+	msg := readProtocolMessage(t, owner)
+	assert.Equal(t, "error", msg["action"])
+	assert.Equal(t, models.ErrorCodeForbidden, msg["code"])
+	assert.Equal(t, "kick", msg["requestId"])
+
+	assert.Equal(t, []string{"user-b"}, participantIDs(t, repo, "team2", models.KindCanvas))
+}
+
+func TestParticipantProtocol_NonMemberCannotListParticipants(t *testing.T) {
+	keys, repo, url := setupParticipantSessionsApp(t)
+	assert.NoError(t, repo.AddParticipant(context.Background(), "team2", models.KindCanvas, "tab-1", models.Participant{ID: "user-b"}))
+
+	conn := dialAs(t, keys, url, "user-a")
+	for _, action := range []string{models.ActionGetParticipants, models.ActionGetAudioParticipants} {
+		assert.NoError(t, conn.WriteJSON(map[string]interface{}{
+			"v": 1, "action": action, "requestId": action,
+			"payload": map[string]string{"team_id": "team2", "kind": "canvas"},
+		}))
+		msg := readProtocolMessage(t, conn)
+		assert.Equal(t, "error", msg["action"], action)
+		assert.Equal(t, models.ErrorCodeForbidden, msg["code"], action)
+		assert.Nil(t, msg["participants"], action)
+	}
+}
@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"go-server/configs"
+	"go-server/controllers"
+	middleware "go-server/middlewares"
+	"go-server/models"
+	"go-server/repository"
+	"go-server/routes"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParticipantSessions_LastConnectionRemoves(t *testing.T) {
+	keys := newTestKeyStore(t)
+	ctx := context.Background()
+	repo := repository.NewParticipantRepository(keys.redisClient, "instance-1")
+
+	assert.NoError(t, repo.AddParticipant(ctx, "team1", models.KindCanvas, "tab-1", models.Participant{ID: "user1"}))
+	assert.NoError(t, repo.AddParticipant(ctx, "team1", models.KindCanvas, "tab-2", models.Participant{ID: "user1"}))
+	// 같은 연결로 다시 들어와도 참조는 늘지 않음
+	assert.NoError(t, repo.AddParticipant(ctx, "team1", models.KindCanvas, "tab-2", models.Participant{ID: "user1", Color: "red"}))
+
+	removed, err := repo.LeaveParticipant(ctx, "team1", models.KindCanvas, "tab-1")
+	assert.NoError(t, err)
+	assert.False(t, removed)
+	assert.Equal(t, []string{"user1"}, participantIDs(t, repo, "team1", models.KindCanvas))
+
+	removed, err = repo.LeaveParticipant(ctx, "team1", models.KindCanvas, "tab-2")
+	assert.NoError(t, err)
+	assert.True(t, removed)
+	assert.Empty(t, participantIDs(t, repo, "team1", models.KindCanvas))
+
+	// 이미 놓은 연결은 아무것도 바꾸지 않음
+	removed, err = repo.LeaveParticipant(ctx, "team1", models.KindCanvas, "tab-2")
+	assert.NoError(t, err)
+	assert.False(t, removed)
+}
+
+func TestParticipantSessions_KickRemovesAllConnections(t *testing.T) {
+	keys := newTestKeyStore(t)
+	ctx := context.Background()
+	repo := repository.NewParticipantRepository(keys.redisClient, "instance-1")
+
+	assert.NoError(t, repo.AddParticipant(ctx, "team1", models.KindNote, "tab-1", models.Participant{ID: "user1"}))
+	assert.NoError(t, repo.AddParticipant(ctx, "team1", models.KindNote, "tab-2", models.Participant{ID: "user1"}))
+	assert.NoError(t, repo.RemoveParticipant(ctx, "team1", models.KindNote, "user1"))
+	assert.Empty(t, participantIDs(t, repo, "team1", models.KindNote))
+
+	conns, err := keys.redisClient.HLen(ctx, "team:team1:note:participants:conns").Result()
+	assert.NoError(t, err)
+	assert.Zero(t, conns)
+
+	// 내보낸 뒤 남은 탭이 끊겨도 다시 지울 것이 없음
+	removed, err := repo.LeaveParticipant(ctx, "team1", models.KindNote, "tab-1")
+	assert.NoError(t, err)
+	assert.False(t, removed)
+}
+
+func TestParticipantSessions_ReconcileKeepsConnectionsOnOtherInstances(t *testing.T) {
+	keys := newTestKeyStore(t)
+	ctx := context.Background()
+	crashed := repository.NewParticipantRepository(keys.redisClient, "instance-1")
+	other := repository.NewParticipantRepository(keys.redisClient, "instance-2")
+
+	// user1은 두 인스턴스에 탭을 하나씩, ghost는 죽은 인스턴스에만
+	assert.NoError(t, crashed.AddParticipant(ctx, "team1", models.KindCanvas, "tab-1", models.Participant{ID: "user1"}))
+	assert.NoError(t, other.AddParticipant(ctx, "team1", models.KindCanvas, "tab-2", models.Participant{ID: "user1"}))
+	assert.NoError(t, crashed.AddParticipant(ctx, "team1", models.KindCanvas, "tab-3", models.Participant{ID: "ghost"}))
+
+	rooms, err := repository.NewParticipantRepository(keys.redisClient, "instance-1").RemoveOwnedParticipants(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.ParticipantRoom{{TeamID: "team1", Kind: models.KindCanvas}}, rooms)
+	assert.Equal(t, []string{"user1"}, participantIDs(t, other, "team1", models.KindCanvas))
+
+	removed, err := other.LeaveParticipant(ctx, "team1", models.KindCanvas, "tab-2")
+	assert.NoError(t, err)
+	assert.True(t, removed)
+}
+
+func TestParticipantSessions_RestartWithSameInstanceIDReconciles(t *testing.T) {
+	t.Setenv("INSTANCE_ID", "pod-0")
+	keys := newTestKeyStore(t)
+	ctx := context.Background()
+
+	// 이전 프로세스가 연결을 남긴 채 죽음
+	crashed := repository.NewParticipantRepository(keys.redisClient, configs.LoadInstanceID())
+	assert.NoError(t, crashed.AddParticipant(ctx, "team1", models.KindCanvas, "tab-1", models.Participant{ID: "ghost"}))
+	other := repository.NewParticipantRepository(keys.redisClient, "pod-1")
+	assert.NoError(t, other.AddParticipant(ctx, "team1", models.KindCanvas, "tab-2", models.Participant{ID: "user2"}))
+
+	// 같은 INSTANCE_ID로 다시 시작하면 남은 연결을 정리함
+	restarted := repository.NewParticipantRepository(keys.redisClient, configs.LoadInstanceID())
+	membership := middleware.ClaimsTeamMembership{}
+	controller := controllers.NewParticipantsController(restarted, middleware.DefaultPermissionPolicy(), membership)
+	assert.NoError(t, controller.ReconcileParticipants(ctx))
+	assert.Equal(t, []string{"user2"}, participantIDs(t, other, "team1", models.KindCanvas))
+
+	// fan-out에서 자기 메시지를 거르는 ID는 프로세스마다 다름
+	assert.NotEqual(t, configs.NewProcessID("pod-0"), configs.NewProcessID("pod-0"))
+}
+
+func TestParticipantSessions_InstanceIDDefaultsToHostname(t *testing.T) {
+	t.Setenv("INSTANCE_ID", "")
+	hostname, err := os.Hostname()
+	assert.NoError(t, err)
+	assert.Equal(t, hostname, configs.LoadInstanceID())
+	assert.Equal(t, configs.LoadInstanceID(), configs.LoadInstanceID())
+}
+
+func setupParticipantSessionsApp(t *testing.T) (*testKeyStore, *repository.ParticipantRepository, string) {
+	t.Helper()
+	keys := newTestKeyStore(t)
+	repo := repository.NewParticipantRepository(keys.redisClient, "instance-1")
+	membership := middleware.ClaimsTeamMembership{}
+	participantsController := controllers.NewParticipantsController(repo, middleware.DefaultPermissionPolicy(), membership)
+
+	app := fiber.New()
+	routes.WebSocketRoutes(app, participantsController, controllers.NewMediaSocketController(membership), controllers.NewWebSocketController(nil, membership), keys.store, nil, middleware.NewSessionRegistry())
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go app.Listener(ln)
+	t.Cleanup(func() { _ = app.Shutdown() })
+	return keys, repo, "ws://" + ln.Addr().String() + "/ws"
+}
+
+func TestParticipantSessions_SecondTabKeepsUserInTeam(t *testing.T) {
+	keys, repo, url := setupParticipantSessionsApp(t)
+	join := map[string]string{"action": "addParticipant", "team_id": "team1", "kind": "canvas"}
+
+	tab1 := dialAs(t, keys, url, "user-a")
+	assert.NoError(t, tab1.WriteJSON(join))
+	assert.Equal(t, []string{"user-a"}, readParticipantIDs(t, tab1))
+
+	tab2 := dialAs(t, keys, url, "user-a")
+	assert.NoError(t, tab2.WriteJSON(join))
+	assert.Equal(t, []string{"user-a"}, readParticipantIDs(t, tab2))
+	assert.Equal(t, []string{"user-a"}, readParticipantIDs(t, tab1))
+
+	assert.NoError(t, tab1.Close())
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, []string{"user-a"}, participantIDs(t, repo, "team1", models.KindCanvas))
+
+	assert.NoError(t, tab2.Close())
+	assert.Eventually(t, func() bool {
+		return len(participantIDs(t, repo, "team1", models.KindCanvas)) == 0
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestParticipantSessions_OneConnectionInSeveralRooms(t *testing.T) {
+	keys, repo, url := setupParticipantSessionsApp(t)
+
+	conn := dialAs(t, keys, url, "user-a")
+	assert.NoError(t, conn.WriteJSON(map[string]string{"action": "addParticipant", "team_id": "team1", "kind": "canvas"}))
+	assert.Equal(t, []string{"user-a"}, readParticipantIDs(t, conn))
+	assert.NoError(t, conn.WriteJSON(map[string]string{"action": "addAudioParticipant", "team_id": "team1", "kind": "audio"}))
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var audio map[string]interface{}
+	assert.NoError(t, conn.ReadJSON(&audio))
+	assert.Equal(t, "updateAudioParticipants", audio["action"])
+
+	observer := dialAs(t, keys, url, "user-b")
+	assert.NoError(t, observer.WriteJSON(map[string]string{"action": "addParticipant", "team_id": "team1", "kind": "canvas"}))
+	assert.ElementsMatch(t, []string{"user-a", "user-b"}, readParticipantIDs(t, observer))
+
+	assert.NoError(t, conn.Close())
+	// 연결이 끊기면 두 방 모두에서 빠짐
+	assert.Equal(t, []string{"user-b"}, readParticipantIDs(t, observer))
+	assert.Empty(t, participantIDs(t, repo, "team1", models.KindAudio))
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	middleware "go-server/middlewares"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupPermissionApp(policy middleware.PermissionPolicy) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &middleware.CustomClaims{UserID: "user1", Role: c.Get("X-Test-Role")})
+		return c.Next()
+	})
+
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Get("/notes/:id", middleware.RequirePermission(policy, middleware.PermissionRead), ok)
+	app.Put("/notes/:id", middleware.RequirePermission(policy, middleware.PermissionWrite), ok)
+	app.Delete("/notes/:id", middleware.RequirePermission(policy, middleware.PermissionDelete), ok)
+	return app
+}
+
+func TestRequirePermission_DefaultPolicy(t *testing.T) {
+	app := setupPermissionApp(middleware.DefaultPermissionPolicy())
+
+	cases := []struct {
+		role   string
+		method string
+		status int
+	}{
+		{"viewer", "GET", fiber.StatusOK},
+		{"viewer", "PUT", fiber.StatusForbidden},
+		{"viewer", "DELETE", fiber.StatusForbidden},
+		{"editor", "PUT", fiber.StatusOK},
+		{"editor", "DELETE", fiber.StatusForbidden},
+		{"owner", "DELETE", fiber.StatusOK},
+		{"ROLE_OWNER", "DELETE", fiber.StatusOK},
+		{"", "GET", fiber.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, "/notes/1", nil)
+		req.Header.Set("X-Test-Role", tc.role)
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.status, resp.StatusCode, tc.role+" "+tc.method)
+	}
+}
+
+func TestRequirePermission_CustomPolicy(t *testing.T) {
+	app := setupPermissionApp(middleware.PermissionPolicy{
+		"member": {middleware.PermissionRead, middleware.PermissionWrite},
+	})
+
+	req := httptest.NewRequest("PUT", "/notes/1", nil)
+	req.Header.Set("X-Test-Role", "member")
+	resp, err := app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	req = httptest.NewRequest("GET", "/notes/1", nil)
+	req.Header.Set("X-Test-Role", "owner")
+	resp, err = app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+}
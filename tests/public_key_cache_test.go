@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	pb "go-server/pkg/keyrotation"
+	"go-server/server"
+	"go-server/utils"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func counterValue(t *testing.T, name string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() == name {
+			var total float64
+			for _, metric := range family.GetMetric() {
+				total += metric.GetCounter().GetValue()
+			}
+			return total
+		}
+	}
+	return 0
+}
+
+func TestPublicKeyStore_CacheServesRepeatedLookups(t *testing.T) {
+	store, _, redisClient := newRetentionStore(t, utils.DefaultKeyRetention())
+	store.SetCacheTTL(time.Minute)
+	ctx := context.Background()
+	assert.NoError(t, store.AddOrUpdateKey(ctx, "kid1", newRSAPublicKeyPEM(t), "", time.Now()))
+
+	hits := counterValue(t, "public_key_cache_hits_total")
+	misses := counterValue(t, "public_key_cache_misses_total")
+
+	_, err := store.GetKey(ctx, "kid1")
+	assert.NoError(t, err)
+
+	// Redis에서 직접 지워도 캐시에서 응답
+	assert.NoError(t, redisClient.Del(ctx, "kid1").Err())
+	_, err = store.GetKey(ctx, "kid1")
+	assert.NoError(t, err)
+
+	assert.Equal(t, misses+1, counterValue(t, "public_key_cache_misses_total"))
+	assert.Equal(t, hits+1, counterValue(t, "public_key_cache_hits_total"))
+}
+
+func TestPublicKeyStore_CacheEntryExpires(t *testing.T) {
+	store, _, redisClient := newRetentionStore(t, utils.DefaultKeyRetention())
+	store.SetCacheTTL(50 * time.Millisecond)
+	ctx := context.Background()
+	assert.NoError(t, store.AddOrUpdateKey(ctx, "kid1", newRSAPublicKeyPEM(t), "", time.Now()))
+
+	_, err := store.GetKey(ctx, "kid1")
+	assert.NoError(t, err)
+	assert.NoError(t, redisClient.Del(ctx, "kid1").Err())
+
+	time.Sleep(100 * time.Millisecond)
+	_, err = store.GetKey(ctx, "kid1")
+	assert.Error(t, err)
+}
+
+func TestPublicKeyStore_NotifyKeyRolledInvalidatesCache(t *testing.T) {
+	store, _, _ := newRetentionStore(t, utils.DefaultKeyRetention())
+	store.SetCacheTTL(time.Hour)
+	ctx := context.Background()
+	assert.NoError(t, store.AddOrUpdateKey(ctx, "kid1", newRSAPublicKeyPEM(t), "", time.Now()))
+
+	before, err := store.GetKey(ctx, "kid1")
+	assert.NoError(t, err)
+
+	// 같은 kid로 다른 키가 들어오면 캐시된 이전 값은 버려야 함
+	rotation := server.NewKeyRotationNotifyServer(store, nil)
+	_, err = rotation.NotifyKeyRolled(ctx, &pb.NotifyKeyRolledRequest{
+		CurrentKid:          "kid1",
+		CurrentPublicKeyPem: newRSAPublicKeyPEM(t),
+	})
+	assert.NoError(t, err)
+
+	after, err := store.GetKey(ctx, "kid1")
+	if assert.NoError(t, err) {
+		assert.NotEqual(t, before.Key.(*rsa.PublicKey).N, after.Key.(*rsa.PublicKey).N)
+	}
+}
+
+func TestPublicKeyStore_CacheInvalidatedAcrossReplicas(t *testing.T) {
+	retention := utils.DefaultKeyRetention()
+	store, _, redisClient := newRetentionStore(t, retention)
+	store.SetCacheTTL(time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, store.SubscribeInvalidations(ctx))
+
+	assert.NoError(t, store.AddOrUpdateKey(ctx, "kid1", newRSAPublicKeyPEM(t), "", time.Now()))
+	_, err := store.GetKey(ctx, "kid1")
+	assert.NoError(t, err)
+
+	// 다른 레플리카가 키를 제거
+	other := utils.NewPublicKeyStore(redisClient, retention)
+	assert.NoError(t, other.RemoveKey(ctx, "kid1"))
+
+	assert.Eventually(t, func() bool {
+		_, err := store.GetKey(ctx, "kid1")
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"go-server/utils"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRSAPublicKeyPEM(t *testing.T) string {
+	t.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PUBLIC KEY",
+		Bytes: x509.MarshalPKCS1PublicKey(&privateKey.PublicKey),
+	}))
+}
+
+func newRetentionStore(t *testing.T, retention utils.KeyRetention) (*utils.PublicKeyStore, *miniredis.Miniredis, *redis.Client) {
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = redisClient.Close() })
+	return utils.NewPublicKeyStore(redisClient, retention), mr, redisClient
+}
+
+func TestPublicKeyStore_PreviousKeyValidDuringGracePeriod(t *testing.T) {
+	store, mr, _ := newRetentionStore(t, utils.KeyRetention{GracePeriod: 10 * time.Minute, MaxPreviousKeys: 2})
+	ctx := context.Background()
+
+	assert.NoError(t, store.AddOrUpdateKey(ctx, "kid1", newRSAPublicKeyPEM(t), "", time.Now()))
+	assert.NoError(t, store.AddOrUpdateKey(ctx, "kid2", newRSAPublicKeyPEM(t), "", time.Now()))
+
+	// 교체 직후에도 이전 키로 검증 가능
+	_, err := store.GetKey(ctx, "kid1")
+	assert.NoError(t, err)
+
+	keys, err := store.ListKeys(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, keys, 2) {
+		assert.Equal(t, "kid1", keys[0].Kid)
+		assert.False(t, keys[0].ExpiresAt.IsZero())
+		assert.Equal(t, "kid2", keys[1].Kid)
+		assert.True(t, keys[1].ExpiresAt.IsZero())
+	}
+
+	// 유예 기간이 지나면 이전 키는 사라지고 현재 키만 남음
+	mr.FastForward(11 * time.Minute)
+	_, err = store.GetKey(ctx, "kid1")
+	assert.Error(t, err)
+	_, err = store.GetKey(ctx, "kid2")
+	assert.NoError(t, err)
+
+	keys, err = store.ListKeys(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+}
+
+func TestPublicKeyStore_RolledAtDrivesExpiry(t *testing.T) {
+	store, _, _ := newRetentionStore(t, utils.KeyRetention{GracePeriod: 10 * time.Minute})
+	ctx := context.Background()
+
+	assert.NoError(t, store.AddOrUpdateKey(ctx, "kid1", newRSAPublicKeyPEM(t), "", time.Now()))
+	// 교체가 이미 한참 전에 일어났다면 이전 키는 바로 제거
+	assert.NoError(t, store.AddOrUpdateKey(ctx, "kid2", newRSAPublicKeyPEM(t), "", time.Now().Add(-time.Hour)))
+
+	_, err := store.GetKey(ctx, "kid1")
+	assert.Error(t, err)
+}
+
+func TestPublicKeyStore_MaxPreviousKeys(t *testing.T) {
+	store, _, _ := newRetentionStore(t, utils.KeyRetention{GracePeriod: time.Hour, MaxPreviousKeys: 1})
+	ctx := context.Background()
+
+	for _, kid := range []string{"kid1", "kid2", "kid3"} {
+		assert.NoError(t, store.AddOrUpdateKey(ctx, kid, newRSAPublicKeyPEM(t), "", time.Now()))
+	}
+
+	_, err := store.GetKey(ctx, "kid1")
+	assert.Error(t, err)
+	_, err = store.GetKey(ctx, "kid2")
+	assert.NoError(t, err)
+	_, err = store.GetKey(ctx, "kid3")
+	assert.NoError(t, err)
+}
+
+func TestPublicKeyStore_StateSharedAcrossInstances(t *testing.T) {
+	retention := utils.KeyRetention{GracePeriod: time.Hour, MaxPreviousKeys: 2}
+	store, _, redisClient := newRetentionStore(t, retention)
+	ctx := context.Background()
+
+	assert.NoError(t, store.AddOrUpdateKey(ctx, "kid1", newRSAPublicKeyPEM(t), "", time.Now()))
+
+	// 재시작했거나 다른 레플리카가 다음 교체를 받은 경우
+	other := utils.NewPublicKeyStore(redisClient, retention)
+	assert.NoError(t, other.AddOrUpdateKey(ctx, "kid2", newRSAPublicKeyPEM(t), "", time.Now()))
+
+	keys, err := store.ListKeys(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, keys, 2) {
+		assert.False(t, keys[0].ExpiresAt.IsZero(), "kid1 should be retired")
+	}
+}
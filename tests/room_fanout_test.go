@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"go-server/controllers"
+	middleware "go-server/middlewares"
+	"go-server/repository"
+	"go-server/routes"
+	"go-server/utils"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// startFanoutInstance는 같은 Redis를 쓰는 레플리카 하나를 띄우고 ws:// 주소를 돌려줍니다.
+func startFanoutInstance(t *testing.T, keys *testKeyStore, instanceID string) string {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	fanout := utils.NewRoomFanout(keys.redisClient, instanceID)
+	membership := middleware.ClaimsTeamMembership{}
+
+	participantsController := controllers.NewParticipantsController(repository.NewParticipantRepository(keys.redisClient, instanceID), middleware.DefaultPermissionPolicy(), membership)
+	participantsController.SetFanout(fanout)
+	signalingController := controllers.NewWebSocketController(controllers.NewDocumentTeamResolver(NewMockNoteRepository(), NewMockCanvasRepository()), membership)
+	signalingController.SetFanout(fanout)
+	assert.NoError(t, fanout.Subscribe(ctx))
+
+	app := fiber.New()
+	routes.WebSocketRoutes(app, participantsController, controllers.NewMediaSocketController(membership), signalingController, keys.store, nil, middleware.NewSessionRegistry())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go app.Listener(ln)
+	t.Cleanup(func() { _ = app.Shutdown() })
+	return "ws://" + ln.Addr().String()
+}
+
+func dialAs(t *testing.T, keys *testKeyStore, url, userID string) *websocket.Conn {
+	t.Helper()
+	token := keys.sign(t, middleware.CustomClaims{UserID: userID, Username: userID, Teams: []string{"team1"}})
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?token="+token, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func readParticipantIDs(t *testing.T, conn *websocket.Conn) []string {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var response struct {
+		Action       string `json:"action"`
+		Participants []struct {
+			ID string `json:"id"`
+		} `json:"participants"`
+	}
+	if !assert.NoError(t, conn.ReadJSON(&response)) {
+		t.FailNow()
+	}
+	assert.Equal(t, "updateParticipants", response.Action)
+	var ids []string
+	for _, p := range response.Participants {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func TestRoomFanout_SkipsOwnMessages(t *testing.T) {
+	keys := newTestKeyStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	received := map[string][]string{}
+	newInstance := func(id string) *utils.RoomFanout {
+		f := utils.NewRoomFanout(keys.redisClient, id)
+		f.Register("test", func(room string, payload []byte) {
+			mu.Lock()
+			defer mu.Unlock()
+			received[id] = append(received[id], room+"="+string(payload))
+		})
+		assert.NoError(t, f.Subscribe(ctx))
+		return f
+	}
+	a := newInstance("a")
+	newInstance("b")
+
+	assert.NoError(t, a.Publish(ctx, "test", "team1:canvas", []byte("hello")))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received["b"]) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"team1:canvas=hello"}, received["b"])
+	assert.Empty(t, received["a"])
+}
+
+func TestRoomFanout_ParticipantsAcrossInstances(t *testing.T) {
+	keys := newTestKeyStore(t)
+	url1 := startFanoutInstance(t, keys, "instance-1")
+	url2 := startFanoutInstance(t, keys, "instance-2")
+
+	join := func(conn *websocket.Conn) {
+		assert.NoError(t, conn.WriteJSON(map[string]string{"action": "addParticipant", "team_id": "team1", "kind": "canvas"}))
+	}
+
+	a := dialAs(t, keys, url1+"/ws", "user-a")
+	join(a)
+	assert.Equal(t, []string{"user-a"}, readParticipantIDs(t, a))
+
+	b := dialAs(t, keys, url2+"/ws", "user-b")
+	join(b)
+	assert.ElementsMatch(t, []string{"user-a", "user-b"}, readParticipantIDs(t, b))
+
+	// 다른 인스턴스에 붙은 a도 b의 입장을 받음
+	assert.ElementsMatch(t, []string{"user-a", "user-b"}, readParticipantIDs(t, a))
+}
+
+func TestRoomFanout_SignalingAcrossInstances(t *testing.T) {
+	keys := newTestKeyStore(t)
+	url1 := startFanoutInstance(t, keys, "instance-1")
+	url2 := startFanoutInstance(t, keys, "instance-2")
+
+	a := dialAs(t, keys, url1+"/signaling", "user-a")
+	b := dialAs(t, keys, url2+"/signaling", "user-b")
+	for _, conn := range []*websocket.Conn{a, b} {
+		sendSignal(t, conn, map[string]interface{}{"type": "subscribe", "topics": []string{"team/team1"}})
+		pingPong(t, conn)
+	}
+
+	sendSignal(t, a, map[string]interface{}{"type": "publish", "topic": "team/team1", "data": "from-a"})
+	msg := readSignal(t, b)
+	assert.Equal(t, "from-a", msg["data"])
+
+	// 보낸 쪽 인스턴스로 되돌아와 a가 자기 메시지를 받지 않아야 함
+	pingPong(t, a)
+	_ = a.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	_, raw, err := a.ReadMessage()
+	assert.Error(t, err, "unexpected echo: %s", raw)
+}
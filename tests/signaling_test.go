@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"go-server/controllers"
+	middleware "go-server/middlewares"
+	"go-server/models"
+	"go-server/routes"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type signalingTestEnv struct {
+	url    string
+	keys   *testKeyStore
+	noteID string
+}
+
+func setupSignalingApp(t *testing.T, pingInterval time.Duration) *signalingTestEnv {
+	t.Helper()
+
+	keys := newTestKeyStore(t)
+	noteRepo := NewMockNoteRepository()
+	noteID, err := noteRepo.SaveNote(models.Note{TeamID: "team1", Title: "doc"}, "user1")
+	assert.NoError(t, err)
+
+	membership := middleware.ClaimsTeamMembership{}
+	signalingController := controllers.NewWebSocketController(controllers.NewDocumentTeamResolver(noteRepo, NewMockCanvasRepository()), membership)
+	signalingController.SetPingInterval(pingInterval)
+	participantsController := controllers.NewParticipantsController(NewMockParticipantRepository(), middleware.DefaultPermissionPolicy(), membership)
+
+	app := fiber.New()
+	routes.WebSocketRoutes(app, participantsController, controllers.NewMediaSocketController(membership), signalingController, keys.store, nil, middleware.NewSessionRegistry())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go app.Listener(ln)
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	return &signalingTestEnv{url: "ws://" + ln.Addr().String(), keys: keys, noteID: noteID}
+}
+
+func (env *signalingTestEnv) dial(t *testing.T, userID, teamID string) *websocket.Conn {
+	t.Helper()
+	token := env.keys.sign(t, middleware.CustomClaims{UserID: userID, Teams: []string{teamID}})
+	conn, _, err := websocket.DefaultDialer.Dial(env.url+"/signaling?token="+token, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func sendSignal(t *testing.T, conn *websocket.Conn, msg map[string]interface{}) {
+	t.Helper()
+	assert.NoError(t, conn.WriteJSON(msg))
+}
+
+func readSignal(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg map[string]interface{}
+	if !assert.NoError(t, conn.ReadJSON(&msg)) {
+		t.FailNow()
+	}
+	return msg
+}
+
+// pingPong은 ping을 보내고 pong을 기다립니다. 메시지는 순서대로 처리되므로 앞서 보낸 subscribe도 처리된 뒤입니다.
+func pingPong(t *testing.T, conn *websocket.Conn) {
+	t.Helper()
+	sendSignal(t, conn, map[string]interface{}{"type": "ping"})
+	assert.Equal(t, "pong", readSignal(t, conn)["type"])
+}
+
+func TestSignaling_PublishToSubscribers(t *testing.T) {
+	env := setupSignalingApp(t, time.Minute)
+	topic := "note/" + env.noteID
+
+	a := env.dial(t, "user1", "team1")
+	b := env.dial(t, "user2", "team1")
+	for _, conn := range []*websocket.Conn{a, b} {
+		sendSignal(t, conn, map[string]interface{}{"type": "subscribe", "topics": []string{topic, "team/team1"}})
+		pingPong(t, conn)
+	}
+
+	sendSignal(t, a, map[string]interface{}{
+		"type":  "publish",
+		"topic": topic,
+		"data":  map[string]interface{}{"type": "announce", "from": "peer-a"},
+	})
+	msg := readSignal(t, b)
+	assert.Equal(t, "publish", msg["type"])
+	assert.Equal(t, topic, msg["topic"])
+	assert.Equal(t, float64(2), msg["clients"])
+	assert.Equal(t, map[string]interface{}{"type": "announce", "from": "peer-a"}, msg["data"])
+
+	// 구독을 해제한 topic의 메시지는 받지 않음
+	sendSignal(t, b, map[string]interface{}{"type": "unsubscribe", "topics": []string{topic}})
+	pingPong(t, b)
+	sendSignal(t, a, map[string]interface{}{"type": "publish", "topic": topic, "data": "dropped"})
+	sendSignal(t, a, map[string]interface{}{"type": "publish", "topic": "team/team1", "data": "delivered"})
+	msg = readSignal(t, b)
+	assert.Equal(t, "team/team1", msg["topic"])
+	assert.Equal(t, "delivered", msg["data"])
+}
+
+func TestSignaling_TopicAccessChecked(t *testing.T) {
+	env := setupSignalingApp(t, time.Minute)
+	topic := "note/" + env.noteID
+
+	member := env.dial(t, "user1", "team1")
+	sendSignal(t, member, map[string]interface{}{"type": "subscribe", "topics": []string{topic}})
+	pingPong(t, member)
+
+	outsider := env.dial(t, "user2", "team2")
+	for _, denied := range []string{topic, "team/team1", "note/missing", "no-kind"} {
+		sendSignal(t, outsider, map[string]interface{}{"type": "subscribe", "topics": []string{denied}})
+		msg := readSignal(t, outsider)
+		assert.Equal(t, "error", msg["type"])
+		assert.Equal(t, denied, msg["topic"])
+	}
+
+	// 구독하지 못한 topic으로는 publish도 전달되지 않음
+	sendSignal(t, outsider, map[string]interface{}{"type": "publish", "topic": topic, "data": "intrusion"})
+	pingPong(t, outsider)
+
+	other := env.dial(t, "user3", "team1")
+	sendSignal(t, other, map[string]interface{}{"type": "subscribe", "topics": []string{topic}})
+	pingPong(t, other)
+	sendSignal(t, other, map[string]interface{}{"type": "publish", "topic": topic, "data": "hello"})
+	assert.Equal(t, "hello", readSignal(t, member)["data"])
+}
+
+func TestSignaling_EvictsDeadPeers(t *testing.T) {
+	env := setupSignalingApp(t, 50*time.Millisecond)
+
+	dead := env.dial(t, "user1", "team1")
+	// pong을 보내지 않는 클라이언트
+	dead.SetPingHandler(func(string) error { return nil })
+	sendSignal(t, dead, map[string]interface{}{"type": "subscribe", "topics": []string{"team/team1"}})
+
+	_ = dead.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := dead.ReadMessage()
+	assert.Error(t, err)
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		assert.False(t, netErr.Timeout(), "server should close the connection before the read deadline")
+	}
+}
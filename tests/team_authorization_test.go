@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-server/controllers"
+	middleware "go-server/middlewares"
+	"go-server/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupTeamAuthApp은 JWTParser 대신 X-Test-Teams 헤더로 claims를 넣고 NoteRoutes와 같은 방식으로 권한을 겁니다.
+func setupTeamAuthApp(repo *MockNoteRepository) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		claims := &middleware.CustomClaims{UserID: "user1"}
+		if teams := c.Get("X-Test-Teams"); teams != "" {
+			claims.Teams = strings.Split(teams, ",")
+		}
+		c.Locals("user", claims)
+		return c.Next()
+	})
+
+	noteController := controllers.NewNoteController(repo)
+	membership := middleware.ClaimsTeamMembership{}
+	byBody := middleware.RequireTeamMember(membership, noteController.ResolveNewNoteTeam)
+	byTeam := middleware.RequireTeamMember(membership, middleware.TeamFromParam("teamId"))
+	byNote := middleware.RequireTeamMember(membership, noteController.ResolveNoteTeam)
+
+	app.Post("/notes", byBody, noteController.CreateNote)
+	app.Get("/notes/:id", byNote, noteController.GetNoteByID)
+	app.Get("/notes/team/:teamId", byTeam, noteController.GetNotesByTeamID)
+	app.Delete("/notes/:id", byNote, noteController.DeleteNoteByID)
+
+	return app
+}
+
+func TestTeamAuthorization_MemberCanRead(t *testing.T) {
+	repo := NewMockNoteRepository()
+	id, _ := repo.SaveNote(models.Note{Title: "Note", TeamID: "teamA"}, "user1")
+	app := setupTeamAuthApp(repo)
+
+	req := httptest.NewRequest("GET", "/notes/"+id, nil)
+	req.Header.Set("X-Test-Teams", "teamA,teamB")
+	resp, err := app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+func TestTeamAuthorization_NonMemberForbidden(t *testing.T) {
+	repo := NewMockNoteRepository()
+	id, _ := repo.SaveNote(models.Note{Title: "Note", TeamID: "teamA"}, "user1")
+	app := setupTeamAuthApp(repo)
+
+	for _, req := range []struct{ method, path string }{
+		{"GET", "/notes/" + id},
+		{"DELETE", "/notes/" + id},
+		{"GET", "/notes/team/teamA"},
+	} {
+		r := httptest.NewRequest(req.method, req.path, nil)
+		r.Header.Set("X-Test-Teams", "teamB")
+		resp, err := app.Test(r, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode, req.method+" "+req.path)
+	}
+
+	// 삭제되지 않았어야 함
+	_, err := repo.FindNoteByID(id)
+	assert.NoError(t, err)
+}
+
+func TestTeamAuthorization_UnknownNote(t *testing.T) {
+	app := setupTeamAuthApp(NewMockNoteRepository())
+
+	req := httptest.NewRequest("GET", "/notes/missing", nil)
+	req.Header.Set("X-Test-Teams", "teamA")
+	resp, err := app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func TestTeamAuthorization_CreateChecksBodyTeam(t *testing.T) {
+	repo := NewMockNoteRepository()
+	existingID, _ := repo.SaveNote(models.Note{Title: "Note", TeamID: "teamA"}, "user1")
+	app := setupTeamAuthApp(repo)
+
+	post := func(note models.Note) int {
+		body, _ := json.Marshal(note)
+		req := httptest.NewRequest("POST", "/notes", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-Teams", "teamB")
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, fiber.StatusCreated, post(models.Note{Title: "Mine", TeamID: "teamB"}))
+	assert.Equal(t, fiber.StatusForbidden, post(models.Note{Title: "Theirs", TeamID: "teamA"}))
+	// 다른 팀 노트 id로 자기 팀에 덮어쓰기 시도
+	assert.Equal(t, fiber.StatusForbidden, post(models.Note{ID: existingID, Title: "Stolen", TeamID: "teamB"}))
+}
+
+func TestAnyTeamMembership(t *testing.T) {
+	claims := &middleware.CustomClaims{UserID: "user1", Teams: []string{"teamA"}}
+	membership := middleware.AnyTeamMembership{middleware.ClaimsTeamMembership{}}
+
+	ok, err := membership.IsMember(context.Background(), claims, "teamA")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = membership.IsMember(context.Background(), claims, "teamB")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
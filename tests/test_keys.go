@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	middleware "go-server/middlewares"
+	"go-server/utils"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testKeyStore는 miniredis 위에 올린 PublicKeyStore와 서명용 RSA 키 한 쌍입니다.
+type testKeyStore struct {
+	store       *utils.PublicKeyStore
+	redis       *miniredis.Miniredis
+	redisClient *redis.Client
+	privateKey  *rsa.PrivateKey
+	kid         string
+}
+
+func newTestKeyStore(t *testing.T) *testKeyStore {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = redisClient.Close() })
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PUBLIC KEY",
+		Bytes: x509.MarshalPKCS1PublicKey(&privateKey.PublicKey),
+	})
+
+	store := utils.NewPublicKeyStore(redisClient, utils.DefaultKeyRetention())
+	if err := store.AddOrUpdateKey(context.Background(), "test-kid", string(pemBytes), "", time.Now()); err != nil {
+		t.Fatalf("failed to add key: %v", err)
+	}
+
+	return &testKeyStore{
+		store:       store,
+		redis:       mr,
+		redisClient: redisClient,
+		privateKey:  privateKey,
+		kid:         "test-kid",
+	}
+}
+
+func (k *testKeyStore) sign(t *testing.T, claims middleware.CustomClaims) string {
+	t.Helper()
+
+	if claims.ExpiresAt == nil {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = k.kid
+	signed, err := token.SignedString(k.privateKey)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed
+}
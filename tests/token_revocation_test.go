@@ -0,0 +1,234 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	middleware "go-server/middlewares"
+	pb "go-server/pkg/keyrotation"
+	"go-server/utils"
+
+	"github.com/fasthttp/websocket"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestRevocations(keys *testKeyStore) *utils.TokenRevocationStore {
+	return utils.NewTokenRevocationStore(keys.redisClient, 24*time.Hour)
+}
+
+func TestTokenRevocation_RevokedJTIRejected(t *testing.T) {
+	keys := newTestKeyStore(t)
+	revocations := newTestRevocations(keys)
+	ctx := context.Background()
+
+	revoked := keys.sign(t, middleware.CustomClaims{UserID: "user1", RegisteredClaims: jwt.RegisteredClaims{ID: "jti-1"}})
+	other := keys.sign(t, middleware.CustomClaims{UserID: "user1", RegisteredClaims: jwt.RegisteredClaims{ID: "jti-2"}})
+
+	assert.NoError(t, revocations.RevokeToken(ctx, "jti-1", time.Now().Add(time.Hour)))
+
+	_, err := middleware.ParseJWT(revoked, keys.store, revocations)
+	assert.Error(t, err)
+	_, err = middleware.ParseJWT(other, keys.store, revocations)
+	assert.NoError(t, err)
+
+	// 폐기 기록은 토큰 만료 시각까지만 유지
+	keys.redis.FastForward(2 * time.Hour)
+	assert.False(t, keys.redis.Exists("revoked:jti:jti-1"))
+}
+
+func TestTokenRevocation_UserRevokedBefore(t *testing.T) {
+	keys := newTestKeyStore(t)
+	revocations := newTestRevocations(keys)
+	ctx := context.Background()
+
+	issuedBefore := keys.sign(t, middleware.CustomClaims{UserID: "user1", RegisteredClaims: jwt.RegisteredClaims{
+		IssuedAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+	}})
+	noIssuedAt := keys.sign(t, middleware.CustomClaims{UserID: "user1"})
+	otherUser := keys.sign(t, middleware.CustomClaims{UserID: "user2", RegisteredClaims: jwt.RegisteredClaims{
+		IssuedAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+	}})
+
+	assert.NoError(t, revocations.RevokeUser(ctx, "user1", time.Now()))
+
+	issuedAfter := keys.sign(t, middleware.CustomClaims{UserID: "user1", RegisteredClaims: jwt.RegisteredClaims{
+		IssuedAt: jwt.NewNumericDate(time.Now().Add(time.Second)),
+	}})
+
+	_, err := middleware.ParseJWT(issuedBefore, keys.store, revocations)
+	assert.Error(t, err)
+	_, err = middleware.ParseJWT(noIssuedAt, keys.store, revocations)
+	assert.Error(t, err)
+	_, err = middleware.ParseJWT(issuedAfter, keys.store, revocations)
+	assert.NoError(t, err)
+	_, err = middleware.ParseJWT(otherUser, keys.store, revocations)
+	assert.NoError(t, err)
+}
+
+func TestTokenRevocation_UserRevokedBeforeSecondBoundary(t *testing.T) {
+	keys := newTestKeyStore(t)
+	revocations := newTestRevocations(keys)
+	ctx := context.Background()
+
+	// 폐기 시각이 초 중간이어도 iat(초 단위)와 같은 초에 발급된 토큰은 유효
+	revokedAt := time.Date(2025, 1, 1, 12, 0, 0, 500*int(time.Millisecond), time.UTC)
+	assert.NoError(t, revocations.RevokeUser(ctx, "user1", revokedAt))
+
+	revoked, err := revocations.IsRevoked(ctx, "", "user1", time.Unix(revokedAt.Unix(), 0))
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	revoked, err = revocations.IsRevoked(ctx, "", "user1", time.Unix(revokedAt.Unix()-1, 0))
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestTokenRevocation_FailsClosedWhenRedisUnavailable(t *testing.T) {
+	keys := newTestKeyStore(t)
+	revocations := newTestRevocations(keys)
+	token := keys.sign(t, middleware.CustomClaims{UserID: "user1", RegisteredClaims: jwt.RegisteredClaims{ID: "jti-1"}})
+
+	// 키는 캐시에서 읽을 수 있어도 폐기 여부를 모르면 거부
+	keys.store.SetCacheTTL(time.Minute)
+	_, err := middleware.ParseJWT(token, keys.store, revocations)
+	assert.NoError(t, err)
+
+	keys.redis.SetError("connection refused")
+	_, err = middleware.ParseJWT(token, keys.store, revocations)
+	assert.Error(t, err)
+}
+
+func TestTokenRevocation_GRPC(t *testing.T) {
+	keys := newTestKeyStore(t)
+	revocations := newTestRevocations(keys)
+	client := newKeyRotationClient(t, keys.store, revocations)
+	ctx := context.Background()
+
+	token := keys.sign(t, middleware.CustomClaims{UserID: "user1", RegisteredClaims: jwt.RegisteredClaims{
+		ID:       "jti-1",
+		IssuedAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+	}})
+
+	_, err := client.RevokeToken(ctx, &pb.RevokeTokenRequest{Jti: "jti-1", ExpiresAt: time.Now().Add(time.Hour).Format(time.RFC3339)})
+	assert.NoError(t, err)
+	_, err = middleware.ParseJWT(token, keys.store, revocations)
+	assert.Error(t, err)
+
+	_, err = client.RevokeUserTokens(ctx, &pb.RevokeUserTokensRequest{UserId: "user2"})
+	assert.NoError(t, err)
+	userToken := keys.sign(t, middleware.CustomClaims{UserID: "user2", RegisteredClaims: jwt.RegisteredClaims{
+		IssuedAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+	}})
+	_, err = middleware.ParseJWT(userToken, keys.store, revocations)
+	assert.Error(t, err)
+
+	_, err = client.RevokeToken(ctx, &pb.RevokeTokenRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.RevokeUserTokens(ctx, &pb.RevokeUserTokensRequest{UserId: "user3", RevokedBefore: "yesterday"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestTokenRevocation_DisconnectsLiveSessions(t *testing.T) {
+	keys := newTestKeyStore(t)
+	revocations := newTestRevocations(keys)
+	sessions := middleware.NewSessionRegistry()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, revocations.SubscribeRevocations(ctx, func(revocation utils.TokenRevocation) {
+		sessions.DisconnectRevoked(ctx, revocations, revocation)
+	}))
+	url := setupWebSocketAuthAppWithRevocations(t, keys, revocations, sessions)
+
+	issuedAt := jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	revokedToken := keys.sign(t, middleware.CustomClaims{UserID: "user1", RegisteredClaims: jwt.RegisteredClaims{IssuedAt: issuedAt}})
+	otherToken := keys.sign(t, middleware.CustomClaims{UserID: "user2", RegisteredClaims: jwt.RegisteredClaims{IssuedAt: issuedAt}})
+
+	wsConn, _, err := websocket.DefaultDialer.Dial(url+"/ws?token="+revokedToken, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer wsConn.Close()
+	audioConn, _, err := websocket.DefaultDialer.Dial(url+"/webrtc/audio?token="+revokedToken, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer audioConn.Close()
+	otherConn, _, err := websocket.DefaultDialer.Dial(url+"/ws?token="+otherToken, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer otherConn.Close()
+
+	// 핸들러가 돌고 있는지(세션이 등록되었는지) 한 번씩 주고받아 확인
+	for _, conn := range []*websocket.Conn{wsConn, otherConn} {
+		conn.WriteJSON(map[string]string{"action": "getParticipants", "team_id": "team1", "kind": "canvas"})
+		_, _, err := conn.ReadMessage()
+		assert.NoError(t, err)
+	}
+	audioConn.WriteJSON(map[string]string{"type": "offer", "teamId": "team1"})
+	_, _, err = audioConn.ReadMessage()
+	assert.NoError(t, err)
+
+	assert.NoError(t, revocations.RevokeUser(ctx, "user1", time.Now()))
+
+	for _, conn := range []*websocket.Conn{wsConn, audioConn} {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, _, err := conn.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), "expected policy violation close, got %v", err)
+	}
+
+	// 다른 사용자의 세션은 유지
+	otherConn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	_, _, err = otherConn.ReadMessage()
+	assert.False(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation))
+	var netErr interface{ Timeout() bool }
+	if assert.ErrorAs(t, err, &netErr) {
+		assert.True(t, netErr.Timeout())
+	}
+}
+
+func TestTokenRevocation_DisconnectsSessionThatIgnoresCloseFrame(t *testing.T) {
+	keys := newTestKeyStore(t)
+	revocations := newTestRevocations(keys)
+	sessions := middleware.NewSessionRegistry()
+	sessions.SetMaxConnectionsPerUser(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, revocations.SubscribeRevocations(ctx, func(revocation utils.TokenRevocation) {
+		sessions.DisconnectRevoked(ctx, revocations, revocation)
+	}))
+	url := setupWebSocketAuthAppWithRevocations(t, keys, revocations, sessions)
+
+	issuedAt := jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	revokedToken := keys.sign(t, middleware.CustomClaims{UserID: "user1", RegisteredClaims: jwt.RegisteredClaims{ID: "jti-1", IssuedAt: issuedAt}})
+	freshToken := keys.sign(t, middleware.CustomClaims{UserID: "user1", RegisteredClaims: jwt.RegisteredClaims{ID: "jti-2", IssuedAt: issuedAt}})
+
+	// 세션이 등록되었는지 한 번 주고받은 뒤로는 읽지 않음(close 프레임을 무시하는 클라이언트)
+	ignoring, _, err := websocket.DefaultDialer.Dial(url+"/ws?token="+revokedToken, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ignoring.Close()
+	ignoring.WriteJSON(map[string]string{"action": "getParticipants", "team_id": "team1", "kind": "canvas"})
+	_, _, err = ignoring.ReadMessage()
+	assert.NoError(t, err)
+
+	assert.NoError(t, revocations.RevokeToken(ctx, "jti-1", time.Now().Add(time.Hour)))
+
+	// 서버 핸들러가 끝나 세션이 빠져야 같은 사용자의 새 연결이 한도(1)에 걸리지 않음
+	assert.Eventually(t, func() bool {
+		conn, _, err := websocket.DefaultDialer.Dial(url+"/ws?token="+freshToken, nil)
+		if err != nil {
+			return false
+		}
+		defer conn.Close()
+		conn.WriteJSON(map[string]string{"action": "getParticipants", "team_id": "team1", "kind": "canvas"})
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, _, err = conn.ReadMessage()
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+}
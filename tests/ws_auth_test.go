@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+
+	"go-server/controllers"
+	middleware "go-server/middlewares"
+	"go-server/routes"
+	"go-server/utils"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupWebSocketAuthApp(t *testing.T, keys *testKeyStore) string {
+	t.Helper()
+	return setupWebSocketAuthAppWithRevocations(t, keys, nil, middleware.NewSessionRegistry())
+}
+
+func setupWebSocketAuthAppWithRevocations(t *testing.T, keys *testKeyStore, revocations *utils.TokenRevocationStore, sessions *middleware.SessionRegistry) string {
+	t.Helper()
+
+	membership := middleware.ClaimsTeamMembership{}
+	participantsController := controllers.NewParticipantsController(NewMockParticipantRepository(), middleware.DefaultPermissionPolicy(), membership)
+	mediaController := controllers.NewMediaSocketController(membership)
+	signalingController := controllers.NewWebSocketController(controllers.NewDocumentTeamResolver(NewMockNoteRepository(), NewMockCanvasRepository()), membership)
+
+	app := fiber.New()
+	routes.WebSocketRoutes(app, participantsController, mediaController, signalingController, keys.store, revocations, sessions)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go app.Listener(ln)
+	t.Cleanup(func() { _ = app.Shutdown() })
+	return "ws://" + ln.Addr().String()
+}
+
+func TestWebSocketAuth_MissingToken(t *testing.T) {
+	keys := newTestKeyStore(t)
+	url := setupWebSocketAuthApp(t, keys)
+
+	for _, path := range []string{"/ws", "/webrtc/audio", "/signaling"} {
+		_, resp, err := websocket.DefaultDialer.Dial(url+path, nil)
+		assert.Error(t, err)
+		if assert.NotNil(t, resp) {
+			assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+		}
+	}
+}
+
+func TestWebSocketAuth_InvalidToken(t *testing.T) {
+	keys := newTestKeyStore(t)
+	url := setupWebSocketAuthApp(t, keys)
+
+	_, resp, err := websocket.DefaultDialer.Dial(url+"/ws?token=not-a-jwt", nil)
+	assert.Error(t, err)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	}
+}
+
+func TestWebSocketAuth_IdentityFromClaims(t *testing.T) {
+	keys := newTestKeyStore(t)
+	url := setupWebSocketAuthApp(t, keys)
+
+	token := keys.sign(t, middleware.CustomClaims{UserID: "user1", Username: "Real Name", Teams: []string{"team1"}})
+	header := http.Header{}
+	header.Set("Sec-WebSocket-Protocol", middleware.WebSocketTokenProtocol+", "+token)
+
+	conn, resp, err := websocket.DefaultDialer.Dial(url+"/ws", header)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	assert.Equal(t, middleware.WebSocketTokenProtocol, resp.Header.Get("Sec-WebSocket-Protocol"))
+
+	// 클라이언트가 다른 사람의 ID를 보내도 claims의 ID로 등록되어야 함
+	addBytes, _ := json.Marshal(map[string]string{
+		"action":      "addParticipant",
+		"team_id":     "team1",
+		"kind":        "canvas",
+		"participant": "someone-else",
+		"name":        "Fake Name",
+		"color":       "red",
+	})
+	conn.WriteMessage(websocket.TextMessage, addBytes)
+
+	_, msg, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	var response struct {
+		Action       string `json:"action"`
+		Participants []struct {
+			ID    string `json:"id"`
+			Name  string `json:"name"`
+			Color string `json:"color"`
+		} `json:"participants"`
+	}
+	_ = json.Unmarshal(msg, &response)
+	assert.Equal(t, "updateParticipants", response.Action)
+	if assert.Len(t, response.Participants, 1) {
+		assert.Equal(t, "user1", response.Participants[0].ID)
+		assert.Equal(t, "Real Name", response.Participants[0].Name)
+		assert.Equal(t, "red", response.Participants[0].Color)
+	}
+}
+
+func TestWebSocketAuth_NonMemberCannotJoin(t *testing.T) {
+	keys := newTestKeyStore(t)
+	url := setupWebSocketAuthApp(t, keys)
+
+	token := keys.sign(t, middleware.CustomClaims{UserID: "user1", Teams: []string{"team1"}})
+	conn, _, err := websocket.DefaultDialer.Dial(url+"/ws?token="+token, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	addBytes, _ := json.Marshal(map[string]string{
+		"action":  "addParticipant",
+		"team_id": "team2",
+		"kind":    "canvas",
+	})
+	conn.WriteMessage(websocket.TextMessage, addBytes)
+
+	_, msg, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	var response map[string]interface{}
+	_ = json.Unmarshal(msg, &response)
+	assert.Equal(t, "error", response["action"])
+}
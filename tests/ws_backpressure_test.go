@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"go-server/configs"
+	"go-server/controllers"
+	middleware "go-server/middlewares"
+	"go-server/routes"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignaling_SlowConsumerDoesNotStallRoom(t *testing.T) {
+	keys := newTestKeyStore(t)
+	membership := middleware.ClaimsTeamMembership{}
+	signalingController := controllers.NewWebSocketController(controllers.NewDocumentTeamResolver(NewMockNoteRepository(), NewMockCanvasRepository()), membership)
+	signalingController.SetOutbound(configs.OutboundConfig{QueueSize: 8, WriteTimeout: 5 * time.Second, Overflow: configs.OverflowDisconnect})
+
+	app := fiber.New()
+	routes.WebSocketRoutes(app, controllers.NewParticipantsController(NewMockParticipantRepository(), nil, membership), controllers.NewMediaSocketController(membership), signalingController, keys.store, nil, middleware.NewSessionRegistry())
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go app.Listener(ln)
+	t.Cleanup(func() { _ = app.Shutdown() })
+	url := "ws://" + ln.Addr().String() + "/signaling"
+
+	publisher := dialAs(t, keys, url, "user-a")
+	fast := dialAs(t, keys, url, "user-b")
+	slow := dialAs(t, keys, url, "user-c")
+	// slow는 pong만 보내고 메시지를 읽지 않음
+	for _, conn := range []*websocket.Conn{publisher, fast, slow} {
+		sendSignal(t, conn, map[string]interface{}{"type": "subscribe", "topics": []string{"team/team1"}})
+		pingPong(t, conn)
+	}
+
+	// fast가 하나씩 받는 속도로 보내므로 밀리는 것은 읽지 않는 slow뿐임
+	data := string(bytes.Repeat([]byte("x"), 128*1024))
+	for i := 0; i < 200; i++ {
+		sendSignal(t, publisher, map[string]interface{}{"type": "publish", "topic": "team/team1", "data": data})
+		assert.Equal(t, "publish", readSignal(t, fast)["type"])
+	}
+	// 브로드캐스트가 막히지 않아 보낸 쪽도 계속 응답을 받음
+	pingPong(t, publisher)
+
+	// 느린 구독자는 서버가 끊음. 소켓이 막혀 있으면 close 프레임이 못 갈 수 있어 코드는 보지 않음
+	_ = slow.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		_, _, err := slow.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) {
+			assert.False(t, netErr.Timeout(), "server should disconnect the slow consumer")
+		}
+		break
+	}
+}
@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go-server/configs"
+	"go-server/controllers"
+	middleware "go-server/middlewares"
+	"go-server/models"
+	"go-server/routes"
+	"go-server/utils"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupLimitedWebSocketApp: limits를 적용한 /ws, /webrtc/media(/webrtc/audio), /signaling 서버. 반환값은 "ws://host" 형태입니다.
+func setupLimitedWebSocketApp(t *testing.T, limits configs.LimitConfig) (*testKeyStore, string) {
+	t.Helper()
+	keys := newTestKeyStore(t)
+	membership := middleware.ClaimsTeamMembership{}
+	participantsController := controllers.NewParticipantsController(NewMockParticipantRepository(), middleware.DefaultPermissionPolicy(), membership)
+	participantsController.SetLimits(limits)
+	mediaController := controllers.NewMediaSocketController(membership)
+	mediaController.SetLimits(limits)
+	signalingController := controllers.NewWebSocketController(nil, membership)
+	signalingController.SetLimits(limits)
+	sessions := middleware.NewSessionRegistry()
+	sessions.SetMaxConnectionsPerUser(limits.MaxConnectionsPerUser)
+
+	app := fiber.New()
+	routes.WebSocketRoutes(app, participantsController, mediaController, signalingController, keys.store, nil, sessions)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go app.Listener(ln)
+	t.Cleanup(func() { _ = app.Shutdown() })
+	return keys, "ws://" + ln.Addr().String()
+}
+
+// readCloseCode: 서버가 연결을 닫을 때까지 읽고 close code를 반환합니다.
+func readCloseCode(t *testing.T, conn *websocket.Conn) int {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !assert.True(t, errors.As(err, &closeErr), "expected close frame, got %v", err) {
+			t.FailNow()
+		}
+		return closeErr.Code
+	}
+}
+
+func getParticipantsRequest(requestID string) map[string]interface{} {
+	return map[string]interface{}{
+		"v":         1,
+		"action":    models.ActionGetParticipants,
+		"requestId": requestID,
+		"payload":   map[string]string{"team_id": "team1", "kind": "canvas"},
+	}
+}
+
+func TestWebSocketLimits_ConnectionRateLimitThenDisconnect(t *testing.T) {
+	limits := configs.DefaultLimitConfig()
+	limits.MaxViolations = 3
+	limits.Endpoints[configs.EndpointParticipants] = configs.EndpointLimits{
+		Actions: map[string]configs.ActionLimit{
+			models.ActionGetParticipants: {Connection: configs.RateLimit{PerSecond: 0.001, Burst: 2}},
+		},
+	}
+	keys, base := setupLimitedWebSocketApp(t, limits)
+	conn := dialAs(t, keys, base+"/ws", "user-a")
+
+	for _, id := range []string{"r1", "r2"} {
+		assert.NoError(t, conn.WriteJSON(getParticipantsRequest(id)))
+		assert.Equal(t, "getParticipants", readProtocolMessage(t, conn)["action"])
+	}
+
+	// 한도를 넘긴 요청은 처리하지 않고 rate_limited 오류로 답함
+	assert.NoError(t, conn.WriteJSON(getParticipantsRequest("r3")))
+	rejected := readProtocolMessage(t, conn)
+	assert.Equal(t, "error", rejected["action"])
+	assert.Equal(t, models.ErrorCodeRateLimited, rejected["code"])
+	assert.Equal(t, "r3", rejected["requestId"])
+
+	// 세 번째 위반에서 연결을 끊음
+	assert.NoError(t, conn.WriteJSON(getParticipantsRequest("r4")))
+	assert.NoError(t, conn.WriteJSON(getParticipantsRequest("r5")))
+	assert.Equal(t, controllers.CloseRateLimited, readCloseCode(t, conn))
+}
+
+func TestWebSocketLimits_UserBucketSharedAcrossConnections(t *testing.T) {
+	limits := configs.DefaultLimitConfig()
+	limits.Endpoints[configs.EndpointParticipants] = configs.EndpointLimits{
+		Actions: map[string]configs.ActionLimit{
+			"*": {User: configs.RateLimit{PerSecond: 0.001, Burst: 2}},
+		},
+	}
+	keys, base := setupLimitedWebSocketApp(t, limits)
+
+	tab1 := dialAs(t, keys, base+"/ws", "user-a")
+	tab2 := dialAs(t, keys, base+"/ws", "user-a")
+	for _, conn := range []*websocket.Conn{tab1, tab2} {
+		assert.NoError(t, conn.WriteJSON(getParticipantsRequest("ok")))
+		assert.Equal(t, "getParticipants", readProtocolMessage(t, conn)["action"])
+	}
+
+	// 같은 사용자의 다른 연결도 같은 버킷을 씀
+	assert.NoError(t, tab1.WriteJSON(getParticipantsRequest("over")))
+	assert.Equal(t, models.ErrorCodeRateLimited, readProtocolMessage(t, tab1)["code"])
+
+	// 다른 사용자는 영향을 받지 않음
+	other := dialAs(t, keys, base+"/ws", "user-b")
+	assert.NoError(t, other.WriteJSON(getParticipantsRequest("ok")))
+	assert.Equal(t, "getParticipants", readProtocolMessage(t, other)["action"])
+}
+
+func TestWebSocketLimits_OversizedFrameClosesWithMessageTooBig(t *testing.T) {
+	limits := configs.DefaultLimitConfig()
+	media := limits.Endpoints[configs.EndpointMedia]
+	media.MaxMessageSize = 1024
+	limits.Endpoints[configs.EndpointMedia] = media
+	keys, base := setupLimitedWebSocketApp(t, limits)
+
+	conn := dialAs(t, keys, base+"/webrtc/media", "user-a")
+	big := `{"type":"offer","sdp":"` + strings.Repeat("x", 4096) + `"}`
+	assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(big)))
+	assert.Equal(t, websocket.CloseMessageTooBig, readCloseCode(t, conn))
+}
+
+func TestWebSocketLimits_MaxConnectionsPerUser(t *testing.T) {
+	limits := configs.DefaultLimitConfig()
+	limits.MaxConnectionsPerUser = 2
+	keys, base := setupLimitedWebSocketApp(t, limits)
+
+	// 한도는 엔드포인트를 합쳐 셈
+	first := dialAs(t, keys, base+"/ws", "user-a")
+	assert.NoError(t, first.WriteJSON(getParticipantsRequest("r1")))
+	readProtocolMessage(t, first)
+	second := dialAs(t, keys, base+"/signaling", "user-a")
+	pingPong(t, second)
+
+	third := dialAs(t, keys, base+"/ws", "user-a")
+	assert.Equal(t, middleware.CloseTooManyConnections, readCloseCode(t, third))
+
+	other := dialAs(t, keys, base+"/signaling", "user-b")
+	pingPong(t, other)
+
+	// 연결 하나를 닫으면 다시 열 수 있음
+	_ = first.Close()
+	assert.Eventually(t, func() bool {
+		conn := dialAs(t, keys, base+"/signaling", "user-a")
+		defer conn.Close()
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		if err := conn.WriteJSON(map[string]string{"type": "ping"}); err != nil {
+			return false
+		}
+		var reply map[string]string
+		return conn.ReadJSON(&reply) == nil && reply["type"] == "pong"
+	}, 3*time.Second, 50*time.Millisecond)
+}
+
+func TestTokenBucket_RefillsUpToBurst(t *testing.T) {
+	bucket := utils.NewTokenBucket(2, 3)
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, bucket.AllowAt(now))
+	}
+	assert.False(t, bucket.AllowAt(now))
+
+	// 초당 2개씩 채워짐
+	assert.True(t, bucket.AllowAt(now.Add(500*time.Millisecond)))
+	assert.False(t, bucket.AllowAt(now.Add(500*time.Millisecond)))
+
+	// 오래 쉬어도 burst까지만 모임
+	later := now.Add(time.Minute)
+	for i := 0; i < 3; i++ {
+		assert.True(t, bucket.AllowAt(later))
+	}
+	assert.False(t, bucket.AllowAt(later))
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"testing"
+
+	"go-server/models"
+	"go-server/repository"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// sentWrite: 저장소가 첫 번째로 보낸 쓰기 명령(findAndModify 또는 update)의 조건과 update 문서
+func sentWrite(mt *mtest.T) (filter, update bson.Raw) {
+	event := mt.GetStartedEvent()
+	if event == nil {
+		mt.Fatal("no command sent")
+	}
+	if update, ok := event.Command.Lookup("update").DocumentOK(); ok {
+		return event.Command.Lookup("query").Document(), update
+	}
+	// update 명령은 updates 배열의 q, u에 문서가 들어감
+	return event.Command.Lookup("updates", "0", "q").Document(), event.Command.Lookup("updates", "0", "u").Document()
+}
+
+// assertGuardsYDocLog: 로그가 비어 있을 때만 쓰고, 쓸 때 로그 필드를 지우는지
+func assertGuardsYDocLog(mt *mtest.T) {
+	filter, update := sentWrite(mt)
+	_, guarded := filter.Lookup("ydoc_updates.0").DocumentOK()
+	assert.True(mt, guarded, "filter %v", filter)
+	_, unset := update.Lookup("$unset").Document().Lookup("ydoc_updates").StringValueOK()
+	assert.True(mt, unset, "update %v", update)
+}
+
+// countResponse: CountDocuments(aggregate)의 응답
+func countResponse(ns string, n int) bson.D {
+	return mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: n}})
+}
+
+func TestYDocLog_RESTWritesGuardLog(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	id := primitive.NewObjectID()
+	duplicateKey := mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11000, Name: "DuplicateKey", Message: "E11000 duplicate key error"})
+
+	mt.Run("save note", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: bson.D{{Key: "_id", Value: id}, {Key: "version", Value: 3}}}},
+			mtest.CreateSuccessResponse(),
+		)
+		repo := repository.NewNoteRepository(mt.Coll, mt.Coll)
+		_, err := repo.SaveNote(models.Note{ID: id.Hex(), TeamID: "team1", Note: "rest"}, "user1")
+		assert.NoError(mt, err)
+		assertGuardsYDocLog(mt)
+	})
+
+	mt.Run("save note over Yjs log", func(mt *mtest.T) {
+		// 조건에 맞지 않아 upsert가 같은 _id로 삽입하려다 실패
+		mt.AddMockResponses(duplicateKey)
+		repo := repository.NewNoteRepository(mt.Coll, mt.Coll)
+		_, err := repo.SaveNote(models.Note{ID: id.Hex(), TeamID: "team1", Note: "rest"}, "user1")
+		assert.ErrorIs(mt, err, repository.ErrYDocActive)
+	})
+
+	mt.Run("update note content over Yjs log", func(mt *mtest.T) {
+		ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}},
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: id}, {Key: "version", Value: 3}}),
+			countResponse(ns, 1),
+		)
+		repo := repository.NewNoteRepository(mt.Coll, mt.Coll)
+		version, err := repo.UpdateNoteContent(id.Hex(), "rest", 3, "user1")
+		assert.ErrorIs(mt, err, repository.ErrYDocActive)
+		assert.Equal(mt, 3, version)
+		assertGuardsYDocLog(mt)
+	})
+
+	mt.Run("save canvas", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+		repo := repository.NewCanvasRepository(mt.Coll)
+		_, err := repo.SaveCanvas(models.Canvas{ID: id.Hex(), TeamID: "team1", Canvas: "rest"})
+		assert.NoError(mt, err)
+		assertGuardsYDocLog(mt)
+	})
+
+	mt.Run("save canvas over Yjs log", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "E11000 duplicate key error"}))
+		repo := repository.NewCanvasRepository(mt.Coll)
+		_, err := repo.SaveCanvas(models.Canvas{ID: id.Hex(), TeamID: "team1", Canvas: "rest"})
+		assert.ErrorIs(mt, err, repository.ErrYDocActive)
+	})
+
+	mt.Run("restore canvas over Yjs log", func(mt *mtest.T) {
+		ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}), countResponse(ns, 1))
+		repo := repository.NewCanvasRepository(mt.Coll)
+		err := repo.RestoreCanvas(id.Hex(), "title", "snapshot")
+		assert.ErrorIs(mt, err, repository.ErrYDocActive)
+		assertGuardsYDocLog(mt)
+	})
+}
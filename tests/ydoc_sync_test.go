@@ -0,0 +1,345 @@
+package tests
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"go-server/controllers"
+	middleware "go-server/middlewares"
+	"go-server/models"
+	"go-server/routes"
+	"go-server/utils"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type ySyncTestEnv struct {
+	url      string
+	keys     *testKeyStore
+	noteRepo *MockNoteRepository
+	docs     *MockYDocRepository
+	noteID   string
+}
+
+func setupYSyncApp(t *testing.T, configure ...func(*controllers.YDocController)) *ySyncTestEnv {
+	t.Helper()
+
+	keys := newTestKeyStore(t)
+	noteRepo := NewMockNoteRepository()
+	noteID, err := noteRepo.SaveNote(models.Note{TeamID: "team1", Title: "doc"}, "user1")
+	assert.NoError(t, err)
+
+	env := &ySyncTestEnv{keys: keys, noteRepo: noteRepo, docs: NewMockYDocRepository(), noteID: noteID}
+	env.url = env.startInstance(t, configure...)
+	return env
+}
+
+// startInstance는 같은 저장소를 쓰는 서버 인스턴스를 하나 더 띄우고 주소를 돌려줍니다.
+func (env *ySyncTestEnv) startInstance(t *testing.T, configure ...func(*controllers.YDocController)) string {
+	t.Helper()
+
+	policy := middleware.DefaultPermissionPolicy()
+	ydocController := controllers.NewYDocController(controllers.NewDocumentTeamResolver(env.noteRepo, NewMockCanvasRepository()), env.docs, NewMockYDocRepository(), policy)
+	for _, fn := range configure {
+		fn(ydocController)
+	}
+
+	app := fiber.New()
+	routes.YDocRoutes(app, ydocController, env.keys.store, nil, middleware.NewSessionRegistry(), middleware.ClaimsTeamMembership{}, policy)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go app.Listener(ln)
+	t.Cleanup(func() { _ = app.Shutdown() })
+	return "ws://" + ln.Addr().String()
+}
+
+func (env *ySyncTestEnv) dial(t *testing.T, role string) *websocket.Conn {
+	t.Helper()
+	return env.dialInstance(t, env.url, role)
+}
+
+func (env *ySyncTestEnv) dialInstance(t *testing.T, url, role string) *websocket.Conn {
+	t.Helper()
+	token := env.keys.sign(t, middleware.CustomClaims{UserID: "user-" + role, Role: role, Teams: []string{"team1"}})
+	conn, _, err := websocket.DefaultDialer.Dial(url+"/yjs/note/"+env.noteID+"?token="+token, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func writeY(t *testing.T, conn *websocket.Conn, msg []byte) {
+	t.Helper()
+	assert.NoError(t, conn.WriteMessage(websocket.BinaryMessage, msg))
+}
+
+// readYSync는 다음 메시지가 sync 메시지인지 확인하고 (syncType, payload)를 돌려줍니다.
+func readYSync(t *testing.T, conn *websocket.Conn) (uint64, []byte) {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	msgType, msg, err := conn.ReadMessage()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, websocket.BinaryMessage, msgType)
+	d := utils.NewYDecoder(msg)
+	messageType, _ := d.ReadVarUint()
+	assert.Equal(t, uint64(utils.YMessageSync), messageType)
+	syncType, _ := d.ReadVarUint()
+	payload, err := d.ReadVarUint8Array()
+	assert.NoError(t, err)
+	return syncType, payload
+}
+
+func readYAwareness(t *testing.T, conn *websocket.Conn) []utils.YAwarenessState {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, msg, err := conn.ReadMessage()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	d := utils.NewYDecoder(msg)
+	messageType, _ := d.ReadVarUint()
+	assert.Equal(t, uint64(utils.YMessageAwareness), messageType)
+	update, _ := d.ReadVarUint8Array()
+	states, err := utils.DecodeYAwarenessUpdate(update)
+	assert.NoError(t, err)
+	return states
+}
+
+// readYClose는 서버가 연결을 닫을 때까지 읽고 close code를 돌려줍니다.
+func readYClose(t *testing.T, conn *websocket.Conn) int {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) {
+				return closeErr.Code
+			}
+			t.Fatalf("expected close frame, got %v", err)
+		}
+	}
+}
+
+// handshake는 y-websocket 클라이언트처럼 step 1을 보내고, 서버가 보낸 update들과 step 2, step 1을 받습니다.
+func handshake(t *testing.T, conn *websocket.Conn) [][]byte {
+	t.Helper()
+	writeY(t, conn, utils.EncodeYSyncMessage(utils.YSyncStep1, utils.YEmptyStateVector))
+
+	var received [][]byte
+	for {
+		syncType, payload := readYSync(t, conn)
+		if syncType == utils.YSyncStep2 {
+			received = append(received, payload)
+			break
+		}
+		assert.Equal(t, uint64(utils.YSyncUpdate), syncType)
+		received = append(received, payload)
+	}
+	syncType, payload := readYSync(t, conn)
+	assert.Equal(t, uint64(utils.YSyncStep1), syncType)
+	assert.Equal(t, utils.YEmptyStateVector, payload)
+	return received
+}
+
+func TestYSync_PersistsAndLoadsWithoutPeers(t *testing.T) {
+	env := setupYSyncApp(t)
+
+	a := env.dial(t, middleware.RoleEditor)
+	assert.Equal(t, [][]byte{utils.YEmptyUpdate}, handshake(t, a))
+	writeY(t, a, utils.EncodeYSyncMessage(utils.YSyncUpdate, []byte{1, 1}))
+	writeY(t, a, utils.EncodeYSyncMessage(utils.YSyncUpdate, []byte{2, 2}))
+	_ = a.Close()
+
+	assert.Eventually(t, func() bool { return len(env.docs.Updates(env.noteID)) == 2 }, 2*time.Second, 10*time.Millisecond)
+
+	// 아무도 접속해 있지 않아도 저장된 로그로 문서를 받음
+	b := env.dial(t, middleware.RoleEditor)
+	assert.Equal(t, [][]byte{{1, 1}, {2, 2}}, handshake(t, b))
+
+	// 로그를 적용한 클라이언트의 전체 상태로 로그가 압축됨
+	writeY(t, b, utils.EncodeYSyncMessage(utils.YSyncStep2, []byte{3, 3, 3}))
+	assert.Eventually(t, func() bool {
+		updates := env.docs.Updates(env.noteID)
+		return len(updates) == 1 && string(updates[0]) == string([]byte{3, 3, 3})
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestYSync_CompactionKeepsLaterUpdates(t *testing.T) {
+	env := setupYSyncApp(t)
+
+	a := env.dial(t, middleware.RoleEditor)
+	handshake(t, a)
+	writeY(t, a, utils.EncodeYSyncMessage(utils.YSyncUpdate, []byte{1}))
+	assert.Eventually(t, func() bool { return len(env.docs.Updates(env.noteID)) == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	b := env.dial(t, middleware.RoleEditor)
+	assert.Equal(t, [][]byte{{1}}, handshake(t, b))
+
+	// b가 step 2로 답하기 전에 a가 보낸 update는 b의 상태에 없을 수 있으므로 남아야 함
+	writeY(t, a, utils.EncodeYSyncMessage(utils.YSyncUpdate, []byte{2}))
+	syncType, payload := readYSync(t, b)
+	assert.Equal(t, uint64(utils.YSyncUpdate), syncType)
+	assert.Equal(t, []byte{2}, payload)
+
+	writeY(t, b, utils.EncodeYSyncMessage(utils.YSyncStep2, []byte{9}))
+	assert.Eventually(t, func() bool {
+		updates := env.docs.Updates(env.noteID)
+		return len(updates) == 2 && string(updates[0]) == string([]byte{9}) && string(updates[1]) == string([]byte{2})
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestYSync_BroadcastsUpdatesAndAwareness(t *testing.T) {
+	env := setupYSyncApp(t)
+
+	a := env.dial(t, middleware.RoleEditor)
+	handshake(t, a)
+	b := env.dial(t, middleware.RoleEditor)
+	handshake(t, b)
+
+	writeY(t, a, utils.EncodeYSyncMessage(utils.YSyncUpdate, []byte{4, 2}))
+	syncType, payload := readYSync(t, b)
+	assert.Equal(t, uint64(utils.YSyncUpdate), syncType)
+	assert.Equal(t, []byte{4, 2}, payload)
+
+	state := utils.YAwarenessState{ClientID: 77, Clock: 1, State: `{"user":{"name":"a"}}`}
+	writeY(t, a, utils.EncodeYAwarenessMessage(utils.EncodeYAwarenessUpdate([]utils.YAwarenessState{state})))
+	assert.Equal(t, []utils.YAwarenessState{state}, readYAwareness(t, b))
+
+	// 새로 들어온 연결은 현재 awareness를 바로 받음
+	c := env.dial(t, middleware.RoleEditor)
+	assert.Equal(t, []utils.YAwarenessState{state}, readYAwareness(t, c))
+
+	// 연결이 끊기면 그 연결의 awareness 제거가 전파됨
+	_ = a.Close()
+	removed := readYAwareness(t, b)
+	if assert.Len(t, removed, 1) {
+		assert.Equal(t, uint64(77), removed[0].ClientID)
+		assert.Equal(t, uint64(2), removed[0].Clock)
+		assert.True(t, removed[0].Removed())
+	}
+}
+
+func TestYSync_ViewerIsReadOnly(t *testing.T) {
+	env := setupYSyncApp(t)
+
+	viewer := env.dial(t, middleware.RoleViewer)
+	handshake(t, viewer)
+	writeY(t, viewer, utils.EncodeYSyncMessage(utils.YSyncUpdate, []byte{5}))
+	writeY(t, viewer, utils.EncodeYSyncMessage(utils.YSyncStep2, []byte{6}))
+
+	// 메시지는 순서대로 처리되므로 다음 handshake 응답을 받으면 위 update는 이미 처리됨
+	assert.Equal(t, [][]byte{utils.YEmptyUpdate}, handshake(t, viewer))
+	assert.Empty(t, env.docs.Updates(env.noteID))
+}
+
+func TestYSync_Authorization(t *testing.T) {
+	env := setupYSyncApp(t)
+
+	outsider := env.keys.sign(t, middleware.CustomClaims{UserID: "user2", Role: middleware.RoleEditor, Teams: []string{"team2"}})
+	_, resp, err := websocket.DefaultDialer.Dial(env.url+"/yjs/note/"+env.noteID+"?token="+outsider, nil)
+	assert.Error(t, err)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	}
+
+	member := env.keys.sign(t, middleware.CustomClaims{UserID: "user1", Role: middleware.RoleEditor, Teams: []string{"team1"}})
+	_, resp, err = websocket.DefaultDialer.Dial(env.url+"/yjs/note/missing?token="+member, nil)
+	assert.Error(t, err)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func TestYSync_CompactsWhenLogGrows(t *testing.T) {
+	env := setupYSyncApp(t, func(yc *controllers.YDocController) { yc.SetCompaction(3, 0) })
+
+	a := env.dial(t, middleware.RoleEditor)
+	handshake(t, a)
+	// 실제 클라이언트처럼 서버의 step 1에 (빈 문서의) step 2로 답함
+	writeY(t, a, utils.EncodeYSyncMessage(utils.YSyncStep2, utils.YEmptyUpdate))
+	for i := byte(1); i <= 3; i++ {
+		writeY(t, a, utils.EncodeYSyncMessage(utils.YSyncUpdate, []byte{i}))
+	}
+
+	// 기준을 넘기면 마지막으로 쓴 연결에 전체 상태를 요청함
+	syncType, payload := readYSync(t, a)
+	assert.Equal(t, uint64(utils.YSyncStep1), syncType)
+	assert.Equal(t, utils.YEmptyStateVector, payload)
+	assert.Len(t, env.docs.Updates(env.noteID), 3)
+
+	writeY(t, a, utils.EncodeYSyncMessage(utils.YSyncStep2, []byte{9}))
+	assert.Eventually(t, func() bool {
+		updates := env.docs.Updates(env.noteID)
+		return len(updates) == 1 && string(updates[0]) == string([]byte{9})
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestYSync_ClosesRoomWhenSaveFails(t *testing.T) {
+	env := setupYSyncApp(t)
+
+	a := env.dial(t, middleware.RoleEditor)
+	handshake(t, a)
+	b := env.dial(t, middleware.RoleEditor)
+	handshake(t, b)
+
+	env.docs.SetAppendError(errors.New("mongo down"))
+	writeY(t, a, utils.EncodeYSyncMessage(utils.YSyncUpdate, []byte{1}))
+
+	// 저장하지 못한 update는 전달하지 않고 모두 끊음
+	assert.Equal(t, websocket.CloseInternalServerErr, readYClose(t, a))
+	assert.Equal(t, websocket.CloseInternalServerErr, readYClose(t, b))
+
+	env.docs.SetAppendError(nil)
+	c := env.dial(t, middleware.RoleEditor)
+	assert.Equal(t, [][]byte{utils.YEmptyUpdate}, handshake(t, c))
+}
+
+func TestYSync_ClosesRoomWhenLogChangedOutside(t *testing.T) {
+	env := setupYSyncApp(t)
+
+	a := env.dial(t, middleware.RoleEditor)
+	handshake(t, a)
+	writeY(t, a, utils.EncodeYSyncMessage(utils.YSyncUpdate, []byte{1}))
+	assert.Eventually(t, func() bool { return len(env.docs.Updates(env.noteID)) == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	// 밖에서(문서 삭제 등) 로그가 바뀐 뒤 온 update는 저장하지 않고, 다시 연결하게 함
+	env.docs.Clear(env.noteID)
+	writeY(t, a, utils.EncodeYSyncMessage(utils.YSyncUpdate, []byte{2}))
+	assert.Equal(t, websocket.CloseTryAgainLater, readYClose(t, a))
+	assert.Empty(t, env.docs.Updates(env.noteID))
+
+	b := env.dial(t, middleware.RoleEditor)
+	assert.Equal(t, [][]byte{utils.YEmptyUpdate}, handshake(t, b))
+}
+
+func TestYSync_RoomLeaseKeepsDocumentOnOneInstance(t *testing.T) {
+	env := setupYSyncApp(t)
+	withLease := func(owner string) func(*controllers.YDocController) {
+		return func(yc *controllers.YDocController) {
+			yc.SetRoomLease(utils.NewRoomLease(env.keys.redisClient, owner, time.Minute))
+		}
+	}
+	first := env.startInstance(t, withLease("instance-1"))
+	other := env.startInstance(t, withLease("instance-2"))
+
+	a := env.dialInstance(t, first, middleware.RoleEditor)
+	handshake(t, a)
+
+	// 다른 인스턴스로 온 연결은 닫힘
+	b := env.dialInstance(t, other, middleware.RoleEditor)
+	assert.Equal(t, controllers.CloseDocumentOpenElsewhere, readYClose(t, b))
+
+	// 방이 닫히면 소유권이 풀려 다른 인스턴스에서 열 수 있음
+	_ = a.Close()
+	assert.Eventually(t, func() bool { return !env.keys.redis.Exists("room-owner:note/" + env.noteID) }, 2*time.Second, 10*time.Millisecond)
+	c := env.dialInstance(t, other, middleware.RoleEditor)
+	assert.Equal(t, [][]byte{utils.YEmptyUpdate}, handshake(t, c))
+}
@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"testing"
+
+	"go-server/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestYProtocol_VarUintRoundTrip(t *testing.T) {
+	values := []uint64{0, 1, 127, 128, 300, 1<<32 + 5, 1<<63 + 1}
+
+	e := utils.NewYEncoder()
+	for _, v := range values {
+		e.WriteVarUint(v)
+	}
+	assert.Equal(t, []byte{0, 1, 0x7f, 0x80, 0x01}, e.Bytes()[:5])
+
+	d := utils.NewYDecoder(e.Bytes())
+	for _, want := range values {
+		got, err := d.ReadVarUint()
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	assert.False(t, d.HasContent())
+}
+
+func TestYProtocol_SyncMessage(t *testing.T) {
+	msg := utils.EncodeYSyncMessage(utils.YSyncUpdate, []byte{9, 8, 7})
+	assert.Equal(t, []byte{0, 2, 3, 9, 8, 7}, msg)
+
+	d := utils.NewYDecoder(msg)
+	messageType, _ := d.ReadVarUint()
+	syncType, _ := d.ReadVarUint()
+	payload, err := d.ReadVarUint8Array()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(utils.YMessageSync), messageType)
+	assert.Equal(t, uint64(utils.YSyncUpdate), syncType)
+	assert.Equal(t, []byte{9, 8, 7}, payload)
+}
+
+func TestYProtocol_Truncated(t *testing.T) {
+	_, err := utils.NewYDecoder([]byte{0x80}).ReadVarUint()
+	assert.ErrorIs(t, err, utils.ErrYMessageTruncated)
+
+	_, err = utils.NewYDecoder([]byte{5, 1, 2}).ReadVarUint8Array()
+	assert.ErrorIs(t, err, utils.ErrYMessageTruncated)
+
+	_, err = utils.DecodeYAwarenessUpdate([]byte{2, 1, 1})
+	assert.Error(t, err)
+}
+
+func TestYProtocol_AwarenessRoundTrip(t *testing.T) {
+	states := []utils.YAwarenessState{
+		{ClientID: 12345, Clock: 3, State: `{"user":{"name":"a"}}`},
+		{ClientID: 7, Clock: 10, State: "null"},
+	}
+	decoded, err := utils.DecodeYAwarenessUpdate(utils.EncodeYAwarenessUpdate(states))
+	assert.NoError(t, err)
+	assert.Equal(t, states, decoded)
+	assert.False(t, decoded[0].Removed())
+	assert.True(t, decoded[1].Removed())
+}
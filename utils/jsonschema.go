@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// JSONSchemaDraft: JSONSchemaBuilder가 만드는 스키마의 $schema 값
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// JSONSchemaBuilder는 Go 구조체에서 JSON Schema를 만듭니다.
+// 구조체는 타입 이름으로 Defs에 한 번만 들어가고 "#/$defs/<이름>"으로 참조됩니다.
+// json 태그 이름을 따르고, omitempty가 없는 필드는 required로 봅니다.
+type JSONSchemaBuilder struct {
+	Defs map[string]interface{}
+}
+
+func NewJSONSchemaBuilder() *JSONSchemaBuilder {
+	return &JSONSchemaBuilder{Defs: make(map[string]interface{})}
+}
+
+// Ref는 v의 타입을 Defs에 등록하고 참조 스키마를 돌려줍니다. 구조체가 아니면 스키마를 그대로 돌려줍니다.
+func (b *JSONSchemaBuilder) Ref(v interface{}) map[string]interface{} {
+	return b.schemaOf(reflect.TypeOf(v))
+}
+
+func (b *JSONSchemaBuilder) schemaOf(t reflect.Type) map[string]interface{} {
+	if t == rawMessageType {
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.schemaOf(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json은 []byte를 base64 문자열로 씀
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schemaOf(t.Elem())}
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := b.Defs[name]; !ok {
+			// 재귀 타입을 위해 먼저 자리를 잡아 둠
+			b.Defs[name] = nil
+			b.Defs[name] = b.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + name}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func (b *JSONSchemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = b.schemaOf(field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
@@ -0,0 +1,240 @@
+package utils
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// JWKSClient는 인증 서버의 JWKS 엔드포인트에서 공개키를 받아 PublicKeyStore에 반영합니다.
+// gRPC 키 푸시(NotifyKeyRolled)를 놓쳤을 때를 대비한 보조 경로입니다.
+type JWKSClient struct {
+	url        string
+	httpClient *http.Client
+	store      *PublicKeyStore
+	// 주기적으로 다시 받아오는 간격 (0이면 주기 갱신 없음)
+	refreshInterval time.Duration
+	// 모르는 kid 때문에 다시 받아올 때의 최소 간격. 임의의 kid로 JWKS를 두드리는 것을 막습니다.
+	minRefreshInterval time.Duration
+
+	mu          sync.Mutex
+	lastFetched time.Time
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC, OKP
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+func NewJWKSClient(url string, store *PublicKeyStore, refreshInterval, minRefreshInterval time.Duration) *JWKSClient {
+	return &JWKSClient{
+		url:                url,
+		httpClient:         &http.Client{Timeout: 10 * time.Second},
+		store:              store,
+		refreshInterval:    refreshInterval,
+		minRefreshInterval: minRefreshInterval,
+	}
+}
+
+// Run은 시작 시 한 번 받아오고, 이후 refreshInterval마다 갱신합니다. ctx가 끝나면 멈춥니다.
+func (c *JWKSClient) Run(ctx context.Context) {
+	if err := c.Refresh(ctx); err != nil {
+		log.Printf("Initial JWKS fetch from %s failed: %v", c.url, err)
+	}
+	if c.refreshInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Refresh(ctx); err != nil {
+				log.Printf("JWKS refresh from %s failed: %v", c.url, err)
+			}
+		}
+	}
+}
+
+// Refresh는 JWKS를 받아 저장소와 동기화합니다.
+func (c *JWKSClient) Refresh(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.refreshLocked(ctx)
+}
+
+// LoadMissingKey는 PublicKeyStore.SetMissingKeyLoader에 넘기는 훅입니다.
+// 마지막 조회 후 minRefreshInterval이 지나지 않았으면 다시 받아오지 않습니다.
+func (c *JWKSClient) LoadMissingKey(ctx context.Context, kid string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.lastFetched) < c.minRefreshInterval {
+		return nil
+	}
+	log.Printf("Unknown kid=%s, refreshing JWKS from %s", kid, c.url)
+	return c.refreshLocked(ctx)
+}
+
+func (c *JWKSClient) refreshLocked(ctx context.Context) error {
+	c.lastFetched = time.Now()
+
+	pubKeys, err := c.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pubKeys) == 0 {
+		// 빈 응답으로 기존 키를 모두 만료시키지 않도록 무시
+		return fmt.Errorf("no usable keys in JWKS")
+	}
+	return c.store.SyncKeys(ctx, pubKeys)
+}
+
+func (c *JWKSClient) fetch(ctx context.Context) (map[string]*PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: %s", resp.Status)
+	}
+
+	var set jwks
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	pubKeys := make(map[string]*PublicKey)
+	for _, key := range set.Keys {
+		if key.Kid == "" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		pubKey, err := parseJWK(key)
+		if err != nil {
+			log.Printf("Skipping invalid JWKS key kid=%s: %v", key.Kid, err)
+			continue
+		}
+		pubKeys[key.Kid] = pubKey
+	}
+	return pubKeys, nil
+}
+
+func parseJWK(key jwk) (*PublicKey, error) {
+	var pubKey crypto.PublicKey
+	var err error
+	switch key.Kty {
+	case "RSA":
+		pubKey, err = parseRSAJWK(key)
+	case "EC":
+		pubKey, err = parseECJWK(key)
+	case "OKP":
+		pubKey, err = parseOKPJWK(key)
+	default:
+		return nil, fmt.Errorf("unsupported kty %s", key.Kty)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewPublicKey(pubKey, key.Alg)
+}
+
+func parseRSAJWK(key jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(key.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(key.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 2 || exponent.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("invalid exponent")
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	}, nil
+}
+
+func parseECJWK(key jwk) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch key.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %s", key.Crv)
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(key.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x coordinate: %w", err)
+	}
+	y, err := base64.RawURLEncoding.DecodeString(key.Y)
+	if err != nil {
+		return nil, fmt.Errorf("invalid y coordinate: %w", err)
+	}
+
+	pubKey := &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+	if !curve.IsOnCurve(pubKey.X, pubKey.Y) {
+		return nil, fmt.Errorf("point is not on curve %s", key.Crv)
+	}
+	return pubKey, nil
+}
+
+func parseOKPJWK(key jwk) (ed25519.PublicKey, error) {
+	if key.Crv != "Ed25519" {
+		return nil, fmt.Errorf("unsupported curve %s", key.Crv)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(key.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x: %w", err)
+	}
+	if len(x) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid Ed25519 key size %d", len(x))
+	}
+	return ed25519.PublicKey(x), nil
+}
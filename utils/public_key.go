@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// 서명 알고리즘 (JWT alg 값)
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmRS384 = "RS384"
+	AlgorithmRS512 = "RS512"
+	AlgorithmPS256 = "PS256"
+	AlgorithmPS384 = "PS384"
+	AlgorithmPS512 = "PS512"
+	AlgorithmES256 = "ES256"
+	AlgorithmES384 = "ES384"
+	AlgorithmES512 = "ES512"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// pemAlgorithmHeader는 저장된 PEM 블록에 알고리즘을 기록하는 헤더 이름입니다.
+// 헤더가 없는 기존 RSA 키는 RS256으로 봅니다.
+const pemAlgorithmHeader = "Algorithm"
+
+// PublicKey는 검증 키와 그 키로 검증할 수 있는 단 하나의 알고리즘입니다.
+// Key는 *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey 중 하나입니다.
+type PublicKey struct {
+	Algorithm string
+	Key       crypto.PublicKey
+}
+
+// NewPublicKey는 키 타입과 알고리즘이 맞는지 확인합니다. algorithm이 비어 있으면 키 타입에서 정합니다.
+func NewPublicKey(key crypto.PublicKey, algorithm string) (*PublicKey, error) {
+	if algorithm == "" {
+		algorithm = defaultAlgorithm(key)
+		if algorithm == "" {
+			return nil, fmt.Errorf("unsupported public key type %T", key)
+		}
+	}
+
+	if err := checkAlgorithm(key, algorithm); err != nil {
+		return nil, err
+	}
+	return &PublicKey{Algorithm: algorithm, Key: key}, nil
+}
+
+func defaultAlgorithm(key crypto.PublicKey) string {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return AlgorithmRS256
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return AlgorithmES256
+		case elliptic.P384():
+			return AlgorithmES384
+		case elliptic.P521():
+			return AlgorithmES512
+		}
+	case ed25519.PublicKey:
+		return AlgorithmEdDSA
+	}
+	return ""
+}
+
+func checkAlgorithm(key crypto.PublicKey, algorithm string) error {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		switch algorithm {
+		case AlgorithmRS256, AlgorithmRS384, AlgorithmRS512, AlgorithmPS256, AlgorithmPS384, AlgorithmPS512:
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		// ES 알고리즘은 곡선이 고정되어 있음
+		if defaultAlgorithm(k) == algorithm {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if algorithm == AlgorithmEdDSA {
+			return nil
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	return fmt.Errorf("algorithm %s does not match %T key", algorithm, key)
+}
+
+// ParsePublicKeyPEM은 PKCS#1("RSA PUBLIC KEY") 또는 PKIX("PUBLIC KEY") PEM을 읽습니다.
+// algorithm이 비어 있으면 PEM의 Algorithm 헤더, 그것도 없으면 키 타입에서 정합니다.
+func ParsePublicKeyPEM(pemBytes []byte, algorithm string) (*PublicKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("failed to decode PEM block")
+	}
+
+	var key crypto.PublicKey
+	var err error
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+
+	if algorithm == "" {
+		algorithm = block.Headers[pemAlgorithmHeader]
+	}
+	return NewPublicKey(key, algorithm)
+}
+
+// MarshalPublicKey는 알고리즘을 헤더에 담은 PEM으로 직렬화합니다. RSA 키는 기존처럼 PKCS#1로 씁니다.
+func MarshalPublicKey(pubKey *PublicKey) ([]byte, error) {
+	block := &pem.Block{
+		Headers: map[string]string{pemAlgorithmHeader: pubKey.Algorithm},
+	}
+
+	if rsaKey, ok := pubKey.Key.(*rsa.PublicKey); ok {
+		block.Type = "RSA PUBLIC KEY"
+		block.Bytes = x509.MarshalPKCS1PublicKey(rsaKey)
+	} else {
+		der, err := x509.MarshalPKIXPublicKey(pubKey.Key)
+		if err != nil {
+			return nil, err
+		}
+		block.Type = "PUBLIC KEY"
+		block.Bytes = der
+	}
+	return pem.EncodeToMemory(block), nil
+}
@@ -1,74 +1,426 @@
 package utils
 
 import (
-	"bytes"
 	"context"
-	"crypto/rsa"
-	"crypto/x509"
-	"encoding/pem"
+	"errors"
 	"fmt"
+	"log"
+	"sync"
+	"time"
 
 	"github.com/go-redis/redis/v8"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
+// kidIndexKey는 저장된 모든 kid를 추가된 시각(unix ms)을 score로 담는 sorted set입니다.
+// 공개키 자체는 기존처럼 kid 이름의 키에 저장되고, 교체된 키는 만료 시각(TTL)을 갖습니다.
+const kidIndexKey = "publickeys:kids"
+
+// revokedKidsKey는 RevokeKey로 폐기된 kid 집합입니다. 폐기된 kid는 푸시나 JWKS로 다시 등록되지 않습니다.
+const revokedKidsKey = "publickeys:revoked"
+
+// ErrKeyRevoked는 폐기된 kid를 다시 등록하려 할 때 반환됩니다.
+var ErrKeyRevoked = errors.New("key has been revoked")
+
+// keyInvalidationChannel로 키가 바뀐 것을 다른 레플리카에 알립니다. payload는 kid이고, "*"이면 전체입니다.
+const keyInvalidationChannel = "publickeys:invalidate"
+
+var (
+	keyCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "public_key_cache_hits_total",
+		Help: "Number of public key lookups served from the in-process cache.",
+	})
+	keyCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "public_key_cache_misses_total",
+		Help: "Number of public key lookups that went to Redis.",
+	})
+)
+
+// KeyRetention은 교체된 이전 키를 얼마나 유지할지 정합니다.
+type KeyRetention struct {
+	// 교체 시각(rolledAt)부터 이전 키를 유지하는 시간
+	GracePeriod time.Duration
+	// 동시에 유지할 이전 키의 최대 개수 (0 이하이면 개수 제한 없음)
+	MaxPreviousKeys int
+}
+
+func DefaultKeyRetention() KeyRetention {
+	return KeyRetention{
+		GracePeriod:     time.Hour,
+		MaxPreviousKeys: 2,
+	}
+}
+
+// KeyInfo는 ListKeys가 돌려주는 키 메타데이터입니다. ExpiresAt이 zero이면 현재 키입니다.
+type KeyInfo struct {
+	Kid       string
+	Algorithm string
+	AddedAt   time.Time
+	ExpiresAt time.Time
+}
+
+// MissingKeyLoader는 GetKey가 모르는 kid를 만났을 때 키를 다시 받아오게 하는 훅입니다 (예: JWKS 재조회).
+type MissingKeyLoader func(ctx context.Context, kid string) error
+
+type cachedKey struct {
+	key       *PublicKey
+	expiresAt time.Time
+}
+
 type PublicKeyStore struct {
-	redisClient *redis.Client
-	previousKid string
+	redisClient      *redis.Client
+	retention        KeyRetention
+	missingKeyLoader MissingKeyLoader
+
+	// 파싱된 키의 프로세스 내 캐시 (cacheTTL이 0이면 사용하지 않음)
+	cacheTTL time.Duration
+	cacheMu  sync.RWMutex
+	cache    map[string]cachedKey
+
+	// Watch로 등록된 변경 알림 채널
+	watchersMu sync.Mutex
+	watchers   map[chan struct{}]struct{}
 }
 
-func NewPublicKeyStore(redisClient *redis.Client) *PublicKeyStore {
+func NewPublicKeyStore(redisClient *redis.Client, retention KeyRetention) *PublicKeyStore {
 	return &PublicKeyStore{
 		redisClient: redisClient,
+		retention:   retention,
+		cache:       make(map[string]cachedKey),
+		watchers:    make(map[chan struct{}]struct{}),
 	}
 }
 
-func MarshalRSAPublicKey(pubKey *rsa.PublicKey) ([]byte, error) {
-	pubKeyBytes := x509.MarshalPKCS1PublicKey(pubKey)
-	var pemBuffer bytes.Buffer
-	pem.Encode(&pemBuffer, &pem.Block{
-		Type:  "RSA PUBLIC KEY",
-		Bytes: pubKeyBytes,
-	})
-	return pemBuffer.Bytes(), nil
+// SetCacheTTL은 서버 시작 시 한 번 설정합니다. 캐시된 키는 ttl과 키 자체의 만료 시각 중 이른 쪽까지 쓰입니다.
+func (store *PublicKeyStore) SetCacheTTL(ttl time.Duration) {
+	store.cacheTTL = ttl
+}
+
+// SubscribeInvalidations는 다른 레플리카의 키 변경 알림을 구독해 로컬 캐시를 비웁니다.
+// 구독이 확인된 뒤 반환하고, 수신은 ctx가 끝날 때까지 백그라운드에서 계속됩니다.
+func (store *PublicKeyStore) SubscribeInvalidations(ctx context.Context) error {
+	pubsub := store.redisClient.Subscribe(ctx, keyInvalidationChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return fmt.Errorf("failed to subscribe to %s: %w", keyInvalidationChannel, err)
+	}
+
+	go func() {
+		defer pubsub.Close()
+		ch := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				store.invalidateLocal(msg.Payload)
+			}
+		}
+	}()
+	return nil
+}
+
+// invalidate는 로컬 캐시를 비우고 다른 레플리카에도 알립니다.
+func (store *PublicKeyStore) invalidate(ctx context.Context, kid string) {
+	store.invalidateLocal(kid)
+	if err := store.redisClient.Publish(ctx, keyInvalidationChannel, kid).Err(); err != nil {
+		log.Printf("Failed to publish key invalidation kid=%s: %v", kid, err)
+	}
 }
 
-func (store *PublicKeyStore) AddOrUpdateKey(ctx context.Context, kid, pemStr string) error {
+func (store *PublicKeyStore) invalidateLocal(kid string) {
+	store.cacheMu.Lock()
+	if kid == "*" {
+		store.cache = make(map[string]cachedKey)
+	} else {
+		delete(store.cache, kid)
+	}
+	store.cacheMu.Unlock()
+
+	store.notifyWatchers()
+}
+
+// Watch는 키가 바뀔 때마다 신호를 받는 채널을 돌려줍니다. 신호는 합쳐질 수 있으므로
+// 받는 쪽은 ListKeys로 현재 상태를 다시 읽어야 합니다. 다 쓰면 stop을 호출합니다.
+func (store *PublicKeyStore) Watch() (changes <-chan struct{}, stop func()) {
+	ch := make(chan struct{}, 1)
+
+	store.watchersMu.Lock()
+	store.watchers[ch] = struct{}{}
+	store.watchersMu.Unlock()
+
+	return ch, func() {
+		store.watchersMu.Lock()
+		delete(store.watchers, ch)
+		store.watchersMu.Unlock()
+	}
+}
+
+func (store *PublicKeyStore) notifyWatchers() {
+	store.watchersMu.Lock()
+	defer store.watchersMu.Unlock()
+
+	for ch := range store.watchers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (store *PublicKeyStore) cachedKey(kid string) (*PublicKey, bool) {
+	store.cacheMu.RLock()
+	defer store.cacheMu.RUnlock()
+
+	entry, ok := store.cache[kid]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.key, true
+}
+
+func (store *PublicKeyStore) cacheKey(kid string, key *PublicKey, keyTTL time.Duration) {
+	expiresAt := time.Now().Add(store.cacheTTL)
+	if keyTTL > 0 && keyTTL < store.cacheTTL {
+		expiresAt = time.Now().Add(keyTTL)
+	}
+
+	store.cacheMu.Lock()
+	defer store.cacheMu.Unlock()
+	store.cache[kid] = cachedKey{key: key, expiresAt: expiresAt}
+}
+
+// SetMissingKeyLoader는 서버 시작 시 한 번 설정합니다.
+func (store *PublicKeyStore) SetMissingKeyLoader(loader MissingKeyLoader) {
+	store.missingKeyLoader = loader
+}
+
+// AddOrUpdateKey는 kid를 현재 키로 저장하고, 나머지 현재 키들은 rolledAt + GracePeriod에 만료되도록 바꿉니다.
+// algorithm이 비어 있으면 키 타입에서 정합니다 (RSA는 RS256).
+// 상태는 모두 Redis에 있으므로 재시작이나 여러 레플리카에서도 같은 결과가 됩니다.
+func (store *PublicKeyStore) AddOrUpdateKey(ctx context.Context, kid, pemStr, algorithm string, rolledAt time.Time) error {
+	revoked, err := store.IsRevoked(ctx, kid)
+	if err != nil {
+		return fmt.Errorf("failed to check revoked keys: %w", err)
+	}
+	if revoked {
+		return fmt.Errorf("kid %s: %w", kid, ErrKeyRevoked)
+	}
+
 	// PEM 파싱
-	pubKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(pemStr))
+	pubKey, err := ParsePublicKeyPEM([]byte(pemStr), algorithm)
 	if err != nil {
-		return fmt.Errorf("failed to parse RSA public key: %w", err)
+		return fmt.Errorf("failed to parse public key: %w", err)
 	}
 
 	// Redis에 저장
-	pubKeyBytes, err := MarshalRSAPublicKey(pubKey)
+	pubKeyBytes, err := MarshalPublicKey(pubKey)
 	if err != nil {
-		return fmt.Errorf("failed to marshal RSA public key: %w", err)
+		return fmt.Errorf("failed to marshal public key: %w", err)
 	}
 
-	if store.previousKid != "" && store.previousKid != kid {
-		err := store.RemoveKey(ctx, store.previousKid)
-		if err != nil {
-			return fmt.Errorf("failed to remove previous key from store: %w", err)
+	keys, err := store.ListKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list keys: %w", err)
+	}
+
+	expiresAt := rolledAt.Add(store.retention.GracePeriod)
+	_, err = store.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, kid, pubKeyBytes, 0)
+		pipe.ZAddNX(ctx, kidIndexKey, &redis.Z{Score: float64(time.Now().UnixMilli()), Member: kid})
+
+		for _, key := range keys {
+			if key.Kid == kid || !key.ExpiresAt.IsZero() {
+				continue
+			}
+			if expiresAt.After(time.Now()) {
+				pipe.PExpireAt(ctx, key.Kid, expiresAt)
+			} else {
+				pipe.Del(ctx, key.Kid)
+				pipe.ZRem(ctx, kidIndexKey, key.Kid)
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	store.invalidate(ctx, "*")
 
-	err = store.redisClient.Set(ctx, kid, pubKeyBytes, 0).Err()
+	return store.pruneRetiredKeys(ctx)
+}
+
+// SyncKeys는 키 목록(JWKS 등)을 그대로 반영합니다. 목록에 있는 키는 유효한 키로 저장하고,
+// 목록에서 빠진 현재 키는 지금부터 GracePeriod 뒤에 만료되도록 바꿉니다.
+func (store *PublicKeyStore) SyncKeys(ctx context.Context, pubKeys map[string]*PublicKey) error {
+	keys, err := store.ListKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list keys: %w", err)
+	}
+
+	revoked, err := store.redisClient.SMembers(ctx, revokedKidsKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read revoked keys: %w", err)
+	}
+	revokedKids := make(map[string]bool, len(revoked))
+	for _, kid := range revoked {
+		revokedKids[kid] = true
+	}
+
+	expiresAt := time.Now().Add(store.retention.GracePeriod)
+	_, err = store.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for kid, pubKey := range pubKeys {
+			if revokedKids[kid] {
+				log.Printf("Ignoring revoked kid=%s from key sync", kid)
+				continue
+			}
+			pubKeyBytes, err := MarshalPublicKey(pubKey)
+			if err != nil {
+				return fmt.Errorf("failed to marshal public key: %w", err)
+			}
+			pipe.Set(ctx, kid, pubKeyBytes, 0)
+			pipe.ZAddNX(ctx, kidIndexKey, &redis.Z{Score: float64(time.Now().UnixMilli()), Member: kid})
+		}
+
+		for _, key := range keys {
+			if _, ok := pubKeys[key.Kid]; ok || !key.ExpiresAt.IsZero() {
+				continue
+			}
+			pipe.PExpireAt(ctx, key.Kid, expiresAt)
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
+	store.invalidate(ctx, "*")
 
-	store.previousKid = kid
+	return store.pruneRetiredKeys(ctx)
+}
 
+// pruneRetiredKeys는 만료 전인 이전 키가 MaxPreviousKeys보다 많으면 오래된 것부터 지웁니다.
+func (store *PublicKeyStore) pruneRetiredKeys(ctx context.Context) error {
+	if store.retention.MaxPreviousKeys <= 0 {
+		return nil
+	}
+
+	keys, err := store.ListKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list keys: %w", err)
+	}
+
+	var retired []KeyInfo
+	for _, key := range keys {
+		if !key.ExpiresAt.IsZero() {
+			retired = append(retired, key)
+		}
+	}
+	for len(retired) > store.retention.MaxPreviousKeys {
+		if err := store.RemoveKey(ctx, retired[0].Kid); err != nil {
+			return fmt.Errorf("failed to remove previous key from store: %w", err)
+		}
+		retired = retired[1:]
+	}
 	return nil
 }
 
 func (store *PublicKeyStore) RemoveKey(ctx context.Context, kid string) error {
-	return store.redisClient.Del(ctx, kid).Err()
+	_, err := store.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, kid)
+		pipe.ZRem(ctx, kidIndexKey, kid)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	store.invalidate(ctx, kid)
+	return nil
 }
 
-func (store *PublicKeyStore) GetKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+// RevokeKey는 kid를 즉시 제거하고 다시 등록되지 않도록 폐기 목록에 남깁니다.
+func (store *PublicKeyStore) RevokeKey(ctx context.Context, kid string) error {
+	_, err := store.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, revokedKidsKey, kid)
+		pipe.Del(ctx, kid)
+		pipe.ZRem(ctx, kidIndexKey, kid)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	store.invalidate(ctx, kid)
+	return nil
+}
+
+func (store *PublicKeyStore) IsRevoked(ctx context.Context, kid string) (bool, error) {
+	return store.redisClient.SIsMember(ctx, revokedKidsKey, kid).Result()
+}
+
+// ListKeys는 아직 유효한 키를 추가된 순서대로 돌려줍니다. TTL로 이미 사라진 키는 색인에서도 정리합니다.
+func (store *PublicKeyStore) ListKeys(ctx context.Context) ([]KeyInfo, error) {
+	entries, err := store.redisClient.ZRangeWithScores(ctx, kidIndexKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := store.redisClient.Pipeline()
+	ttls := make([]*redis.DurationCmd, len(entries))
+	values := make([]*redis.StringCmd, len(entries))
+	for i, entry := range entries {
+		ttls[i] = pipe.PTTL(ctx, entry.Member.(string))
+		values[i] = pipe.Get(ctx, entry.Member.(string))
+	}
+	if len(entries) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	keys := make([]KeyInfo, 0, len(entries))
+	for i, entry := range entries {
+		kid := entry.Member.(string)
+		ttl := ttls[i].Val()
+		if ttl == -2 {
+			store.redisClient.ZRem(ctx, kidIndexKey, kid)
+			continue
+		}
+
+		key := KeyInfo{
+			Kid:     kid,
+			AddedAt: time.UnixMilli(int64(entry.Score)),
+		}
+		if pubKey, err := ParsePublicKeyPEM([]byte(values[i].Val()), ""); err == nil {
+			key.Algorithm = pubKey.Algorithm
+		}
+		if ttl > 0 {
+			key.ExpiresAt = now.Add(ttl)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (store *PublicKeyStore) GetKey(ctx context.Context, kid string) (*PublicKey, error) {
+	if store.cacheTTL > 0 {
+		if key, ok := store.cachedKey(kid); ok {
+			keyCacheHits.Inc()
+			return key, nil
+		}
+		keyCacheMisses.Inc()
+	}
+
 	pubKeyBytes, err := store.redisClient.Get(ctx, kid).Bytes()
+	if err == redis.Nil && store.missingKeyLoader != nil {
+		if loadErr := store.missingKeyLoader(ctx, kid); loadErr != nil {
+			log.Printf("Failed to load missing key kid=%s: %v", kid, loadErr)
+		}
+		pubKeyBytes, err = store.redisClient.Get(ctx, kid).Bytes()
+	}
 	if err != nil {
 		if err == redis.Nil {
 			return nil, fmt.Errorf("public key not found for kid: %s", kid)
@@ -76,9 +428,19 @@ func (store *PublicKeyStore) GetKey(ctx context.Context, kid string) (*rsa.Publi
 		return nil, err
 	}
 
-	pubKey, err := jwt.ParseRSAPublicKeyFromPEM(pubKeyBytes)
+	pubKey, err := ParsePublicKeyPEM(pubKeyBytes, "")
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse RSA public key: %w", err)
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+
+	if store.cacheTTL > 0 {
+		// 교체된 키는 만료 시각 이후까지 캐시에 남지 않도록 TTL을 확인
+		keyTTL, err := store.redisClient.PTTL(ctx, kid).Result()
+		if err != nil {
+			log.Printf("Failed to read TTL for kid=%s, not caching: %v", kid, err)
+		} else if keyTTL != -2 {
+			store.cacheKey(kid, pubKey, keyTTL)
+		}
 	}
 
 	return pubKey, nil
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenBucket: 초당 rate개씩 채워지고 burst개까지 모이는 토큰 버킷. 동시에 불러도 안전합니다.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// Allow: 토큰이 있으면 하나 쓰고 true를 반환합니다.
+func (b *TokenBucket) Allow() bool {
+	return b.AllowAt(time.Now())
+}
+
+func (b *TokenBucket) AllowAt(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// full: now 기준으로 버킷이 가득 찼는지. 가득 찬 버킷은 지워도 동작이 같습니다.
+func (b *TokenBucket) full(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	return b.tokens >= b.burst
+}
+
+func (b *TokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+}
+
+// KeyedRateLimiter: 키(예: 사용자 ID)마다 따로 두는 토큰 버킷.
+// 다 채워진 버킷은 주기적으로 지워 오래 접속하지 않은 키가 메모리에 남지 않게 합니다.
+type KeyedRateLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     int
+	buckets   map[string]*TokenBucket
+	lastSweep time.Time
+}
+
+const keyedRateLimiterSweepInterval = time.Minute
+
+func NewKeyedRateLimiter(rate float64, burst int) *KeyedRateLimiter {
+	return &KeyedRateLimiter{
+		rate:      rate,
+		burst:     burst,
+		buckets:   make(map[string]*TokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow: key의 버킷에서 토큰을 하나 씁니다.
+func (l *KeyedRateLimiter) Allow(key string) bool {
+	now := time.Now()
+	l.mu.Lock()
+	if now.Sub(l.lastSweep) >= keyedRateLimiterSweepInterval {
+		for k, b := range l.buckets {
+			if b.full(now) {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = NewTokenBucket(l.rate, l.burst)
+		l.buckets[key] = bucket
+	}
+	l.mu.Unlock()
+	return bucket.AllowAt(now)
+}
+
+// Len: 메모리에 있는 버킷 수
+func (l *KeyedRateLimiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
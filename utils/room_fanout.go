@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// roomChannelPrefix 뒤에 "<namespace>:<room>"이 붙어 방마다 채널이 하나씩 생깁니다.
+const roomChannelPrefix = "rooms:"
+
+// RoomDeliverFunc는 다른 인스턴스에서 온 브로드캐스트를 이 인스턴스의 로컬 연결에 전달합니다.
+type RoomDeliverFunc func(room string, payload []byte)
+
+type roomEnvelope struct {
+	Instance string `json:"instance"`
+	Payload  []byte `json:"payload"`
+}
+
+// RoomFanout은 방 단위 WebSocket 브로드캐스트를 Redis pub/sub으로 모든 인스턴스에 퍼뜨립니다.
+// 보낸 인스턴스는 로컬 연결에 직접 전달하므로, 자기 instanceID가 붙은 메시지는 무시합니다.
+type RoomFanout struct {
+	redisClient *redis.Client
+	instanceID  string
+	mu          sync.RWMutex
+	handlers    map[string]RoomDeliverFunc
+}
+
+func NewRoomFanout(redisClient *redis.Client, instanceID string) *RoomFanout {
+	return &RoomFanout{
+		redisClient: redisClient,
+		instanceID:  instanceID,
+		handlers:    make(map[string]RoomDeliverFunc),
+	}
+}
+
+func (f *RoomFanout) InstanceID() string {
+	return f.instanceID
+}
+
+// Register는 namespace(예: "participants", "signaling") 방들의 원격 메시지를 받을 함수를 등록합니다.
+func (f *RoomFanout) Register(namespace string, deliver RoomDeliverFunc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handlers[namespace] = deliver
+}
+
+// Publish는 다른 인스턴스에 payload를 보냅니다. 로컬 연결에는 호출한 쪽이 직접 보내야 합니다.
+func (f *RoomFanout) Publish(ctx context.Context, namespace, room string, payload []byte) error {
+	data, err := json.Marshal(roomEnvelope{Instance: f.instanceID, Payload: payload})
+	if err != nil {
+		return err
+	}
+	return f.redisClient.Publish(ctx, roomChannelPrefix+namespace+":"+room, data).Err()
+}
+
+// Subscribe는 모든 방 채널을 패턴 구독합니다.
+// 구독이 확인된 뒤 반환하고, 수신은 ctx가 끝날 때까지 백그라운드에서 계속됩니다.
+func (f *RoomFanout) Subscribe(ctx context.Context) error {
+	pattern := roomChannelPrefix + "*"
+	pubsub := f.redisClient.PSubscribe(ctx, pattern)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return fmt.Errorf("failed to subscribe to %s: %w", pattern, err)
+	}
+
+	go func() {
+		defer pubsub.Close()
+		ch := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				f.deliver(msg.Channel, msg.Payload)
+			}
+		}
+	}()
+	return nil
+}
+
+func (f *RoomFanout) deliver(channel, data string) {
+	namespace, room, ok := strings.Cut(strings.TrimPrefix(channel, roomChannelPrefix), ":")
+	if !ok {
+		return
+	}
+	var envelope roomEnvelope
+	if err := json.Unmarshal([]byte(data), &envelope); err != nil {
+		log.Printf("Invalid room broadcast on %s: %v", channel, err)
+		return
+	}
+	if envelope.Instance == f.instanceID {
+		return
+	}
+
+	f.mu.RLock()
+	handler := f.handlers[namespace]
+	f.mu.RUnlock()
+	if handler != nil {
+		handler(room, envelope.Payload)
+	}
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// roomLeaseKeyPrefix 뒤에 방 이름이 붙습니다. 값은 방을 연 인스턴스 ID입니다.
+const roomLeaseKeyPrefix = "room-owner:"
+
+// 값이 owner일 때만 TTL을 늘리거나 지웁니다. 다른 인스턴스가 가져간 임대를 건드리지 않기 위함입니다.
+// 갱신할 때 키가 없으면(아무도 갖고 있지 않으면) 다시 가져옵니다.
+var (
+	refreshRoomLeaseScript = redis.NewScript(`
+local owner = redis.call("GET", KEYS[1])
+if owner == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+if not owner then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+end
+return 0`)
+	releaseRoomLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// RoomLease는 메모리에 상태를 두는 방을 한 인스턴스만 열도록 Redis에 소유권을 기록합니다.
+// 소유 인스턴스가 죽으면 TTL이 지난 뒤 다른 인스턴스가 가져갈 수 있습니다.
+type RoomLease struct {
+	redisClient *redis.Client
+	owner       string
+	ttl         time.Duration
+}
+
+func NewRoomLease(redisClient *redis.Client, owner string, ttl time.Duration) *RoomLease {
+	return &RoomLease{
+		redisClient: redisClient,
+		owner:       owner,
+		ttl:         ttl,
+	}
+}
+
+// TTL은 갱신하지 않으면 임대가 풀리기까지의 시간입니다. 이보다 짧은 간격으로 Refresh해야 합니다.
+func (l *RoomLease) TTL() time.Duration {
+	return l.ttl
+}
+
+// Acquire는 room을 이 인스턴스의 것으로 기록합니다. 다른 인스턴스가 갖고 있으면 false를 반환합니다.
+func (l *RoomLease) Acquire(ctx context.Context, room string) (bool, error) {
+	ok, err := l.redisClient.SetNX(ctx, roomLeaseKeyPrefix+room, l.owner, l.ttl).Result()
+	if err != nil || ok {
+		return ok, err
+	}
+	return l.Refresh(ctx, room)
+}
+
+// Refresh는 아직 이 인스턴스의 임대이거나 비어 있으면 TTL을 늘리고 true를 반환합니다.
+// 같은 인스턴스에서 방을 닫자마자 다시 열면 이전 방의 Release가 키를 지울 수 있는데, 다음 갱신에서 되찾습니다.
+func (l *RoomLease) Refresh(ctx context.Context, room string) (bool, error) {
+	n, err := refreshRoomLeaseScript.Run(ctx, l.redisClient, []string{roomLeaseKeyPrefix + room}, l.owner, l.ttl.Milliseconds()).Int()
+	return n == 1, err
+}
+
+// Release는 이 인스턴스의 임대이면 지웁니다.
+func (l *RoomLease) Release(ctx context.Context, room string) error {
+	return releaseRoomLeaseScript.Run(ctx, l.redisClient, []string{roomLeaseKeyPrefix + room}, l.owner).Err()
+}
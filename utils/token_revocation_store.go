@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	revokedTokenKeyPrefix = "revoked:jti:"
+	revokedUserKeyPrefix  = "revoked:user:"
+	// tokenRevocationChannel로 폐기 사실을 모든 레플리카에 알려 열린 WebSocket 세션을 끊게 합니다.
+	tokenRevocationChannel = "revoked:events"
+)
+
+// TokenRevocation은 폐기 알림 내용입니다. JTI나 UserID 중 하나가 채워집니다.
+type TokenRevocation struct {
+	JTI    string `json:"jti,omitempty"`
+	UserID string `json:"user_id,omitempty"`
+}
+
+// TokenRevocationStore는 만료 전에 무효화된 액세스 토큰 목록입니다.
+// jti 단위 폐기와, 사용자 단위 "이 시각 이전에 발급된 토큰은 모두 무효" 폐기를 지원합니다.
+type TokenRevocationStore struct {
+	redisClient *redis.Client
+	// 액세스 토큰의 최대 수명. 이 시간이 지나면 폐기 기록이 필요 없으므로 TTL로 씁니다.
+	maxTokenLifetime time.Duration
+}
+
+func NewTokenRevocationStore(redisClient *redis.Client, maxTokenLifetime time.Duration) *TokenRevocationStore {
+	return &TokenRevocationStore{
+		redisClient:      redisClient,
+		maxTokenLifetime: maxTokenLifetime,
+	}
+}
+
+// RevokeToken은 jti 토큰을 expiresAt까지 폐기합니다. expiresAt이 zero이면 최대 수명만큼 유지합니다.
+func (s *TokenRevocationStore) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := s.maxTokenLifetime
+	if !expiresAt.IsZero() {
+		ttl = time.Until(expiresAt)
+		if ttl <= 0 {
+			// 이미 만료된 토큰은 기록할 필요 없음
+			return nil
+		}
+	}
+
+	if err := s.redisClient.Set(ctx, revokedTokenKeyPrefix+jti, 1, ttl).Err(); err != nil {
+		return err
+	}
+	s.publish(ctx, TokenRevocation{JTI: jti})
+	return nil
+}
+
+// RevokeUser는 userID에게 before 이전에 발급된 토큰을 모두 폐기합니다.
+// JWT iat는 초 단위이므로 before도 초 단위로 내려 저장합니다. 폐기 직후 같은 초에 새로 발급된 토큰은 유효합니다.
+func (s *TokenRevocationStore) RevokeUser(ctx context.Context, userID string, before time.Time) error {
+	err := s.redisClient.Set(ctx, revokedUserKeyPrefix+userID, before.Truncate(time.Second).UnixMilli(), s.maxTokenLifetime).Err()
+	if err != nil {
+		return err
+	}
+	s.publish(ctx, TokenRevocation{UserID: userID})
+	return nil
+}
+
+// IsRevoked는 jti가 폐기되었거나 issuedAt이 사용자의 폐기 시각 이전이면 true입니다.
+// issuedAt을 모르는 토큰은 사용자 폐기 기록이 있으면 폐기된 것으로 봅니다.
+func (s *TokenRevocationStore) IsRevoked(ctx context.Context, jti, userID string, issuedAt time.Time) (bool, error) {
+	pipe := s.redisClient.Pipeline()
+	var tokenRevoked *redis.IntCmd
+	var userRevokedBefore *redis.StringCmd
+	if jti != "" {
+		tokenRevoked = pipe.Exists(ctx, revokedTokenKeyPrefix+jti)
+	}
+	if userID != "" {
+		userRevokedBefore = pipe.Get(ctx, revokedUserKeyPrefix+userID)
+	}
+	if tokenRevoked == nil && userRevokedBefore == nil {
+		return false, nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return false, err
+	}
+
+	if tokenRevoked != nil && tokenRevoked.Val() > 0 {
+		return true, nil
+	}
+	if userRevokedBefore != nil && userRevokedBefore.Err() == nil {
+		millis, err := strconv.ParseInt(userRevokedBefore.Val(), 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("invalid revocation time for user %s: %w", userID, err)
+		}
+		if issuedAt.IsZero() || issuedAt.Before(time.UnixMilli(millis)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *TokenRevocationStore) publish(ctx context.Context, revocation TokenRevocation) {
+	data, err := json.Marshal(revocation)
+	if err != nil {
+		log.Println("Failed to marshal token revocation:", err)
+		return
+	}
+	if err := s.redisClient.Publish(ctx, tokenRevocationChannel, data).Err(); err != nil {
+		log.Printf("Failed to publish token revocation: %v", err)
+	}
+}
+
+// SubscribeRevocations는 모든 레플리카의 폐기 알림을 onRevoke로 넘깁니다.
+// 구독이 확인된 뒤 반환하고, 수신은 ctx가 끝날 때까지 백그라운드에서 계속됩니다.
+func (s *TokenRevocationStore) SubscribeRevocations(ctx context.Context, onRevoke func(TokenRevocation)) error {
+	pubsub := s.redisClient.Subscribe(ctx, tokenRevocationChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return fmt.Errorf("failed to subscribe to %s: %w", tokenRevocationChannel, err)
+	}
+
+	go func() {
+		defer pubsub.Close()
+		ch := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				var revocation TokenRevocation
+				if err := json.Unmarshal([]byte(msg.Payload), &revocation); err != nil {
+					log.Println("Invalid token revocation message:", err)
+					continue
+				}
+				onRevoke(revocation)
+			}
+		}
+	}()
+	return nil
+}
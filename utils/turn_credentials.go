@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"strconv"
+	"time"
+)
+
+// TURNCredential: TURN REST API 방식의 임시 자격 증명
+// Username은 "<만료 unix 시각>:<사용자 ID>", Credential은 base64(HMAC-SHA1(secret, Username))입니다.
+// TURN 서버는 같은 secret으로 Credential을 다시 계산하고 만료 시각을 확인합니다(coturn의 use-auth-secret).
+type TURNCredential struct {
+	Username   string
+	Credential string
+	ExpiresAt  time.Time
+}
+
+func NewTURNCredential(secret, userID string, expiresAt time.Time) TURNCredential {
+	username := strconv.FormatInt(expiresAt.Unix(), 10) + ":" + userID
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(username))
+	return TURNCredential{
+		Username:   username,
+		Credential: base64.StdEncoding.EncodeToString(mac.Sum(nil)),
+		ExpiresAt:  expiresAt,
+	}
+}
@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"errors"
+)
+
+// y-protocols 메시지 타입 (y-websocket 첫 varuint)
+const (
+	YMessageSync           = 0
+	YMessageAwareness      = 1
+	YMessageAuth           = 2
+	YMessageQueryAwareness = 3
+)
+
+// YMessageSync 안의 sync 단계
+const (
+	YSyncStep1  = 0
+	YSyncStep2  = 1
+	YSyncUpdate = 2
+)
+
+var (
+	// YEmptyUpdate는 struct도 delete set도 없는 Yjs update(v1)입니다.
+	YEmptyUpdate = []byte{0, 0}
+	// YEmptyStateVector는 아무 것도 모르는 문서의 state vector입니다. sync step 1에 보내면 상대가 전체 상태를 돌려줍니다.
+	YEmptyStateVector = []byte{0}
+
+	ErrYMessageTruncated = errors.New("y-protocol message truncated")
+	ErrYVarUintOverflow  = errors.New("y-protocol varuint overflows 64 bits")
+)
+
+// YDecoder는 lib0 decoding 중 y-websocket에 필요한 부분(varuint, varUint8Array, varString)만 구현합니다.
+type YDecoder struct {
+	buf []byte
+	pos int
+}
+
+func NewYDecoder(buf []byte) *YDecoder {
+	return &YDecoder{buf: buf}
+}
+
+func (d *YDecoder) HasContent() bool {
+	return d.pos < len(d.buf)
+}
+
+func (d *YDecoder) ReadVarUint() (uint64, error) {
+	var n uint64
+	for shift := uint(0); ; shift += 7 {
+		if d.pos >= len(d.buf) {
+			return 0, ErrYMessageTruncated
+		}
+		if shift >= 64 {
+			return 0, ErrYVarUintOverflow
+		}
+		b := d.buf[d.pos]
+		d.pos++
+		n |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return n, nil
+		}
+	}
+}
+
+func (d *YDecoder) ReadVarUint8Array() ([]byte, error) {
+	n, err := d.ReadVarUint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(d.buf)-d.pos) {
+		return nil, ErrYMessageTruncated
+	}
+	b := d.buf[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+func (d *YDecoder) ReadVarString() (string, error) {
+	b, err := d.ReadVarUint8Array()
+	return string(b), err
+}
+
+// YEncoder는 YDecoder의 짝입니다.
+type YEncoder struct {
+	buf []byte
+}
+
+func NewYEncoder() *YEncoder {
+	return &YEncoder{}
+}
+
+func (e *YEncoder) WriteVarUint(n uint64) {
+	for n >= 0x80 {
+		e.buf = append(e.buf, byte(n)|0x80)
+		n >>= 7
+	}
+	e.buf = append(e.buf, byte(n))
+}
+
+func (e *YEncoder) WriteVarUint8Array(b []byte) {
+	e.WriteVarUint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *YEncoder) WriteVarString(s string) {
+	e.WriteVarUint8Array([]byte(s))
+}
+
+func (e *YEncoder) Bytes() []byte {
+	return e.buf
+}
+
+// EncodeYSyncMessage는 [messageSync, syncType, payload] 메시지를 만듭니다.
+// step 1이면 payload는 state vector, step 2와 update면 Yjs update입니다.
+func EncodeYSyncMessage(syncType uint64, payload []byte) []byte {
+	e := NewYEncoder()
+	e.WriteVarUint(YMessageSync)
+	e.WriteVarUint(syncType)
+	e.WriteVarUint8Array(payload)
+	return e.Bytes()
+}
+
+// EncodeYAwarenessMessage는 awareness update를 [messageAwareness, update] 메시지로 감쌉니다.
+func EncodeYAwarenessMessage(update []byte) []byte {
+	e := NewYEncoder()
+	e.WriteVarUint(YMessageAwareness)
+	e.WriteVarUint8Array(update)
+	return e.Bytes()
+}
+
+// YAwarenessState는 awareness update의 한 항목입니다. State는 JSON 문자열이며 "null"이면 클라이언트가 떠났다는 뜻입니다.
+type YAwarenessState struct {
+	ClientID uint64
+	Clock    uint64
+	State    string
+}
+
+func (s YAwarenessState) Removed() bool {
+	return s.State == "null"
+}
+
+func DecodeYAwarenessUpdate(update []byte) ([]YAwarenessState, error) {
+	d := NewYDecoder(update)
+	n, err := d.ReadVarUint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(update)) {
+		return nil, ErrYMessageTruncated
+	}
+	states := make([]YAwarenessState, 0, n)
+	for i := uint64(0); i < n; i++ {
+		var s YAwarenessState
+		if s.ClientID, err = d.ReadVarUint(); err != nil {
+			return nil, err
+		}
+		if s.Clock, err = d.ReadVarUint(); err != nil {
+			return nil, err
+		}
+		if s.State, err = d.ReadVarString(); err != nil {
+			return nil, err
+		}
+		states = append(states, s)
+	}
+	return states, nil
+}
+
+func EncodeYAwarenessUpdate(states []YAwarenessState) []byte {
+	e := NewYEncoder()
+	e.WriteVarUint(uint64(len(states)))
+	for _, s := range states {
+		e.WriteVarUint(s.ClientID)
+		e.WriteVarUint(s.Clock)
+		e.WriteVarString(s.State)
+	}
+	return e.Bytes()
+}